@@ -46,11 +46,14 @@ func main() {
 		out              = os.Stdout
 		rootCmd          = cmd.NewRootCmd()
 		clientCmd        = cmd.NewClientCmd(mobileClient, scClient, k8Client, out)
-		bindCmd          = cmd.NewIntegrationCmd(scClient, k8Client, out)
+		dcClient         = cmd.NewDeploymentConfigClient(k8Client.Discovery().RESTClient())
+		bindCmd          = cmd.NewIntegrationCmd(scClient, k8Client, mobileClient, dcClient, out)
 		serviceConfigCmd = cmd.NewServiceConfigCommand(k8Client)
 		clientCfgCmd     = cmd.NewClientConfigCmd(k8Client, mobileClient, scClient, config.Host, out)
 		clientBuilds     = cmd.NewClientBuildsCmd()
-		svcCmd           = cmd.NewServicesCmd(scClient, k8Client, out)
+		svcCmd           = cmd.NewServicesCmd(scClient, k8Client, dcClient, out)
+		manifestCmd      = cmd.NewManifestCmd(mobileClient, scClient, k8Client, dcClient, clientsForContext(*kubeconfig), out)
+		accessCmd        = cmd.NewAccessCmd(k8Client, out)
 	)
 
 	// create
@@ -77,9 +80,25 @@ func main() {
 		getCmd.AddCommand(clientBuilds.ListClientBuildsCmd())
 		getCmd.AddCommand(svcCmd.ListServicesCmd())
 		getCmd.AddCommand(svcCmd.ListServiceInstCmd())
+		getCmd.AddCommand(svcCmd.GetServiceInstanceCmd())
 		rootCmd.AddCommand(getCmd)
 	}
 
+	//describe
+	{
+		describeCmd := cmd.NewDescribeCommand()
+		describeCmd.AddCommand(svcCmd.DescribeServiceCmd())
+		rootCmd.AddCommand(describeCmd)
+	}
+
+	//update
+	{
+		updateCmd := cmd.NewUpdateCommand()
+		updateCmd.AddCommand(svcCmd.UpdateServiceInstanceCmd())
+		updateCmd.AddCommand(clientCmd.UpdateClientCmd())
+		rootCmd.AddCommand(updateCmd)
+	}
+
 	//set
 	{
 		setCmd := cmd.NewSetCommand()
@@ -88,6 +107,23 @@ func main() {
 		rootCmd.AddCommand(setCmd)
 	}
 
+	// check
+	{
+		checkCmd := cmd.NewCheckCommand()
+		checkCmd.AddCommand(bindCmd.CheckIntegrationsCmd())
+		rootCmd.AddCommand(checkCmd)
+	}
+
+	// wait
+	{
+		waitCmd := cmd.NewWaitCommand()
+		waitCmd.AddCommand(svcCmd.WaitServiceInstanceCmd())
+		waitCmd.AddCommand(bindCmd.WaitIntegrationCmd())
+		waitCmd.AddCommand(clientCmd.WaitClientCmd())
+		waitCmd.AddCommand(clientBuilds.WaitClientBuildsCmd())
+		rootCmd.AddCommand(waitCmd)
+	}
+
 	// delete
 	{
 		deleteCmd := cmd.NewDeleteComand()
@@ -106,6 +142,27 @@ func main() {
 		rootCmd.AddCommand(stopCmd)
 	}
 
+	// rotate
+	{
+		rotateCmd := cmd.NewRotateCmd()
+		rotateCmd.AddCommand(clientCmd.RotateClientAPIKeyCmd())
+		rootCmd.AddCommand(rotateCmd)
+	}
+
+	// auth
+	{
+		authCmd := cmd.NewAuthCommand()
+		authCmd.AddCommand(accessCmd.CanICmd())
+		rootCmd.AddCommand(authCmd)
+	}
+
+	// apply, export and diff
+	{
+		rootCmd.AddCommand(manifestCmd.ApplyCmd())
+		rootCmd.AddCommand(manifestCmd.ExportCmd())
+		rootCmd.AddCommand(manifestCmd.DiffCmd())
+	}
+
 	// start
 	{
 		startCmd := cmd.NewStartCmd()
@@ -114,13 +171,14 @@ func main() {
 	}
 
 	rootCmd.SilenceUsage = true
+	rootCmd.PersistentPreRunE = cmd.CheckCommandAccess(k8Client)
 
 	if err := rootCmd.Execute(); err != nil {
 		// as using pkg/errors lets allow the full stack to be seen if needed
 		if os.Getenv("MCP_DEBUG") == "true" {
 			log.Fatalf("error: %+v", err)
 		}
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 
@@ -144,6 +202,33 @@ func NewClientsOrDie(config *restclient.Config) (kubernetes.Interface, m.Interfa
 	return k8client, mobileClientSet, scClientSet
 }
 
+// clientsForContext connects to the contexts of the kubeconfig other than the current one. Unlike NewClientsOrDie it returns the errors
+// as the context is given by the user
+func clientsForContext(kubeconfig string) cmd.ClientsForContext {
+	return func(context string) (m.Interface, sc.Interface, kubernetes.Interface, error) {
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+			&clientcmd.ConfigOverrides{CurrentContext: context},
+		).ClientConfig()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		k8client, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		scClientSet, err := sc.NewForConfig(config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		mobileClientSet, err := m.NewForConfig(config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return mobileClientSet, scClientSet, k8client, nil
+	}
+}
+
 func homeDir() string {
 	if h := os.Getenv("HOME"); h != "" {
 		return h
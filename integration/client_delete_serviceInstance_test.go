@@ -68,7 +68,7 @@ func TestDeleteServiceInstance(t *testing.T) {
 		},
 		{
 			name:          "Successful Delete serviceinstance",
-			args:          []string{"delete", "serviceinstance", fhSyncID, "--namespace=" + *namespace},
+			args:          []string{"delete", "serviceinstance", fhSyncID, "--namespace=" + *namespace, "--no-wait"},
 			expectedError: nil,
 			validate: func(t *testing.T) {
 				expectedStateFound := false
@@ -0,0 +1,335 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	authv1 "k8s.io/client-go/pkg/apis/authorization/v1"
+)
+
+// resourceAccess is a verb on a kind of resource, in the core group when Group is empty.
+// When Flag is set the access is only needed when the command is run with that flag and when Unless is set it is not needed with that flag.
+// The accesses with the same AnyOf are alternatives, being allowed one of them is enough
+type resourceAccess struct {
+	Verb     string
+	Group    string
+	Resource string
+	Flag     string
+	Unless   string
+	AnyOf    string
+}
+
+func (r resourceAccess) String() string {
+	if r.Group == "" {
+		return r.Verb + " " + r.Resource
+	}
+	return r.Verb + " " + r.Resource + "." + r.Group
+}
+
+const (
+	serviceCatalogGroup = "servicecatalog.k8s.io"
+	mobileGroup         = "mobile.k8s.io"
+	settingsGroup       = "settings.k8s.io"
+)
+
+// redeployAccess is what --auto-redeploy does to the consuming workloads. A namespace often only runs one kind of workload
+// so being allowed to change one of them is enough
+var redeployAccess = []resourceAccess{
+	{Verb: "update", Group: "apps", Resource: "deployments", Flag: "auto-redeploy", AnyOf: "workloads"},
+	{Verb: "update", Group: "apps", Resource: "statefulsets", Flag: "auto-redeploy", AnyOf: "workloads"},
+	{Verb: "patch", Group: "apps.openshift.io", Resource: "deploymentconfigs", Flag: "auto-redeploy", AnyOf: "workloads"},
+}
+
+// withFlag returns the accesses as only needed when the command is run with the flag
+func withFlag(accesses []resourceAccess, flag string) []resourceAccess {
+	var flagged []resourceAccess
+	for _, access := range accesses {
+		access.Flag = flag
+		flagged = append(flagged, access)
+	}
+	return flagged
+}
+
+// commandAccess is what the commands changing the namespace do in it, by their path below the root command
+var commandAccess = map[string][]resourceAccess{
+	"create client": {
+		{Verb: "create", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "create", Resource: "secrets"},
+		{Verb: "update", Group: mobileGroup, Resource: "mobileclients", Flag: "from"},
+	},
+	"create serviceinstance": {
+		{Verb: "create", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "create", Resource: "secrets"},
+	},
+	"create integration": append([]resourceAccess{
+		{Verb: "create", Group: settingsGroup, Resource: "podpresets"},
+		{Verb: "create", Group: serviceCatalogGroup, Resource: "servicebindings"},
+	}, redeployAccess...),
+	"update client": {
+		{Verb: "patch", Group: mobileGroup, Resource: "mobileclients"},
+	},
+	"update serviceinstance": {
+		{Verb: "update", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "create", Resource: "secrets"},
+		{Verb: "update", Resource: "secrets"},
+	},
+	"set client": {
+		{Verb: "patch", Group: mobileGroup, Resource: "mobileclients"},
+	},
+	"set value": {
+		{Verb: "patch", Group: mobileGroup, Resource: "mobileclients"},
+	},
+	"check integrations": append([]resourceAccess{
+		{Verb: "create", Group: settingsGroup, Resource: "podpresets", Flag: "fix"},
+		{Verb: "delete", Group: settingsGroup, Resource: "podpresets", Flag: "fix"},
+	}, withFlag(redeployAccess, "fix")...),
+	"create serviceconfig": {
+		{Verb: "create", Resource: "secrets"},
+	},
+	"delete serviceconfig": {
+		{Verb: "delete", Resource: "secrets"},
+	},
+	"delete client": {
+		{Verb: "delete", Group: settingsGroup, Resource: "podpresets", Unless: "keep-services"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "servicebindings", Unless: "keep-services"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "serviceinstances", Unless: "keep-services"},
+		{Verb: "delete", Resource: "secrets"},
+		{Verb: "delete", Group: mobileGroup, Resource: "mobileclients"},
+	},
+	"delete integration": append([]resourceAccess{
+		{Verb: "delete", Group: settingsGroup, Resource: "podpresets"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "servicebindings"},
+	}, redeployAccess...),
+	"delete serviceinstance": append([]resourceAccess{
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "delete", Resource: "secrets"},
+		{Verb: "delete", Group: settingsGroup, Resource: "podpresets", Flag: "cascade"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "servicebindings", Flag: "cascade"},
+	}, withFlag(redeployAccess, "cascade")...),
+	"rotate client-apikey": {
+		{Verb: "update", Group: mobileGroup, Resource: "mobileclients"},
+		{Verb: "update", Resource: "secrets"},
+	},
+	"apply": append([]resourceAccess{
+		{Verb: "create", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "update", Group: serviceCatalogGroup, Resource: "serviceinstances"},
+		{Verb: "create", Resource: "secrets"},
+		{Verb: "update", Resource: "secrets"},
+		{Verb: "update", Group: mobileGroup, Resource: "mobileclients"},
+		{Verb: "create", Group: serviceCatalogGroup, Resource: "servicebindings"},
+		{Verb: "create", Group: settingsGroup, Resource: "podpresets"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "serviceinstances", Flag: "prune"},
+		{Verb: "delete", Resource: "secrets", Flag: "prune"},
+		{Verb: "delete", Group: mobileGroup, Resource: "mobileclients", Flag: "prune"},
+		{Verb: "delete", Group: serviceCatalogGroup, Resource: "servicebindings", Flag: "prune"},
+		{Verb: "delete", Group: settingsGroup, Resource: "podpresets", Flag: "prune"},
+	}, redeployAccess...),
+}
+
+// commandPath is the path of the command below the root command, such as create client
+func commandPath(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+// flagSet returns whether the flag was set to something other than false
+func flagSet(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	return flag != nil && flag.Changed && flag.Value.String() != "false"
+}
+
+// canI asks the API server whether the current user is allowed the access in the namespace
+func canI(k8Client kubernetes.Interface, namespace string, access resourceAccess) (bool, string, error) {
+	review, err := k8Client.AuthorizationV1().SelfSubjectAccessReviews().Create(&authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{Namespace: namespace, Verb: access.Verb, Group: access.Group, Resource: access.Resource},
+		},
+	})
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to check whether you can "+access.String())
+	}
+	return review.Status.Allowed, review.Status.Reason, nil
+}
+
+// checkAccesses reviews each of the accesses in the namespace
+func checkAccesses(k8Client kubernetes.Interface, namespace string, accesses []resourceAccess) ([]AccessCheck, error) {
+	var checks []AccessCheck
+	for _, access := range accesses {
+		allowed, reason, err := canI(k8Client, namespace, access)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, AccessCheck{Verb: access.Verb, Group: access.Group, Resource: access.Resource, Flag: access.Flag, Unless: access.Unless, AnyOf: access.AnyOf, Allowed: allowed, Reason: reason})
+	}
+	return checks, nil
+}
+
+// CheckCommandAccess returns a pre run checking the current user is allowed everything a command changing the namespace does
+// before it starts, so it does not fail half way through. Dry runs are not checked as they change nothing
+func CheckCommandAccess(k8Client kubernetes.Interface) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		path := commandPath(cmd)
+		accesses, ok := commandAccess[path]
+		// every command in the map either only records its changes with --dry-run or rejects the flag
+		if !ok || flagSet(cmd, "dry-run") {
+			return nil
+		}
+		ns, err := currentNamespace(cmd.Flags())
+		if err != nil {
+			// the command reports the missing namespace
+			return nil
+		}
+		var needed []resourceAccess
+		for _, access := range accesses {
+			if (access.Flag == "" || flagSet(cmd, access.Flag)) && (access.Unless == "" || !flagSet(cmd, access.Unless)) {
+				needed = append(needed, access)
+			}
+		}
+		checks, err := checkAccesses(k8Client, ns, needed)
+		if err != nil {
+			return err
+		}
+		allowedAnyOf := map[string]bool{}
+		alternatives := map[string][]string{}
+		for i, check := range checks {
+			if anyOf := needed[i].AnyOf; anyOf != "" {
+				allowedAnyOf[anyOf] = allowedAnyOf[anyOf] || check.Allowed
+				alternatives[anyOf] = append(alternatives[anyOf], needed[i].String())
+			}
+		}
+		var missing []string
+		for i, check := range checks {
+			anyOf := needed[i].AnyOf
+			switch {
+			case check.Allowed || allowedAnyOf[anyOf]:
+			case anyOf == "":
+				missing = append(missing, needed[i].String())
+			case alternatives[anyOf] != nil:
+				missing = append(missing, strings.Join(alternatives[anyOf], " or "))
+				// the alternatives are reported once
+				alternatives[anyOf] = nil
+			}
+		}
+		if len(missing) > 0 {
+			return errors.New(fmt.Sprintf("%s needs you to be allowed to %s in the namespace %s. Run mobile auth can-i %s for the details", path, strings.Join(missing, ", "), ns, path))
+		}
+		return nil
+	}
+}
+
+// AccessCmd checks what the current user is allowed to do
+type AccessCmd struct {
+	*BaseCmd
+	k8Client kubernetes.Interface
+}
+
+// NewAccessCmd returns the AccessCmd
+func NewAccessCmd(k8Client kubernetes.Interface, out io.Writer) *AccessCmd {
+	return &AccessCmd{k8Client: k8Client, BaseCmd: &BaseCmd{Out: output.NewRenderer(out)}}
+}
+
+// CanICmd builds the auth can-i command
+func (ac *AccessCmd) CanICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "can-i <command>",
+		Short: "check you are allowed everything a command does in your namespace",
+		Long: `auth can-i lists each verb and resource the command needs in your namespace and whether you are allowed it.
+The commands that change the namespace check these before they start, so they do not fail half way through.
+The verbs and resources only needed with one of the flags of the command are listed with that flag,
+and those not needed with one of its flags are listed as without that flag.
+Alternatives, of which being allowed one is enough, such as the kinds of workload --auto-redeploy rolls out, share the same Any Of.`,
+		Example: `  mobile auth can-i create integration --namespace=myproject
+  kubectl plugin mobile auth can-i delete client
+  oc plugin mobile auth can-i apply`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Usage()
+			}
+			path := strings.Join(args, " ")
+			accesses, ok := commandAccess[path]
+			if !ok {
+				var paths []string
+				for p := range commandAccess {
+					paths = append(paths, p)
+				}
+				sort.Strings(paths)
+				return errors.New("unknown command " + path + ", the commands changing the namespace are " + strings.Join(paths, ", "))
+			}
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			checks, err := checkAccesses(ac.k8Client, ns, accesses)
+			if err != nil {
+				return err
+			}
+			outType := outputType(cmd.Flags())
+			if err := ac.Out.Render("auth"+cmd.Name(), outType, checks); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "access checks", outType))
+			}
+			return nil
+		},
+	}
+	ac.Out.AddRenderer("auth"+cmd.Name(), "table", func(out io.Writer, data interface{}) error {
+		checks := data.([]AccessCheck)
+		var rows [][]string
+		for _, c := range checks {
+			resource := c.Resource
+			if c.Group != "" {
+				resource += "." + c.Group
+			}
+			flag := ""
+			if c.Flag != "" {
+				flag = "--" + c.Flag
+			}
+			if c.Unless != "" {
+				flag = "without --" + c.Unless
+			}
+			allowed := "no"
+			if c.Allowed {
+				allowed = "yes"
+			}
+			rows = append(rows, []string{c.Verb, resource, flag, c.AnyOf, allowed, c.Reason})
+		}
+		table := tablewriter.NewWriter(out)
+		table.SetHeader([]string{"Verb", "Resource", "Flag", "Any Of", "Allowed", "Reason"})
+		table.AppendBulk(rows)
+		table.Render()
+		return nil
+	})
+	return cmd
+}
+
+// NewAuthCommand returns the auth command
+func NewAuthCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "auth",
+		Short: "check what you are allowed to do",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
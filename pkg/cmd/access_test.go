@@ -0,0 +1,248 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
+	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	kFake "k8s.io/client-go/kubernetes/fake"
+	authv1 "k8s.io/client-go/pkg/apis/authorization/v1"
+	kt "k8s.io/client-go/testing"
+)
+
+// reviewingClient answers the access reviews in myproject, denying the verb and resource pairs in denied
+func reviewingClient(t *testing.T, denied []string, reviewed *[]string) *kFake.Clientset {
+	k8Client := &kFake.Clientset{}
+	k8Client.AddReactor("create", "selfsubjectaccessreviews", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+		attributes := action.(kt.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview).Spec.ResourceAttributes
+		if attributes.Namespace != "myproject" {
+			t.Fatal("expected the review to be in myproject but was in", attributes.Namespace)
+		}
+		review := attributes.Verb + " " + attributes.Resource
+		*reviewed = append(*reviewed, review)
+		status := authv1.SubjectAccessReviewStatus{Allowed: true}
+		for _, d := range denied {
+			if d == review {
+				status = authv1.SubjectAccessReviewStatus{Reason: "no RBAC policy matched"}
+			}
+		}
+		return true, &authv1.SelfSubjectAccessReview{Status: status}, nil
+	})
+	return k8Client
+}
+
+func TestCheckCommandAccess(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Command        func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command)
+		Flags          []string
+		Denied         []string
+		ExpectReviewed []string
+		ErrorPattern   string
+	}{
+		{
+			Name: "test create integration checks the pod preset and binding can be created",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewCreateCommand(), ic.CreateIntegrationCmd()
+			},
+			Flags:          []string{"--namespace=myproject"},
+			ExpectReviewed: []string{"create podpresets", "create servicebindings"},
+		},
+		{
+			Name: "test create integration reports the missing permissions before it starts",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewCreateCommand(), ic.CreateIntegrationCmd()
+			},
+			Flags:          []string{"--namespace=myproject", "--auto-redeploy"},
+			Denied:         []string{"create servicebindings", "update deployments", "update statefulsets", "patch deploymentconfigs"},
+			ExpectReviewed: []string{"create podpresets", "create servicebindings", "update deployments", "update statefulsets", "patch deploymentconfigs"},
+			ErrorPattern:   "^create integration needs you to be allowed to create servicebindings.servicecatalog.k8s.io, update deployments.apps or update statefulsets.apps or patch deploymentconfigs.apps.openshift.io in the namespace myproject. Run mobile auth can-i create integration for the details$",
+		},
+		{
+			Name: "test --auto-redeploy only needs one of the kinds of workload",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewCreateCommand(), ic.CreateIntegrationCmd()
+			},
+			Flags:          []string{"--namespace=myproject", "--auto-redeploy"},
+			Denied:         []string{"update statefulsets", "patch deploymentconfigs"},
+			ExpectReviewed: []string{"create podpresets", "create servicebindings", "update deployments", "update statefulsets", "patch deploymentconfigs"},
+		},
+		{
+			Name: "test the permissions of --auto-redeploy are only checked when it is set",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewDeleteComand(), ic.DeleteIntegrationCmd()
+			},
+			Flags:          []string{"--namespace=myproject", "--auto-redeploy=false"},
+			Denied:         []string{"update deployments"},
+			ExpectReviewed: []string{"delete podpresets", "delete servicebindings"},
+		},
+		{
+			Name: "test check integrations --fix checks the workloads can be relabelled",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewCheckCommand(), ic.CheckIntegrationsCmd()
+			},
+			Flags:          []string{"--namespace=myproject", "--fix"},
+			Denied:         []string{"update deployments", "update statefulsets", "patch deploymentconfigs"},
+			ExpectReviewed: []string{"create podpresets", "delete podpresets", "update deployments", "update statefulsets", "patch deploymentconfigs"},
+			ErrorPattern:   "^check integrations needs you to be allowed to update deployments.apps or update statefulsets.apps or patch deploymentconfigs.apps.openshift.io in the namespace myproject",
+		},
+		{
+			Name: "test delete client does not check the services can be deleted with --keep-services",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewDeleteComand(), cmd.NewClientCmd(&mcFake.Clientset{}, &scFake.Clientset{}, &kFake.Clientset{}, ioutil.Discard).DeleteClientCmd()
+			},
+			Flags:          []string{"--namespace=myproject", "--keep-services"},
+			Denied:         []string{"delete serviceinstances"},
+			ExpectReviewed: []string{"delete secrets", "delete mobileclients"},
+		},
+		{
+			Name: "test dry runs are not checked",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewCreateCommand(), ic.CreateIntegrationCmd()
+			},
+			Flags:  []string{"--namespace=myproject", "--dry-run"},
+			Denied: []string{"create podpresets", "create servicebindings"},
+		},
+		{
+			Name: "test commands that change nothing are not checked",
+			Command: func(ic *cmd.IntegrationCmd) (*cobra.Command, *cobra.Command) {
+				return cmd.NewGetCommand(), ic.ListIntegrationsCmd()
+			},
+			Flags: []string{"--namespace=myproject"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reviewed []string
+			k8Client := reviewingClient(t, tc.Denied, &reviewed)
+			var out bytes.Buffer
+			ic := cmd.NewIntegrationCmd(&scFake.Clientset{}, k8Client, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+			root := cmd.NewRootCmd()
+			parent, command := tc.Command(ic)
+			parent.AddCommand(command)
+			root.AddCommand(parent)
+			if err := command.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := cmd.CheckCommandAccess(k8Client)(command, []string{})
+			if tc.ErrorPattern == "" && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ErrorPattern != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+					t.Fatalf("expected the error to match the pattern %s but got %s", tc.ErrorPattern, err.Error())
+				}
+			}
+			if strings.Join(reviewed, ",") != strings.Join(tc.ExpectReviewed, ",") {
+				t.Fatalf("expected the reviews %v but got %v", tc.ExpectReviewed, reviewed)
+			}
+		})
+	}
+}
+
+func TestAccessCmd_CanICmd(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Args         []string
+		Denied       []string
+		ErrorPattern string
+		Validate     func(t *testing.T, checks []cmd.AccessCheck)
+	}{
+		{
+			Name:   "test can-i lists the permissions of the command and their flags",
+			Args:   []string{"delete", "serviceinstance"},
+			Denied: []string{"delete podpresets"},
+			Validate: func(t *testing.T, checks []cmd.AccessCheck) {
+				var actual []string
+				for _, c := range checks {
+					line := c.Verb + " " + c.Resource + " " + c.Flag
+					if !c.Allowed {
+						line += " denied: " + c.Reason
+					}
+					actual = append(actual, line)
+				}
+				expected := []string{"delete serviceinstances ", "delete secrets ", "delete podpresets cascade denied: no RBAC policy matched", "delete servicebindings cascade",
+					"update deployments cascade", "update statefulsets cascade", "patch deploymentconfigs cascade"}
+				if strings.Join(actual, ",") != strings.Join(expected, ",") {
+					t.Fatalf("expected the checks %v but got %v", expected, actual)
+				}
+			},
+		},
+		{
+			Name: "test can-i lists the permissions not needed with a flag of the command",
+			Args: []string{"delete", "client"},
+			Validate: func(t *testing.T, checks []cmd.AccessCheck) {
+				var actual []string
+				for _, c := range checks {
+					actual = append(actual, c.Verb+" "+c.Resource+" "+c.Unless)
+				}
+				expected := []string{"delete podpresets keep-services", "delete servicebindings keep-services", "delete serviceinstances keep-services", "delete secrets ", "delete mobileclients "}
+				if strings.Join(actual, ",") != strings.Join(expected, ",") {
+					t.Fatalf("expected the checks %v but got %v", expected, actual)
+				}
+			},
+		},
+		{
+			Name: "test can-i lists the kinds of workload --auto-redeploy needs as alternatives",
+			Args: []string{"create", "integration"},
+			Validate: func(t *testing.T, checks []cmd.AccessCheck) {
+				var actual []string
+				for _, c := range checks {
+					actual = append(actual, c.Verb+" "+c.Resource+" "+c.AnyOf)
+				}
+				expected := []string{"create podpresets ", "create servicebindings ", "update deployments workloads", "update statefulsets workloads", "patch deploymentconfigs workloads"}
+				if strings.Join(actual, ",") != strings.Join(expected, ",") {
+					t.Fatalf("expected the checks %v but got %v", expected, actual)
+				}
+			},
+		},
+		{
+			Name:         "test can-i lists the commands it knows for an unknown one",
+			Args:         []string{"get", "clients"},
+			ErrorPattern: "^unknown command get clients, the commands changing the namespace are apply, check integrations, create client,",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var reviewed []string
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			canI := cmd.NewAccessCmd(reviewingClient(t, tc.Denied, &reviewed), &out).CanICmd()
+			root.AddCommand(canI)
+			if err := canI.ParseFlags([]string{"--namespace=myproject", "-o=json"}); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := canI.RunE(canI, tc.Args)
+			if tc.ErrorPattern != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+					t.Fatalf("expected the error to match the pattern %s but got %s", tc.ErrorPattern, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			var checks []cmd.AccessCheck
+			if err := json.Unmarshal(out.Bytes(), &checks); err != nil {
+				t.Fatal("failed to unmarshal the access checks", err)
+			}
+			tc.Validate(t, checks)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// previousAPIKeyAnnotation keeps the api key replaced by a rotation valid until previousAPIKeyExpiresAnnotation
+	previousAPIKeyAnnotation        = "mobile.k8s.io/previous-api-key"
+	previousAPIKeyExpiresAnnotation = "mobile.k8s.io/previous-api-key-expires"
+	// the services read the old key and its expiry time from the data of their integration secret, under these keys suffixed
+	// with the client ID as a secret can hold the keys of several clients
+	previousAPIKeyData        = "previousApiKey."
+	previousAPIKeyExpiresData = "previousApiKeyExpires."
+)
+
+// RotateClientAPIKeyCmd builds the rotate client-apikey command
+func (cc *ClientCmd) RotateClientAPIKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client-apikey <clientID>",
+		Short: "replace the api key of a mobile client with a new one",
+		Long: `rotate client-apikey generates a new api key for the mobile client, for example when the current one has leaked.
+The services integrated with mcp-mobile-keys get the new key in place of the old one in the secret of their integration.
+
+With --grace-period the old key is kept in the mobile.k8s.io/previous-api-key annotation of the mobile client and of the updated
+integration secrets, with its expiry time in mobile.k8s.io/previous-api-key-expires, so the released apps can be updated before it stops working.
+The services read it from the previousApiKey.<clientID> and previousApiKeyExpires.<clientID> keys of their integration secret.
+The old key is not removed when it expires, the services check the expiry time, and the next rotation replaces or removes it.
+Otherwise the old key is no longer valid once the command completes.
+Run the "mobile get clients" command from this tool to get the client ID.`,
+		Example: `  mobile rotate client-apikey <clientID> --namespace=myproject
+  mobile rotate client-apikey <clientID> --grace-period=72h
+  kubectl plugin mobile rotate client-apikey <clientID>
+  oc plugin mobile rotate client-apikey <clientID>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			clientID := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			gracePeriod, err := cmd.PersistentFlags().GetDuration("grace-period")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if gracePeriod < 0 {
+				return errors.New("--grace-period can not be negative")
+			}
+
+			client, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Get(clientID, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get mobile client with clientID "+clientID)
+			}
+			oldKey := client.Spec.ApiKey
+			newKey, err := newAPIKey()
+			if err != nil {
+				return err
+			}
+			secrets, err := cc.apiKeySecrets(ns, oldKey)
+			if err != nil {
+				return err
+			}
+
+			annotations := map[string]string{}
+			var expires string
+			if gracePeriod > 0 && oldKey != "" {
+				expires = time.Now().Add(gracePeriod).UTC().Format(time.RFC3339)
+				annotations[previousAPIKeyAnnotation] = oldKey
+				annotations[previousAPIKeyExpiresAnnotation] = expires
+			}
+			for i := range secrets {
+				for k, v := range secrets[i].Data {
+					secrets[i].Data[k] = bytes.Replace(v, []byte(oldKey), []byte(newKey), -1)
+				}
+				setPreviousAPIKey(&secrets[i].ObjectMeta, annotations)
+				setPreviousAPIKeyData(&secrets[i], clientID, annotations)
+			}
+			client.Spec.ApiKey = newKey
+			setPreviousAPIKey(&client.ObjectMeta, annotations)
+
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				dryRun.patch("MobileClient", clientID, previousAPIKeyPatch(annotations, map[string]interface{}{"spec": map[string]interface{}{"apiKey": newKey}}))
+				for _, s := range secrets {
+					data := map[string]interface{}{previousAPIKeyData + clientID: nil, previousAPIKeyExpiresData + clientID: nil}
+					for k, v := range s.Data {
+						data[k] = v
+					}
+					dryRun.patch("Secret", s.Name, previousAPIKeyPatch(annotations, map[string]interface{}{"data": data}))
+				}
+				return dryRun.render(cc.Out, cmd.Flags())
+			}
+
+			// the client holds the key the apps are given so it is changed first
+			if _, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Update(client); err != nil {
+				return errors.Wrap(err, "failed to update the api key of mobile client "+clientID)
+			}
+			rotation := &APIKeyRotation{ClientID: clientID, APIKey: newKey, PreviousAPIKeyExpires: expires}
+			for i := range secrets {
+				if _, err := cc.k8Client.CoreV1().Secrets(ns).Update(&secrets[i]); err != nil {
+					return errors.Wrap(err, fmt.Sprintf("the mobile client %s uses the new api key but the integration secret %s still has the old one", clientID, secrets[i].Name))
+				}
+				rotation.UpdatedSecrets = append(rotation.UpdatedSecrets, secrets[i].Name)
+			}
+
+			outType := outputType(cmd.Flags())
+			if err := cc.Out.Render("rotate"+cmd.Name(), outType, rotation); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "api key rotation", outType))
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().Duration("grace-period", 0, "--grace-period=72h keep the old api key valid for this long so released apps can be updated")
+	cc.Out.AddRenderer("rotate"+cmd.Name(), "table", func(out io.Writer, data interface{}) error {
+		rotation := data.(*APIKeyRotation)
+		expires := rotation.PreviousAPIKeyExpires
+		if expires == "" {
+			expires = "now"
+		}
+		table := tablewriter.NewWriter(out)
+		table.SetHeader([]string{"ID", "ApiKey", "Previous ApiKey Expires", "Updated Integration Secrets"})
+		table.Append([]string{rotation.ClientID, rotation.APIKey, expires, strings.Join(rotation.UpdatedSecrets, ",\n")})
+		table.Render()
+		return nil
+	})
+	return cmd
+}
+
+// apiKeySecrets returns the secrets of the integrations with mcp-mobile-keys holding the api key
+func (cc *ClientCmd) apiKeySecrets(ns, apiKey string) ([]v1.Secret, error) {
+	if apiKey == "" {
+		return nil, nil
+	}
+	bindings, err := cc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the integrations")
+	}
+	var secrets []v1.Secret
+	for _, b := range bindings.Items {
+		if b.Annotations["provider"] != IntegrationAPIKeys || b.Spec.SecretName == "" {
+			continue
+		}
+		secret, err := cc.k8Client.CoreV1().Secrets(ns).Get(b.Spec.SecretName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// the binding is not ready yet, it will get the current key
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the secret of integration "+b.Name)
+		}
+		for _, v := range secret.Data {
+			if bytes.Contains(v, []byte(apiKey)) {
+				secrets = append(secrets, *secret)
+				break
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// setPreviousAPIKey sets the previous api key annotations or removes them when there are none
+func setPreviousAPIKey(meta *metav1.ObjectMeta, annotations map[string]string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	for _, k := range []string{previousAPIKeyAnnotation, previousAPIKeyExpiresAnnotation} {
+		if v, ok := annotations[k]; ok {
+			meta.Annotations[k] = v
+		} else {
+			delete(meta.Annotations, k)
+		}
+	}
+}
+
+// setPreviousAPIKeyData sets the old key of the client and its expiry time in the data of the integration secret, or removes them when there is none
+func setPreviousAPIKeyData(secret *v1.Secret, clientID string, annotations map[string]string) {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	keys := map[string]string{
+		previousAPIKeyData + clientID:        previousAPIKeyAnnotation,
+		previousAPIKeyExpiresData + clientID: previousAPIKeyExpiresAnnotation,
+	}
+	for k, annotation := range keys {
+		if v, ok := annotations[annotation]; ok {
+			secret.Data[k] = []byte(v)
+		} else {
+			delete(secret.Data, k)
+		}
+	}
+}
+
+// previousAPIKeyPatch adds the previous api key annotations to the merge patch, removing them when there are none
+func previousAPIKeyPatch(annotations map[string]string, patch map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{previousAPIKeyAnnotation: nil, previousAPIKeyExpiresAnnotation: nil}
+	for k, v := range annotations {
+		values[k] = v
+	}
+	patch["metadata"] = map[string]interface{}{"annotations": values}
+	return patch
+}
+
+// newAPIKey generates a random (version 4) UUID as the api key, which matches the apiKey pattern of the MobileClient CRD
+func newAPIKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate a new api key")
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
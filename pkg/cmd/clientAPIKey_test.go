@@ -0,0 +1,183 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
+	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kFake "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
+
+const oldAPIKey = "1f3a2b4c-5d6e-4f70-8192-a3b4c5d6e7f8"
+
+func TestMobileClientsCmd_RotateClientAPIKeyCmd(t *testing.T) {
+	// apiKeyPattern is the pattern of the apiKey in the MobileClient CRD
+	apiKeyPattern := regexp.MustCompile(`(\w{8}-\w{4}-\w{4}-\w{4}-\w{11})`)
+
+	cases := []struct {
+		Name         string
+		Args         []string
+		Flags        []string
+		ExpectError  bool
+		ErrorPattern string
+		Validate     func(t *testing.T, out []byte, mobileClient mobile.Interface, k8Client kubernetes.Interface)
+	}{
+		{
+			Name:  "test rotate client-apikey replaces the key in the client and the mobile keys integrations",
+			Args:  []string{"myapp-android"},
+			Flags: []string{"--namespace=test", "-o=json"},
+			Validate: func(t *testing.T, out []byte, mobileClient mobile.Interface, k8Client kubernetes.Interface) {
+				rotation := cmd.APIKeyRotation{}
+				if err := json.Unmarshal(out, &rotation); err != nil {
+					t.Fatal("failed to unmarshal the rotation", err)
+				}
+				client, err := mobileClient.MobileV1alpha1().MobileClients("test").Get("myapp-android", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal("failed to get the mobile client", err)
+				}
+				if client.Spec.ApiKey == oldAPIKey || client.Spec.ApiKey != rotation.APIKey || !apiKeyPattern.MatchString(client.Spec.ApiKey) {
+					t.Fatalf("expected a new api key matching the CRD pattern but got %s", client.Spec.ApiKey)
+				}
+				if _, ok := client.Annotations["mobile.k8s.io/previous-api-key"]; ok || rotation.PreviousAPIKeyExpires != "" {
+					t.Fatal("expected the old key not to be kept without a grace period")
+				}
+				keys, _ := k8Client.CoreV1().Secrets("test").Get("sync-mobile-keys", metav1.GetOptions{})
+				if string(keys.Data["clients"]) != `{"myapp-android":"`+client.Spec.ApiKey+`"}` {
+					t.Fatal("expected the integration secret to have the new key but got", string(keys.Data["clients"]))
+				}
+				if _, ok := keys.Data["previousApiKey.myapp-android"]; ok {
+					t.Fatal("expected the old key of an earlier rotation to be removed from the integration secret")
+				}
+				other, _ := k8Client.CoreV1().Secrets("test").Get("sync-keycloak", metav1.GetOptions{})
+				if string(other.Data["apiKey"]) != oldAPIKey {
+					t.Fatal("expected the secrets of other integrations to be left alone")
+				}
+				if strings.Join(rotation.UpdatedSecrets, ",") != "sync-mobile-keys" {
+					t.Fatal("expected the updated secrets to be reported but got", rotation.UpdatedSecrets)
+				}
+			},
+		},
+		{
+			Name:  "test rotate client-apikey with a grace period keeps the old key",
+			Args:  []string{"myapp-android"},
+			Flags: []string{"--namespace=test", "--grace-period=1h", "-o=json"},
+			Validate: func(t *testing.T, out []byte, mobileClient mobile.Interface, k8Client kubernetes.Interface) {
+				client, _ := mobileClient.MobileV1alpha1().MobileClients("test").Get("myapp-android", metav1.GetOptions{})
+				keys, _ := k8Client.CoreV1().Secrets("test").Get("sync-mobile-keys", metav1.GetOptions{})
+				for _, meta := range []metav1.ObjectMeta{client.ObjectMeta, keys.ObjectMeta} {
+					if meta.Annotations["mobile.k8s.io/previous-api-key"] != oldAPIKey {
+						t.Fatalf("expected the old key to be kept on %s but got %v", meta.Name, meta.Annotations)
+					}
+					expires, err := time.Parse(time.RFC3339, meta.Annotations["mobile.k8s.io/previous-api-key-expires"])
+					if err != nil || expires.Before(time.Now().Add(59*time.Minute)) || expires.After(time.Now().Add(time.Hour)) {
+						t.Fatalf("expected the old key to expire in an hour on %s but got %v", meta.Name, meta.Annotations)
+					}
+				}
+				if string(keys.Data["previousApiKey.myapp-android"]) != oldAPIKey {
+					t.Fatalf("expected the old key to be kept in the data of the integration secret but got %v", keys.Data)
+				}
+				if string(keys.Data["previousApiKeyExpires.myapp-android"]) != keys.Annotations["mobile.k8s.io/previous-api-key-expires"] {
+					t.Fatalf("expected the expiry time of the old key in the data of the integration secret but got %v", keys.Data)
+				}
+			},
+		},
+		{
+			Name:  "test rotate client-apikey with --dry-run changes nothing",
+			Args:  []string{"myapp-android"},
+			Flags: []string{"--namespace=test", "--dry-run"},
+			Validate: func(t *testing.T, out []byte, mobileClient mobile.Interface, k8Client kubernetes.Interface) {
+				var changes []cmd.DryRunChange
+				if err := yaml.Unmarshal(out, &changes); err != nil {
+					t.Fatal("failed to unmarshal the dry run changes", err)
+				}
+				if len(changes) != 2 || changes[0].Kind != "MobileClient" || changes[1].Kind != "Secret" || changes[1].Name != "sync-mobile-keys" {
+					t.Fatalf("expected the client and the integration secret to be patched but got %v", changes)
+				}
+				client, _ := mobileClient.MobileV1alpha1().MobileClients("test").Get("myapp-android", metav1.GetOptions{})
+				if client.Spec.ApiKey != oldAPIKey {
+					t.Fatal("expected the api key not to change but got", client.Spec.ApiKey)
+				}
+			},
+		},
+		{
+			Name:         "test rotate client-apikey fails for an unknown client",
+			Args:         []string{"nope-android"},
+			Flags:        []string{"--namespace=test"},
+			ExpectError:  true,
+			ErrorPattern: "^failed to get mobile client with clientID nope-android",
+		},
+		{
+			Name:         "test rotate client-apikey fails with a negative grace period",
+			Args:         []string{"myapp-android"},
+			Flags:        []string{"--namespace=test", "--grace-period=-1h"},
+			ExpectError:  true,
+			ErrorPattern: "^--grace-period can not be negative$",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mobileClient := mcFake.NewSimpleClientset(&v1alpha1.MobileClient{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp-android", Namespace: "test"},
+				Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ApiKey: oldAPIKey},
+			})
+			scClient := scFake.NewSimpleClientset(
+				&v1beta1.ServiceBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-mobile-keys-binding", Namespace: "test", Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "mcp-mobile-keys"}},
+					Spec:       v1beta1.ServiceBindingSpec{SecretName: "sync-mobile-keys"},
+				},
+				&v1beta1.ServiceBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak-binding", Namespace: "test", Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"}},
+					Spec:       v1beta1.ServiceBindingSpec{SecretName: "sync-keycloak"},
+				},
+			)
+			k8Client := kFake.NewSimpleClientset(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-mobile-keys", Namespace: "test"},
+					Data:       map[string][]byte{"clients": []byte(`{"myapp-android":"` + oldAPIKey + `"}`), "previousApiKey.myapp-android": []byte("an-earlier-key")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak", Namespace: "test"},
+					Data:       map[string][]byte{"apiKey": []byte(oldAPIKey)},
+				},
+			)
+
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			clientCmd := cmd.NewClientCmd(mobileClient, scClient, k8Client, &out)
+			rotateCmd := clientCmd.RotateClientAPIKeyCmd()
+			root.AddCommand(rotateCmd)
+			if err := rotateCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := rotateCmd.RunE(rotateCmd, tc.Args)
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ExpectError {
+				if m, regErr := regexp.Match(tc.ErrorPattern, []byte(err.Error())); !m {
+					t.Fatal("expected the error to match the pattern "+tc.ErrorPattern, err, regErr)
+				}
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, out.Bytes(), mobileClient, k8Client)
+			}
+		})
+	}
+}
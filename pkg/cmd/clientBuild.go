@@ -14,7 +14,10 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
 
 type ClientBuildsCmd struct{}
 
@@ -87,3 +90,18 @@ func (cbc *ClientBuildsCmd) StartClientBuildsCmd() *cobra.Command {
 	}
 	return cmd
 }
+
+func (cbc *ClientBuildsCmd) WaitClientBuildsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clientbuild <clientBuildID>",
+		Short: "wait for a build of a mobile client to reach a condition",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return errors.New("waiting for clientbuilds is not supported yet as clientbuilds cannot be created by this tool")
+		},
+	}
+	addWaitFlags(cmd)
+	return cmd
+}
@@ -20,6 +20,7 @@ import (
 	"regexp"
 
 	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
 	sc "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
 	"github.com/olekukonko/tablewriter"
@@ -63,8 +64,6 @@ kubectl plugin mobile get clientconfig`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var ns string
 			var err error
-			var dmzRegexp = regexp.MustCompile("http(s)?://.*/")
-			ret := make([]*ServiceConfig, 0)
 
 			if len(args) != 1 {
 				return cmd.Usage()
@@ -80,26 +79,9 @@ kubectl plugin mobile get clientconfig`,
 				return errors.Wrap(err, "failed to get mobile client with id "+clientID)
 			}
 
-			filter := v1.ListOptions{LabelSelector: fmt.Sprintf("clientId=%s", clientID)}
-			secrets, err := ccc.k8Client.CoreV1().Secrets(ns).List(filter)
+			ret, err := clientServiceConfigs(ccc.k8Client, ns, clientID, mc)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("Error retrieving secrets with clientId %s", clientID))
-			}
-			for _, secret := range secrets.Items {
-				convertor := defaultSecretConvertor{}
-				svcConfig, err := convertor.Convert(secret)
-				if err != nil {
-					return err
-				}
-				if nil != mc && mc.Spec.DmzUrl != "" {
-					var dmzURL = mc.Spec.DmzUrl
-					if dmzURL[len(dmzURL)-1:] != "/" {
-						dmzURL += "/"
-					}
-					dmzURL = dmzURL + "mobile/" + svcConfig.Name + "/"
-					svcConfig.URL = string(dmzRegexp.ReplaceAll([]byte(svcConfig.URL), []byte(dmzURL)))
-				}
-				ret = append(ret, svcConfig)
+				return err
 			}
 
 			outputJSON := ServiceConfigs{
@@ -146,3 +128,32 @@ kubectl plugin mobile get clientconfig`,
 	cmd.Flags().BoolVar(&includeCertificatePins, "include-cert-pins", false, "include certificate hashes for services in the client config")
 	return cmd
 }
+
+// clientServiceConfigs converts the secrets labelled with the ID of the mobile client into its service configs,
+// pointing their URLs at the DMZ URL of the client when it has one
+func clientServiceConfigs(k8Client kubernetes.Interface, ns, clientID string, mc *v1alpha1.MobileClient) ([]*ServiceConfig, error) {
+	var dmzRegexp = regexp.MustCompile("http(s)?://.*/")
+	ret := make([]*ServiceConfig, 0)
+	filter := v1.ListOptions{LabelSelector: fmt.Sprintf("clientId=%s", clientID)}
+	secrets, err := k8Client.CoreV1().Secrets(ns).List(filter)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Error retrieving secrets with clientId %s", clientID))
+	}
+	for _, secret := range secrets.Items {
+		convertor := defaultSecretConvertor{}
+		svcConfig, err := convertor.Convert(secret)
+		if err != nil {
+			return nil, err
+		}
+		if nil != mc && mc.Spec.DmzUrl != "" {
+			var dmzURL = mc.Spec.DmzUrl
+			if dmzURL[len(dmzURL)-1:] != "/" {
+				dmzURL += "/"
+			}
+			dmzURL = dmzURL + "mobile/" + svcConfig.Name + "/"
+			svcConfig.URL = string(dmzRegexp.ReplaceAll([]byte(svcConfig.URL), []byte(dmzURL)))
+		}
+		ret = append(ret, svcConfig)
+	}
+	return ret, nil
+}
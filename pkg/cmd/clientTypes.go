@@ -0,0 +1,124 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mobileClientTypeTag marks the service classes provisioning a type of mobile client
+const mobileClientTypeTag = "mobile-client-type"
+
+// legacyClientTypes are provisioned by the <clientType>-app service classes that predate the mobile-client-type tag
+var legacyClientTypes = []string{"android", "cordova", "iOS", "xamarin"}
+
+// clientType is a type of mobile client and the APB provisioning it
+type clientType struct {
+	Name    string
+	APBName string
+}
+
+// hasTag returns whether the service class has the tag
+func hasTag(class v1beta1.ClusterServiceClass, tag string) bool {
+	for _, t := range class.Spec.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// classClientType returns the type of mobile client provisioned by the service class, if any.
+// Tagged classes name it with clientType in their metadata, otherwise it is their serviceName without the -app suffix
+func classClientType(class v1beta1.ClusterServiceClass) (*clientType, error) {
+	if class.Spec.ExternalMetadata == nil {
+		return nil, nil
+	}
+	var extData ExternalServiceMetaData
+	if err := json.Unmarshal(class.Spec.ExternalMetadata.Raw, &extData); err != nil {
+		return nil, errors.Wrap(err, "failed to read the metadata of the serviceclass "+class.Name)
+	}
+	if hasTag(class, mobileClientTypeTag) {
+		name := extData.ClientType
+		if name == "" {
+			name = strings.TrimSuffix(extData.ServiceName, "-app")
+		}
+		return &clientType{Name: name, APBName: extData.ServiceName}, nil
+	}
+	for _, legacy := range legacyClientTypes {
+		if extData.ServiceName == legacy+"-app" {
+			return &clientType{Name: legacy, APBName: extData.ServiceName}, nil
+		}
+	}
+	return nil, nil
+}
+
+// listClientTypes returns the types of mobile client that can be provisioned in the cluster sorted by name
+func listClientTypes(scClient versioned.Interface) ([]clientType, error) {
+	classes, err := scClient.ServicecatalogV1beta1().ClusterServiceClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service classes")
+	}
+	if classes == nil {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	var types []clientType
+	for _, class := range classes.Items {
+		ct, err := classClientType(class)
+		if err != nil {
+			return nil, err
+		}
+		if ct == nil || seen[ct.Name] {
+			continue
+		}
+		seen[ct.Name] = true
+		types = append(types, *ct)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+	return types, nil
+}
+
+// clientTypeNames returns the names of the client types
+func clientTypeNames(types []clientType) []string {
+	names := []string{}
+	for _, t := range types {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// findClientType returns the client type with the name along with the names of all the client types
+func findClientType(scClient versioned.Interface, name string) (*clientType, []string, error) {
+	types, err := listClientTypes(scClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range types {
+		if t.Name == name {
+			return &t, clientTypeNames(types), nil
+		}
+	}
+	return nil, nil, errors.New("Unknown client type " + name + ", the available client types are " + strings.Join(clientTypeNames(types), ","))
+}
@@ -15,25 +15,34 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/aerogear/mobile-cli/pkg/cmd/input"
 	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
 	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
 	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
 	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
+	"github.com/ghodss/yaml"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
 )
 
 type ClientCmd struct {
@@ -134,19 +143,45 @@ oc plugin mobile get client <clientID>`,
 // CreateClientCmd builds the create mobileclient command
 func (cc *ClientCmd) CreateClientCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "client <name> <clientType iOS|cordova|android|xamarin> <appIdentifier bundleID|packageName>",
+		Use:   "client <name> <clientType> <appIdentifier bundleID|packageName> | --from=<namespace>/<clientID>",
 		Short: "create a mobile client representation in your namespace",
 		Long: `create client sets up the representation of a mobile application of the specified type in your namespace.
 		       This is used to provide a mobile client context for various actions such as creating, starting or stopping mobile client builds.
 
-		       The available client types are android, cordova, iOS and xamarin.
+		       The available client types are those of the service classes tagged mobile-client-type, such as android, cordova, iOS and xamarin.
+
+		       --from copies an existing mobile client from another namespace, for example to promote an app from a dev to a test project.
+		       The client is provisioned with the plan and parameters of the original, then its dmzUrl, excluded services and
+		       client service configs are carried over for the services that also exist in your namespace. The services without a counterpart are reported.
 
 		       When used standalone, a namespace must be specified by providing the --namespace flag.`,
 		Example: `  mobile create client <name> <clientType> <appIdentifier> --namespace=myproject 
+  mobile create client --from=dev/myapp-android --namespace=test
   					kubectl plugin mobile create client <name> <clientType> <appIdentifier>
 					oc plugin mobile create client <name> <clientType> <appIdentifier>`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := cmd.PersistentFlags().GetString("from")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			noWait, err := cmd.PersistentFlags().GetBool("no-wait")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			var source *clientSource
+			if from != "" {
+				if len(args) != 0 {
+					return cmd.Usage()
+				}
+				if noWait {
+					return errors.New("--no-wait can not be used with --from as the configuration is copied once the mobile client is provisioned")
+				}
+				if source, err = cc.readClientSource(from); err != nil {
+					return err
+				}
+				args = []string{source.Client.Spec.Name, source.Client.Spec.ClientType, source.Client.Spec.AppIdentifier}
+			}
 			if len(args) != 3 {
 				return cmd.Usage()
 			}
@@ -160,79 +195,71 @@ func (cc *ClientCmd) CreateClientCmd() *cobra.Command {
 				return errors.Wrap(err, "failed to get namespace")
 			}
 
-			if appIdentifier == "" {
-				return errors.New("failed validation while creating new mobile client")
-			}
-
-			var apbName string
-			switch clientType {
-			case "android", "iOS", "cordova", "xamarin":
-				apbName = clientType + "-app"
-			default:
-				return errors.New("Unknown client type")
-
-			}
-
-			clientId := strings.ToLower(name + "-" + clientType)
-			client, err := cc.mobileClient.MobileV1alpha1().MobileClients(namespace).Get(clientId, metav1.GetOptions{})
-			if client.ObjectMeta.UID != "" {
-				return errors.New("App with this name already exist for this client type")
-			}
-
-			if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("\"%s\" not found", clientId)) {
-				return errors.Wrap(err, "failed to check if application name exists")
+			planName, err := cmd.PersistentFlags().GetString("plan")
+			if err != nil {
+				return errors.WithStack(err)
 			}
-
-			//Get available provision parameters from the cluster service plan
-			clusterServiceClass, err := findServiceClassByName(cc.scClient, apbName)
+			parameters, err := paramsFromFlags(cmd.Flags())
 			if err != nil {
-				return errors.Wrap(err, "failed to find ServiceClass by name")
+				return errors.WithStack(err)
 			}
-
-			validServiceName := clusterServiceClass.Spec.ExternalName
-			extMeta := clusterServiceClass.Spec.ExternalMetadata.Raw
-			var extServiceClass ExternalServiceMetaData
-			if err := json.Unmarshal(extMeta, &extServiceClass); err != nil {
-				return errors.Wrap(err, "failed to read ClusterServiceClass")
+			if source != nil {
+				if planName == "" {
+					planName = source.Plan
+				}
+				// the parameters given override those of the original
+				for k, v := range parameters {
+					source.Params[k] = v
+				}
+				parameters = source.Params
 			}
-
-			secretName := clientId + "-apb-" + "params"
-			si := buildServiceInstance(namespace, validServiceName+"-", secretName, *clusterServiceClass)
-
-			if _, err := cc.scClient.ServicecatalogV1beta1().ServiceInstances(namespace).Create(&si); err != nil {
-				return errors.Wrap(err, "failed to create mobile client")
+			client, err := cc.buildClient(namespace, name, clientType, appIdentifier, planName, parameters, isInteractive(cmd.Flags()))
+			if err != nil {
+				return err
 			}
-			fmt.Println("Creating Mobile Client")
-
-			parameters := map[string]string{
-				"appName":       name,
-				"appIdentifier": appIdentifier,
+			clientId := client.ID
+			var copied *clientCopy
+			if source != nil {
+				if copied, err = cc.planClientCopy(namespace, source); err != nil {
+					return err
+				}
+				for _, note := range copied.Notes {
+					fmt.Fprintln(os.Stderr, note)
+				}
 			}
 
-			secretData, err := json.Marshal(parameters)
+			dryRun, err := newDryRun(cmd.Flags(), namespace)
 			if err != nil {
-				return errors.Wrap(err, "invalid secret data")
+				return err
 			}
-
-			pSecret := v1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: secretName,
-				},
-				Data: map[string][]byte{
-					"parameters": secretData,
-				},
-			}
-
-			if _, err := cc.k8Client.CoreV1().Secrets(namespace).Create(&pSecret); err != nil {
-				return errors.Wrap(err, "failed to create secret")
+			if dryRun != nil {
+				if _, err := dryRun.create(cc.scClient.ServicecatalogV1beta1().RESTClient(), serviceInstancesResource, "ServiceInstance", &client.Instance); err != nil {
+					return err
+				}
+				if _, err := dryRun.create(cc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", &client.ParamsSecret); err != nil {
+					return err
+				}
+				if copied != nil {
+					dryRun.patch("MobileClient", clientId, copied.specPatch())
+					for i := range copied.Configs {
+						if _, err := dryRun.create(cc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", &copied.Configs[i]); err != nil {
+							return err
+						}
+					}
+				}
+				return dryRun.render(cc.Out, cmd.Flags())
 			}
 
-			noWait, err := cmd.PersistentFlags().GetBool("no-wait")
+			// the objects created are deleted again if a later step fails
+			steps := newJournal(cmd.Flags())
+			created, err := cc.provisionClient(namespace, client, steps)
 			if err != nil {
-				return errors.WithStack(err)
+				return steps.finish(err)
 			}
+			fmt.Println("Creating Mobile Client")
+
 			if noWait {
-				return nil
+				return steps.finish(nil)
 			}
 			cc.Out.AddRenderer("create"+cmd.Name(), "table", func(writer io.Writer, mobileClient interface{}) error {
 				var data [][]string
@@ -245,63 +272,454 @@ func (cc *ClientCmd) CreateClientCmd() *cobra.Command {
 				return nil
 			})
 
-			timeout := int64(10 * 60) // ten minutes
-			w, err := cc.scClient.ServicecatalogV1beta1().ServiceInstances(namespace).Watch(metav1.ListOptions{TimeoutSeconds: &timeout})
+			waiter, err := newWaiter(cmd.Flags(), created.Name, cc.scClient.ServicecatalogV1beta1().ServiceInstances(namespace).Watch)
+			if err != nil {
+				return steps.finish(err)
+			}
+			if err := waiter.Until(wait.ServiceInstanceReady(0)); err != nil {
+				return steps.finish(errors.Wrap(err, "Failed to provision "+client.ServiceName))
+			}
 
+			outType := outputType(cmd.Flags())
+			mClient, err := cc.mobileClient.MobileV1alpha1().MobileClients(namespace).Get(clientId, metav1.GetOptions{})
 			if err != nil {
-				return errors.WithStack(err)
+				return steps.finish(errors.Wrap(err, "Cant get client post creation, something went wrong"))
 			}
-			for {
-				select {
-				case msg, ok := <-w.ResultChan():
-					if !ok {
-						fmt.Println("Timedout waiting. It seems to be taking a long time for the Mobile Client to provision. Your Mobile Client service may still be provisioning.")
-						return nil
-					}
-					o := msg.Object.(*v1beta1.ServiceInstance)
-					switch msg.Type {
-					case watch.Error:
-						w.Stop()
-						return errors.New("unexpected error watching ServiceInstance " + err.Error())
-					case watch.Modified:
-						for _, c := range o.Status.Conditions {
-							if c.Type == "Ready" && c.Status == "True" {
-								w.Stop()
-
-								outType := outputType(cmd.Flags())
-								mClient, err := cc.mobileClient.MobileV1alpha1().MobileClients(namespace).Get(clientId, metav1.GetOptions{})
-								if err != nil {
-									return errors.Wrap(err, "Cant get client post creation, something went wrong")
-								}
-								if err := cc.Out.Render("create"+cmd.Name(), outType, mClient); err != nil {
-									return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "mobile client", outType))
-								}
-								return nil
-							}
-
-							if c.Type == "Failed" {
-								w.Stop()
-								return errors.New("Failed to provision " + extServiceClass.ServiceName + ". " + c.Message)
-							}
-						}
-					}
+			if copied != nil {
+				if mClient, err = cc.applyClientCopy(namespace, mClient, copied, steps); err != nil {
+					return steps.finish(err)
 				}
 			}
+			if err := steps.finish(nil); err != nil {
+				return err
+			}
+			if err := cc.Out.Render("create"+cmd.Name(), outType, mClient); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "mobile client", outType))
+			}
+			return nil
 		},
 	}
 
 	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the service is fully provisioned")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the mobile client to be provisioned before giving up. 0 waits forever")
+	cmd.PersistentFlags().String("plan", "", "--plan=<planName> the service plan used to provision the mobile client service, the default plan when not set")
+	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set additional parameters for the mobile client service: -p PARAM1=val -p PARAM2=val2. Values can be read from a file with -p PARAM=@file or from the environment with -p PARAM=env:VAR, start the value with \\ to use it as it is: -p PARAM=\\@value")
+	cmd.PersistentFlags().String("params-file", "", "--params-file=params.yaml read additional parameters from a YAML or JSON file. Values set with --params override the values in the file")
+	cmd.PersistentFlags().String("from", "", "--from=<namespace>/<clientID> copy the mobile client and its configuration from another namespace")
+	addNoRollbackFlag(cmd)
+	return cmd
+}
+
+// clientProvision holds the objects provisioning a mobile client
+type clientProvision struct {
+	ID           string
+	ServiceName  string
+	Instance     v1beta1.ServiceInstance
+	ParamsSecret v1.Secret
+}
+
+// buildClient validates the mobile client and builds the service instance and parameters secret provisioning it.
+// The default plan is used when planName is empty
+func (cc *ClientCmd) buildClient(namespace, name, clientType, appIdentifier, planName string, parameters map[string]string, interactive bool) (*clientProvision, error) {
+	if appIdentifier == "" {
+		return nil, errors.New("failed validation while creating new mobile client")
+	}
+
+	ct, clientTypes, err := findClientType(cc.scClient, clientType)
+	if err != nil {
+		return nil, err
+	}
+	apbName := ct.APBName
+	spec := v1alpha1.MobileClientSpec{Name: name, ClientType: clientType, AppIdentifier: appIdentifier}
+	if err := input.ValidateMobileClient(&v1alpha1.MobileClient{Spec: spec}, clientTypes); err != nil {
+		return nil, errors.Wrap(err, "invalid mobile client")
+	}
+	if err := input.ValidateAppIdentifier(clientType, appIdentifier); err != nil {
+		return nil, errors.Wrap(err, "invalid mobile client")
+	}
+
+	clientId := strings.ToLower(name + "-" + clientType)
+	// the client ID names the mobile client and its parameters secret
+	if errs := validation.IsDNS1123Subdomain(clientParamsSecretName(clientId)); len(errs) > 0 {
+		return nil, errors.New("invalid mobile client: the name " + name + " can not be used in the client ID " + clientId + ", " + strings.Join(errs, ", "))
+	}
+	client, err := cc.mobileClient.MobileV1alpha1().MobileClients(namespace).Get(clientId, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "failed to check if application name exists")
+	}
+	if err == nil && client != nil && client.ObjectMeta.UID != "" {
+		return nil, errors.New("App with this name already exist for this client type")
+	}
+
+	//Get available provision parameters from the cluster service plan
+	clusterServiceClass, err := findServiceClassByName(cc.scClient, apbName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find ServiceClass by name")
+	}
+
+	validServiceName := clusterServiceClass.Spec.ExternalName
+	extMeta := clusterServiceClass.Spec.ExternalMetadata.Raw
+	var extServiceClass ExternalServiceMetaData
+	if err := json.Unmarshal(extMeta, &extServiceClass); err != nil {
+		return nil, errors.Wrap(err, "failed to read ClusterServiceClass")
+	}
+
+	if planName == "" {
+		planName = defaultServicePlan
+	}
+	plan, err := findServicePlanByNameAndClass(cc.scClient, planName, clusterServiceClass.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find ServicePlan")
+	}
+
+	params := map[string]string{}
+	for k, v := range parameters {
+		params[k] = v
+	}
+	params["appName"] = name
+	params["appIdentifier"] = appIdentifier
+	// the create schema of the plan adds the defaults and checks the required parameters are set
+	planParams, err := instanceParams(plan, params, interactive)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid parameters for the plan "+planName)
+	}
+	for k, v := range planParams {
+		params[k] = v
+	}
+
+	secretName := clientParamsSecretName(clientId)
+	si := buildServiceInstance(namespace, validServiceName+"-", secretName, *clusterServiceClass, planName)
+
+	secretData, err := json.Marshal(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid secret data")
+	}
+
+	pSecret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: secretName,
+		},
+		Data: map[string][]byte{
+			"parameters": secretData,
+		},
+	}
+	return &clientProvision{ID: clientId, ServiceName: extServiceClass.ServiceName, Instance: si, ParamsSecret: pSecret}, nil
+}
+
+// provisionClient checks the mobile client can be provisioned then creates the parameters secret and the service instance provisioning it
+func (cc *ClientCmd) provisionClient(namespace string, client *clientProvision, steps *journal) (*v1beta1.ServiceInstance, error) {
+	if err := cc.preflight(namespace); err != nil {
+		return nil, err
+	}
+	secrets := cc.k8Client.CoreV1().Secrets(namespace)
+	err := steps.do("secret", func() (string, error) {
+		_, err := secrets.Create(&client.ParamsSecret)
+		return client.ParamsSecret.Name, errors.Wrap(err, "failed to create secret")
+	}, func(name string) error {
+		return secrets.Delete(name, &metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	instances := cc.scClient.ServicecatalogV1beta1().ServiceInstances(namespace)
+	var created *v1beta1.ServiceInstance
+	err = steps.do("service instance", func() (string, error) {
+		var err error
+		if created, err = instances.Create(&client.Instance); err != nil {
+			return "", errors.Wrap(err, "failed to create mobile client")
+		}
+		return created.Name, nil
+	}, func(name string) error {
+		return instances.Delete(name, &metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// clientSource is a mobile client being copied from another namespace
+type clientSource struct {
+	Namespace string
+	Client    *v1alpha1.MobileClient
+	// Plan and Params provisioned the original client, Params leaves out those set from the client itself
+	Plan   string
+	Params map[string]string
+	// ExcludedServices are the services of the excluded service instances
+	ExcludedServices []string
+	Configs          []v1.Secret
+}
+
+// clientCopy is the configuration of a copied mobile client that has a counterpart in the target namespace
+type clientCopy struct {
+	DmzURL           string
+	ExcludedServices []string
+	Configs          []v1.Secret
+	// Notes report what could not be copied
+	Notes []string
+}
+
+// readClientSource reads the mobile client to copy from --from=<namespace>/<clientID>
+func (cc *ClientCmd) readClientSource(from string) (*clientSource, error) {
+	parts := strings.SplitN(from, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("--from must be <namespace>/<clientID> but was " + from)
+	}
+	ns, clientID := parts[0], parts[1]
+	client, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Get(clientID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to get the mobile client %s in %s", clientID, ns))
+	}
+	source := &clientSource{Namespace: ns, Client: client, Params: map[string]string{}}
+
+	si, err := findClientServiceInstance(cc.scClient, ns, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if si != nil {
+		source.Plan = si.Spec.ClusterServicePlanExternalName
+		secret, err := cc.k8Client.CoreV1().Secrets(ns).Get(clientParamsSecretName(clientID), metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrap(err, "failed to get the parameters of mobile client "+clientID)
+		}
+		if err == nil {
+			if err := json.Unmarshal(secret.Data["parameters"], &source.Params); err != nil {
+				return nil, errors.Wrap(err, "failed to read the parameters of mobile client "+clientID)
+			}
+		}
+		delete(source.Params, "appName")
+		delete(source.Params, "appIdentifier")
+	}
+
+	sis, err := cc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances in "+ns)
+	}
+	services := map[string]string{}
+	for _, si := range sis.Items {
+		services[si.Name] = si.Spec.ClusterServiceClassExternalName
+	}
+	for _, excluded := range client.Spec.ExcludedServices {
+		// excluded services are usually instance names but may already be service names
+		if service, ok := services[excluded]; ok {
+			excluded = service
+		}
+		source.ExcludedServices = append(source.ExcludedServices, excluded)
+	}
+
+	secrets, err := cc.k8Client.CoreV1().Secrets(ns).List(metav1.ListOptions{LabelSelector: "clientId=" + clientID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the service configs of mobile client "+clientID)
+	}
+	source.Configs = secrets.Items
+	return source, nil
+}
+
+// instancesByService maps the services in the namespace to the name of their first instance, which is how mobile clients exclude them
+func (cc *ClientCmd) instancesByService(namespace string) (map[string]string, error) {
+	sis, err := cc.scClient.ServicecatalogV1beta1().ServiceInstances(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances")
+	}
+	instances := map[string]string{}
+	for _, si := range sis.Items {
+		if _, ok := instances[si.Spec.ClusterServiceClassExternalName]; !ok {
+			instances[si.Spec.ClusterServiceClassExternalName] = si.Name
+		}
+	}
+	return instances, nil
+}
+
+// planClientCopy matches the excluded services and service configs of the copied client with the services in the namespace
+func (cc *ClientCmd) planClientCopy(namespace string, source *clientSource) (*clientCopy, error) {
+	instances, err := cc.instancesByService(namespace)
+	if err != nil {
+		return nil, err
+	}
+	copied := &clientCopy{DmzURL: source.Client.Spec.DmzUrl}
+	for _, service := range source.ExcludedServices {
+		instance, ok := instances[service]
+		if !ok {
+			copied.Notes = append(copied.Notes, fmt.Sprintf("the excluded service %s has no instance in %s and was not excluded", service, namespace))
+			continue
+		}
+		copied.ExcludedServices = append(copied.ExcludedServices, instance)
+	}
+	for _, config := range source.Configs {
+		service := string(config.Data["name"])
+		if _, ok := instances[service]; !ok {
+			copied.Notes = append(copied.Notes, fmt.Sprintf("the %s service config was not copied as there is no %s service in %s", config.Name, service, namespace))
+			continue
+		}
+		copied.Configs = append(copied.Configs, v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: config.Name, Labels: config.Labels, Annotations: config.Annotations},
+			Type:       config.Type,
+			Data:       config.Data,
+		})
+	}
+	return copied, nil
+}
+
+// specPatch is the merge patch of the copied fields of the mobile client spec
+func (c *clientCopy) specPatch() map[string]interface{} {
+	spec := map[string]interface{}{}
+	if c.DmzURL != "" {
+		spec["dmzUrl"] = c.DmzURL
+	}
+	if len(c.ExcludedServices) > 0 {
+		spec["excludedServices"] = c.ExcludedServices
+	}
+	return map[string]interface{}{"spec": spec}
+}
+
+// applyClientCopy sets the copied fields on the provisioned mobile client and copies its service configs
+func (cc *ClientCmd) applyClientCopy(namespace string, client *v1alpha1.MobileClient, copied *clientCopy, steps *journal) (*v1alpha1.MobileClient, error) {
+	if copied.DmzURL != "" || len(copied.ExcludedServices) > 0 {
+		if copied.DmzURL != "" {
+			client.Spec.DmzUrl = copied.DmzURL
+		}
+		client.Spec.ExcludedServices = append(client.Spec.ExcludedServices, copied.ExcludedServices...)
+		updated, err := cc.mobileClient.MobileV1alpha1().MobileClients(namespace).Update(client)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to copy the configuration of mobile client "+client.Name)
+		}
+		client = updated
+	}
+	secrets := cc.k8Client.CoreV1().Secrets(namespace)
+	for i := range copied.Configs {
+		config := &copied.Configs[i]
+		err := steps.do("service config", func() (string, error) {
+			_, err := secrets.Create(config)
+			return config.Name, err
+		}, func(name string) error {
+			return secrets.Delete(name, &metav1.DeleteOptions{})
+		})
+		if apierrors.IsAlreadyExists(err) {
+			fmt.Fprintf(os.Stderr, "the %s service config already exists in %s and was not copied\n", config.Name, namespace)
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to copy the service config "+config.Name)
+		}
+	}
+	return client, nil
+}
+
+// WaitClientCmd builds the wait mobile client command
+func (cc *ClientCmd) WaitClientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client <clientID>",
+		Short: "wait for a mobile client to be ready or deleted",
+		Long: `wait client blocks until the mobile client is ready or deleted or the timeout is reached.
+A mobile client is ready once the service provisioning it is ready and the client exists in the namespace.
+Run the "mobile get clients" command from this tool to get the client ID.`,
+		Example: `  mobile wait client <clientID> --for=condition=Ready --timeout=5m --namespace=myproject
+  mobile wait client <clientID> --for=delete
+  kubectl plugin mobile wait client <clientID>
+  oc plugin mobile wait client <clientID>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			clientID := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			condition, err := waitFor(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			clients := cc.mobileClient.MobileV1alpha1().MobileClients(ns)
+			if condition == waitForDelete {
+				getClient := func() (runtime.Object, error) {
+					return clients.Get(clientID, metav1.GetOptions{})
+				}
+				return errors.Wrap(waitForDeletion(cmd.Flags(), clientID, getClient, clients.Watch), "failed waiting for mobile client "+clientID+" to be deleted")
+			}
+			if condition != "Ready" {
+				return errors.New("mobile clients can only be waited for with --for=condition=Ready or --for=delete")
+			}
+
+			start := time.Now()
+			si, err := findClientServiceInstance(cc.scClient, ns, clientID)
+			if err != nil {
+				return err
+			}
+			if si == nil {
+				// nothing is provisioning the client so it has to exist already
+				client, err := clients.Get(clientID, metav1.GetOptions{})
+				if err != nil {
+					return errors.Wrap(err, "failed to get mobile client "+clientID)
+				}
+				waiter, err := newWaiter(cmd.Flags(), clientID, clients.Watch)
+				if err != nil {
+					return err
+				}
+				return errors.Wrap(waitFrom(waiter, client, wait.Exists()), "failed waiting for mobile client "+clientID)
+			}
+			siWaiter, err := newWaiter(cmd.Flags(), si.Name, cc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
+			if err != nil {
+				return err
+			}
+			if err := waitFrom(siWaiter, si, wait.ServiceInstanceReady(si.Generation)); err != nil {
+				return errors.Wrap(err, "failed waiting for the service provisioning mobile client "+clientID)
+			}
+			waiter, err := newWaiter(cmd.Flags(), clientID, clients.Watch)
+			if err != nil {
+				return err
+			}
+			if waiter.Timeout > 0 {
+				// the timeout covers both waits
+				waiter.Timeout -= time.Since(start)
+				if waiter.Timeout <= 0 {
+					return errors.Wrap(wait.ErrTimeout, "failed waiting for mobile client "+clientID)
+				}
+			}
+			return errors.Wrap(waiter.Until(wait.Exists()), "failed waiting for mobile client "+clientID)
+		},
+	}
+	addWaitFlags(cmd)
 	return cmd
 }
 
+// clientParamsSecretName returns the name of the secret holding the parameters of the service provisioning a mobile client
+func clientParamsSecretName(clientID string) string {
+	return clientID + "-apb-" + "params"
+}
+
+// findClientServiceInstance returns the service instance that provisioned the mobile client or nil when there is none
+func findClientServiceInstance(scClient versioned.Interface, ns, clientID string) (*v1beta1.ServiceInstance, error) {
+	sis, err := scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances")
+	}
+	secretName := clientParamsSecretName(clientID)
+	for _, si := range sis.Items {
+		for _, pf := range si.Spec.ParametersFrom {
+			if pf.SecretKeyRef != nil && pf.SecretKeyRef.Name == secretName {
+				return &si, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // DeleteClientCmd builds the delete mobile client command
 func (cc *ClientCmd) DeleteClientCmd() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "client <clientID>",
 		Short: "deletes a single mobile client in the namespace",
-		Long: `delete client allows you to delete a single mobile client in your namespace.
-               Run the "mobile get clients" command from this tool to get the client ID.`,
+		Long: `delete client allows you to delete a single mobile client in your namespace along with the resources it owns:
+the service instance provisioning it and its parameters secret, the integrations of that service instance and
+the service configs labelled with the client ID, such as the keycloak client secrets. Resources with an owner reference
+to the mobile client are deleted too. Use --keep-services to keep the service instance provisioning the client.
+
+The resources are listed for confirmation before they are deleted unless --yes is set. When stdin is not a terminal
+the delete is refused unless --yes or --keep-services is set.
+Run the "mobile get clients" command from this tool to get the client ID.`,
 		Example: `  mobile delete client <clientID> --namespace=myproject 
+                    mobile delete client <clientID> --keep-services --yes
                     kubectl plugin mobile delete client <clientID>
                     oc plugin mobile delete client <clientID>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -316,17 +734,222 @@ func (cc *ClientCmd) DeleteClientCmd() *cobra.Command {
 			if err != nil {
 				return errors.Wrap(err, "failed to get namespace")
 			}
+			keepServices, err := cmd.PersistentFlags().GetBool("keep-services")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			yes, err := cmd.PersistentFlags().GetBool("yes")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			owned, err := cc.findClientResources(ns, clientID, keepServices)
+			if err != nil {
+				return err
+			}
 
-			err = cc.mobileClient.MobileV1alpha1().MobileClients(ns).Delete(clientID, &metav1.DeleteOptions{})
+			dryRun, err := newDryRun(cmd.Flags(), ns)
 			if err != nil {
-				return errors.Wrap(err, "failed to get mobile client with clientID "+clientID)
+				return err
 			}
-			return nil
+			if dryRun != nil {
+				for _, p := range owned.Presets {
+					if err := dryRun.delete(cc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", p.Name); err != nil {
+						return err
+					}
+				}
+				for _, b := range owned.Bindings {
+					if err := dryRun.delete(cc.scClient.ServicecatalogV1beta1().RESTClient(), serviceBindingsResource, "ServiceBinding", b.Name); err != nil {
+						return err
+					}
+				}
+				for _, si := range owned.Instances {
+					if err := dryRun.delete(cc.scClient.ServicecatalogV1beta1().RESTClient(), serviceInstancesResource, "ServiceInstance", si.Name); err != nil {
+						return err
+					}
+				}
+				for _, name := range owned.secretNames() {
+					if err := dryRun.delete(cc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", name); err != nil {
+						return err
+					}
+				}
+				if err := dryRun.delete(cc.mobileClient.MobileV1alpha1().RESTClient(), mobileClientsResource, "MobileClient", clientID); err != nil {
+					return err
+				}
+				return dryRun.render(cc.Out, cmd.Flags())
+			}
+
+			if !yes && !isInteractive(cmd.Flags()) && !keepServices {
+				return errors.New("mobile client " + clientID + " and the resources it owns can not be confirmed for deletion as stdin is not a terminal. Set --yes to delete them or --keep-services to keep the service instance provisioning it")
+			}
+			if !yes && isInteractive(cmd.Flags()) {
+				confirmed, err := confirmClientDeletion(clientID, owned)
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return errors.New("the deletion of mobile client " + clientID + " was cancelled")
+				}
+			}
+
+			return cc.deleteClientResources(ns, clientID, owned)
 		},
 	}
+	command.PersistentFlags().Bool("keep-services", false, "--keep-services keep the service instance provisioning the mobile client and its parameters secret")
+	command.PersistentFlags().BoolP("yes", "y", false, "--yes delete the mobile client and the resources it owns without asking for confirmation")
 	return command
 }
 
+// clientResources are the resources owned by a mobile client
+type clientResources struct {
+	Instances []v1beta1.ServiceInstance
+	Bindings  []v1beta1.ServiceBinding
+	Presets   []kalpha.PodPreset
+	// Secrets are the owned secrets other than the parameters secrets of the instances
+	Secrets []v1.Secret
+}
+
+// findClientResources finds the resources owned by the mobile client, by owner reference or by the names and clientId labels
+// the CLI gives them. The service instances provisioning the client and their integrations are left out when keepServices is set
+func (cc *ClientCmd) findClientResources(ns, clientID string, keepServices bool) (*clientResources, error) {
+	client, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Get(clientID, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mobile client with clientID "+clientID)
+	}
+	owned := &clientResources{}
+	services := &ServicesCmd{scClient: cc.scClient, k8Client: cc.k8Client}
+	paramsSecrets := map[string]bool{}
+	if !keepServices {
+		sis, err := cc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list service instances")
+		}
+		for _, si := range sis.Items {
+			owns := ownedByClient(si.OwnerReferences, client)
+			for _, pf := range si.Spec.ParametersFrom {
+				if pf.SecretKeyRef != nil && pf.SecretKeyRef.Name == clientParamsSecretName(clientID) {
+					owns = true
+				}
+			}
+			if !owns {
+				continue
+			}
+			owned.Instances = append(owned.Instances, si)
+			for _, pf := range si.Spec.ParametersFrom {
+				if pf.SecretKeyRef != nil {
+					paramsSecrets[pf.SecretKeyRef.Name] = true
+				}
+			}
+			bindings, presets, err := services.serviceInstanceDependents(ns, si.Name)
+			if err != nil {
+				return nil, err
+			}
+			owned.Bindings = append(owned.Bindings, bindings...)
+			owned.Presets = append(owned.Presets, presets...)
+		}
+	}
+
+	secrets, err := cc.k8Client.CoreV1().Secrets(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secrets")
+	}
+	for _, s := range secrets.Items {
+		if paramsSecrets[s.Name] {
+			continue
+		}
+		if s.Labels["clientId"] == clientID || ownedByClient(s.OwnerReferences, client) {
+			owned.Secrets = append(owned.Secrets, s)
+		}
+	}
+	return owned, nil
+}
+
+// deleteClientResources deletes the resources owned by the mobile client and then the client itself
+func (cc *ClientCmd) deleteClientResources(ns, clientID string, owned *clientResources) error {
+	for _, p := range owned.Presets {
+		if err := cc.k8Client.SettingsV1alpha1().PodPresets(ns).Delete(p.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete pod preset "+p.Name)
+		}
+	}
+	for _, b := range owned.Bindings {
+		if err := cc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Delete(b.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete integration "+b.Name)
+		}
+	}
+	services := &ServicesCmd{scClient: cc.scClient, k8Client: cc.k8Client}
+	for _, si := range owned.Instances {
+		if err := services.deprovision(ns, si.Name, si.Spec.ParametersFrom); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete the service instance "+si.Name+" of mobile client "+clientID)
+		}
+	}
+	for _, s := range owned.Secrets {
+		if err := cc.k8Client.CoreV1().Secrets(ns).Delete(s.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete the secret "+s.Name+" of mobile client "+clientID)
+		}
+	}
+
+	err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Delete(clientID, &metav1.DeleteOptions{})
+	// deprovisioning the service instance can delete the client first
+	if err != nil && !(apierrors.IsNotFound(err) && len(owned.Instances) > 0) {
+		return errors.Wrap(err, "failed to get mobile client with clientID "+clientID)
+	}
+	return nil
+}
+
+// secretNames lists the owned secrets including the parameters secrets of the instances
+func (r *clientResources) secretNames() []string {
+	var names []string
+	for _, si := range r.Instances {
+		for _, pf := range si.Spec.ParametersFrom {
+			if pf.SecretKeyRef != nil {
+				names = append(names, pf.SecretKeyRef.Name)
+			}
+		}
+	}
+	for _, s := range r.Secrets {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// ownedByClient checks the owner references for the mobile client. The UID is compared too so resources owned by an earlier
+// client with the same name are not taken for its own
+func ownedByClient(refs []metav1.OwnerReference, client *v1alpha1.MobileClient) bool {
+	for _, ref := range refs {
+		if ref.Kind == "MobileClient" && ref.Name == client.Name && ref.UID == client.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmClientDeletion lists the resources that will be deleted with the mobile client and asks the user to confirm
+func confirmClientDeletion(clientID string, owned *clientResources) (bool, error) {
+	data := [][]string{{"MobileClient", clientID}}
+	for _, si := range owned.Instances {
+		data = append(data, []string{"ServiceInstance", si.Name})
+	}
+	for _, b := range owned.Bindings {
+		data = append(data, []string{"ServiceBinding", b.Name})
+	}
+	for _, p := range owned.Presets {
+		data = append(data, []string{"PodPreset", p.Name})
+	}
+	for _, name := range owned.secretNames() {
+		data = append(data, []string{"Secret", name})
+	}
+	table := tablewriter.NewWriter(os.Stderr)
+	table.SetHeader([]string{"Kind", "Name"})
+	table.AppendBulk(data)
+	table.Render()
+	fmt.Fprint(os.Stderr, "Delete these resources? [y/N]: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, errors.Wrap(err, "failed to read the confirmation")
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
 // SetClientValueCmd sets value in client
 func (cc *ClientCmd) SetClientValueFromJsonCmd() *cobra.Command {
 	var patch string
@@ -360,6 +983,19 @@ func (cc *ClientCmd) SetClientValueFromJsonCmd() *cobra.Command {
 				return errors.Wrap(err, "failed to get namespace")
 			}
 
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				var parsed interface{}
+				if err := json.Unmarshal([]byte(patch), &parsed); err != nil {
+					return errors.Wrap(err, "--patch is not valid JSON")
+				}
+				dryRun.patch("MobileClient", clientID, parsed)
+				return dryRun.render(cc.Out, cmd.Flags())
+			}
+
 			res, err = cc.mobileClient.MobileV1alpha1().MobileClients(ns).Patch(clientID, types.MergePatchType, []byte(patch))
 			if err != nil {
 				return errors.Wrap(err, fmt.Sprintf("failed to patch mobile client with clientID %s", clientID))
@@ -377,6 +1013,24 @@ func (cc *ClientCmd) SetClientValueFromJsonCmd() *cobra.Command {
 	return command
 }
 
+// settableClientSpecValues are the fields of the mobile client spec set value can change
+var settableClientSpecValues = map[string]bool{"name": true, "appIdentifier": true, "dmzUrl": true}
+
+// validateClientSpecValue checks the value of a field of the mobile client spec against the MobileClient CRD
+func validateClientSpecValue(name, value string) error {
+	// the other fields are set to valid values so only the given one can fail
+	client := &v1alpha1.MobileClient{Spec: v1alpha1.MobileClientSpec{Name: "name", AppIdentifier: "appIdentifier"}}
+	switch name {
+	case "name":
+		client.Spec.Name = value
+	case "appIdentifier":
+		client.Spec.AppIdentifier = value
+	case "dmzUrl":
+		client.Spec.DmzUrl = value
+	}
+	return errors.Wrap(input.ValidateMobileClient(client, nil), "invalid value for "+name)
+}
+
 // SetClientSpecValueCmd sets value in client
 func (cc *ClientCmd) SetClientSpecValueCmd() *cobra.Command {
 	var (
@@ -420,14 +1074,32 @@ func (cc *ClientCmd) SetClientSpecValueCmd() *cobra.Command {
 				return errors.Wrap(err, "failed to get namespace")
 			}
 
-			var patch string
-			if value == "null" || value == "" {
-				patch = fmt.Sprintf("{\"spec\": {\"%s\": null}}", name)
-			} else {
-				patch = fmt.Sprintf("{\"spec\": {\"%s\": \"%v\"}}", name, value)
+			if !settableClientSpecValues[name] {
+				return errors.New("--name must be one of name, appIdentifier or dmzUrl but was " + name)
+			}
+			var specValue interface{} = value
+			// only the dmzUrl is optional, clearing the other fields is rejected by the validation
+			if name == "dmzUrl" && (value == "null" || value == "") {
+				specValue = nil
+			} else if err := validateClientSpecValue(name, value); err != nil {
+				return err
+			}
+			specPatch := map[string]interface{}{"spec": map[string]interface{}{name: specValue}}
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				dryRun.patch("MobileClient", clientId, specPatch)
+				return dryRun.render(cc.Out, cmd.Flags())
+			}
+			// the patch is marshalled so the value is escaped
+			patch, err := json.Marshal(specPatch)
+			if err != nil {
+				return errors.WithStack(err)
 			}
 
-			res, err = cc.mobileClient.MobileV1alpha1().MobileClients(ns).Patch(clientId, types.MergePatchType, []byte(patch))
+			res, err = cc.mobileClient.MobileV1alpha1().MobileClients(ns).Patch(clientId, types.MergePatchType, patch)
 			if err != nil {
 				return errors.Wrap(err, fmt.Sprintf("failed to set value in mobile client with clientID %s", clientId))
 			}
@@ -444,3 +1116,169 @@ func (cc *ClientCmd) SetClientSpecValueCmd() *cobra.Command {
 	command.PersistentFlags().StringVarP(&value, "value", "v", "", "value")
 	return command
 }
+
+// UpdateClientCmd builds the update mobile client command
+func (cc *ClientCmd) UpdateClientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client <clientID>",
+		Short: "update the name, dmzUrl, appIdentifier or labels of a mobile client",
+		Long: `update client changes the given fields of a mobile client in your namespace and prints what changed as a diff.
+The updated client is validated like a new one, against the patterns of the MobileClient CRD, before it is changed.
+Labels are set with --label key=value and removed with --label key-.
+Run the "mobile get clients" command from this tool to get the client ID.`,
+		Example: `  mobile update client <clientID> --dmz-url=https://dmz.example.com --namespace=myproject
+  mobile update client <clientID> --name=myapp --app-identifier=org.example.myapp --label team=mobile --label deprecated-
+  kubectl plugin mobile update client <clientID> --dmz-url=https://dmz.example.com
+  oc plugin mobile update client <clientID> --dmz-url=https://dmz.example.com`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			clientID := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			labels, err := cmd.PersistentFlags().GetStringArray("label")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			current, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Get(clientID, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get mobile client with clientID "+clientID)
+			}
+			updated := *current
+			updated.Labels = map[string]string{}
+			for k, v := range current.Labels {
+				updated.Labels[k] = v
+			}
+			spec := map[string]interface{}{}
+			for _, f := range []struct {
+				flag, field string
+				value       *string
+			}{
+				{"name", "name", &updated.Spec.Name},
+				{"dmz-url", "dmzUrl", &updated.Spec.DmzUrl},
+				{"app-identifier", "appIdentifier", &updated.Spec.AppIdentifier},
+			} {
+				if !cmd.PersistentFlags().Changed(f.flag) {
+					continue
+				}
+				value, err := cmd.PersistentFlags().GetString(f.flag)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if value != *f.value {
+					*f.value = value
+					spec[f.field] = value
+					if value == "" {
+						// null removes the field, an empty value does not match the pattern of the MobileClient CRD
+						spec[f.field] = nil
+					}
+				}
+			}
+			patchLabels := map[string]interface{}{}
+			for _, l := range labels {
+				if strings.HasSuffix(l, "-") && !strings.Contains(l, "=") {
+					key := strings.TrimSuffix(l, "-")
+					if _, ok := updated.Labels[key]; ok {
+						delete(updated.Labels, key)
+						patchLabels[key] = nil
+					}
+					continue
+				}
+				parts := strings.SplitN(l, "=", 2)
+				if len(parts) != 2 || parts[0] == "" {
+					return errors.New("invalid label " + l + " labels are set with --label key=value and removed with --label key-")
+				}
+				if value, ok := updated.Labels[parts[0]]; !ok || value != parts[1] {
+					updated.Labels[parts[0]] = parts[1]
+					patchLabels[parts[0]] = parts[1]
+				}
+			}
+			if err := input.ValidateMobileClient(&updated, nil); err != nil {
+				return errors.Wrap(err, "invalid update of mobile client "+clientID)
+			}
+			if _, ok := spec["appIdentifier"]; ok {
+				if err := input.ValidateAppIdentifier(updated.Spec.ClientType, updated.Spec.AppIdentifier); err != nil {
+					return errors.Wrap(err, "invalid update of mobile client "+clientID)
+				}
+			}
+
+			changes, err := clientChanges(clientID, current, &updated)
+			if err != nil {
+				return err
+			}
+			if changes == "" {
+				fmt.Fprintf(os.Stderr, "mobile client %s is unchanged\n", clientID)
+				return nil
+			}
+			patch := map[string]interface{}{}
+			if len(spec) > 0 {
+				patch["spec"] = spec
+			}
+			if len(patchLabels) > 0 {
+				patch["metadata"] = map[string]interface{}{"labels": patchLabels}
+			}
+
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				dryRun.patch("MobileClient", clientID, patch)
+				return dryRun.render(cc.Out, cmd.Flags())
+			}
+
+			// the patch is marshalled so the values are escaped
+			raw, err := json.Marshal(patch)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			res, err := cc.mobileClient.MobileV1alpha1().MobileClients(ns).Patch(clientID, types.MergePatchType, raw)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("failed to update mobile client with clientID %s", clientID))
+			}
+			// the table shows what changed
+			var data interface{} = res
+			outType := outputType(cmd.Flags())
+			if outType == "table" {
+				data = changes
+			}
+			if err := cc.Out.Render("update"+cmd.Name(), outType, data); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "mobile client", outType))
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().String("name", "", "--name=myapp the name of the mobile client")
+	cmd.PersistentFlags().String("dmz-url", "", "--dmz-url=https://dmz.example.com the url of the DMZ the mobile client reaches the services through, empty removes it")
+	cmd.PersistentFlags().String("app-identifier", "", "--app-identifier=org.example.myapp the bundleID or package name of the mobile client")
+	cmd.PersistentFlags().StringArray("label", []string{}, "--label key=value sets a label on the mobile client, --label key- removes it")
+	cc.Out.AddRenderer("update"+cmd.Name(), "table", func(out io.Writer, data interface{}) error {
+		_, err := fmt.Fprint(out, data.(string))
+		return err
+	})
+	return cmd
+}
+
+// clientChanges returns the changes to the labels and spec of the mobile client as a unified diff
+func clientChanges(clientID string, current, updated *v1alpha1.MobileClient) (string, error) {
+	fields := func(c *v1alpha1.MobileClient) ([]string, error) {
+		raw, err := yaml.Marshal(map[string]interface{}{"labels": c.Labels, "spec": c.Spec})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n"), nil
+	}
+	from, err := fields(current)
+	if err != nil {
+		return "", err
+	}
+	to, err := fields(updated)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(clientID, clientID+" (updated)", from, to), nil
+}
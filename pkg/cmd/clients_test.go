@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/aerogear/mobile-cli/pkg/cmd"
@@ -28,12 +30,17 @@ import (
 	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
 	sc "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
 	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	kMetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	ktFake "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+	settings "k8s.io/client-go/pkg/apis/settings/v1alpha1"
 	kt "k8s.io/client-go/testing"
 )
 
@@ -262,6 +269,7 @@ func TestMobileClientsCmd_TestDeleteClient(t *testing.T) {
 		ExpectError      bool
 		ExpectUsage      bool
 		ValidateOutput   func(t *testing.T, out string)
+		ValidateClients  func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface)
 		ErrorPattern     string
 		Flags            []string
 	}{
@@ -278,7 +286,22 @@ func TestMobileClientsCmd_TestDeleteClient(t *testing.T) {
 				return &ktFake.Clientset{}
 			},
 			ClientName: "myapp",
-			Flags:      []string{"--namespace=myproject", "-o=json"},
+			Flags:      []string{"--namespace=myproject", "--yes", "-o=json"},
+		},
+		{
+			Name:             "test delete client refuses to delete the resources it owns without --yes when stdin is not a terminal",
+			MobileClient:     ownedClientResources.mobileClient,
+			SvcCatalogClient: ownedClientResources.scClient,
+			K8Client:         ownedClientResources.k8Client,
+			ClientName:       "myapp-android",
+			Flags:            []string{"--namespace=myproject"},
+			ExpectError:      true,
+			ErrorPattern:     "^mobile client myapp-android and the resources it owns can not be confirmed for deletion as stdin is not a terminal. Set --yes to delete them or --keep-services",
+			ValidateClients: func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface) {
+				ownedClientResources.expectRemaining(t, mobileClient, scClient, k8Client,
+					[]string{"myapp-android"}, []string{"android-app-abcde", "other-instance", "owned-instance"}, []string{"android-app-binding"}, []string{"android-app-preset"},
+					[]string{"android-app-binding-secret", "keycloak-myapp-android", "myapp-android-apb-params", "other-config", "owned-config", "owned-params", "stale-config"})
+			},
 		},
 		{
 			Name: "test delete client fails and returns usage when missing arguments",
@@ -317,13 +340,68 @@ func TestMobileClientsCmd_TestDeleteClient(t *testing.T) {
 				t.Log("output ", out)
 			},
 		},
+		{
+			Name:             "test delete client deletes the resources it owns",
+			MobileClient:     ownedClientResources.mobileClient,
+			SvcCatalogClient: ownedClientResources.scClient,
+			K8Client:         ownedClientResources.k8Client,
+			ClientName:       "myapp-android",
+			Flags:            []string{"--namespace=myproject", "--yes"},
+			ValidateClients: func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface) {
+				// the service catalog deletes the binding secret once it is unbound
+				ownedClientResources.expectRemaining(t, mobileClient, scClient, k8Client,
+					[]string{}, []string{"other-instance"}, []string{}, []string{}, []string{"android-app-binding-secret", "other-config", "stale-config"})
+			},
+		},
+		{
+			Name:             "test delete client with --keep-services keeps the service instance provisioning it",
+			MobileClient:     ownedClientResources.mobileClient,
+			SvcCatalogClient: ownedClientResources.scClient,
+			K8Client:         ownedClientResources.k8Client,
+			ClientName:       "myapp-android",
+			Flags:            []string{"--namespace=myproject", "--keep-services"},
+			ValidateClients: func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface) {
+				ownedClientResources.expectRemaining(t, mobileClient, scClient, k8Client,
+					[]string{}, []string{"android-app-abcde", "other-instance", "owned-instance"}, []string{"android-app-binding"}, []string{"android-app-preset"},
+					[]string{"android-app-binding-secret", "myapp-android-apb-params", "other-config", "owned-params", "stale-config"})
+			},
+		},
+		{
+			Name:             "test delete client with --dry-run lists the resources it owns",
+			MobileClient:     ownedClientResources.mobileClient,
+			SvcCatalogClient: ownedClientResources.scClient,
+			K8Client:         ownedClientResources.k8Client,
+			ClientName:       "myapp-android",
+			Flags:            []string{"--namespace=myproject", "--dry-run", "-o=json"},
+			ValidateOutput: func(t *testing.T, out string) {
+				var changes []cmd.DryRunChange
+				if err := json.Unmarshal([]byte(out), &changes); err != nil {
+					t.Fatal("failed to unmarshal the dry run changes", err)
+				}
+				var deleted []string
+				for _, c := range changes {
+					deleted = append(deleted, c.Kind+" "+c.Name)
+				}
+				expected := "PodPreset android-app-preset,ServiceBinding android-app-binding,ServiceInstance android-app-abcde,ServiceInstance owned-instance," +
+					"Secret myapp-android-apb-params,Secret owned-params,Secret keycloak-myapp-android,Secret owned-config,MobileClient myapp-android"
+				if strings.Join(deleted, ",") != expected {
+					t.Fatalf("expected the deletes %s but got %s", expected, strings.Join(deleted, ","))
+				}
+			},
+			ValidateClients: func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface) {
+				ownedClientResources.expectRemaining(t, mobileClient, scClient, k8Client,
+					[]string{"myapp-android"}, []string{"android-app-abcde", "other-instance", "owned-instance"}, []string{"android-app-binding"}, []string{"android-app-preset"},
+					[]string{"android-app-binding-secret", "keycloak-myapp-android", "myapp-android-apb-params", "other-config", "owned-config", "owned-params", "stale-config"})
+			},
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			var stdOut bytes.Buffer
 			root := cmd.NewRootCmd()
-			clientCmd := cmd.NewClientCmd(tc.MobileClient(), tc.SvcCatalogClient(), tc.K8Client(), &stdOut)
+			mobileClient, scClient, k8Client := tc.MobileClient(), tc.SvcCatalogClient(), tc.K8Client()
+			clientCmd := cmd.NewClientCmd(mobileClient, scClient, k8Client, &stdOut)
 			deleteClient := clientCmd.DeleteClientCmd()
 			deleteClient.SetOutput(&stdOut)
 			root.AddCommand(deleteClient)
@@ -349,10 +427,113 @@ func TestMobileClientsCmd_TestDeleteClient(t *testing.T) {
 			if tc.ExpectUsage && deleteClient.UsageString() != string(stdOut.Bytes()) {
 				t.Fatalf("expected usage to match %s but got %s ", deleteClient.UsageString(), string(stdOut.Bytes()))
 			}
+			if tc.ValidateOutput != nil {
+				tc.ValidateOutput(t, stdOut.String())
+			}
+			if tc.ValidateClients != nil {
+				tc.ValidateClients(t, mobileClient, scClient, k8Client)
+			}
 		})
 	}
 }
 
+// ownedClientResources is a namespace with a mobile client, the resources it owns and others
+var ownedClientResources = struct {
+	mobileClient    func() mc.Interface
+	scClient        func() sc.Interface
+	k8Client        func() kubernetes.Interface
+	expectRemaining func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface, clients, instances, bindings, presets, secrets []string)
+}{
+	mobileClient: func() mc.Interface {
+		return mcFake.NewSimpleClientset(&v1alpha1.MobileClient{ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android", Namespace: "myproject", UID: "myapp-android-uid"}})
+	},
+	scClient: func() sc.Interface {
+		owner := []kMetav1.OwnerReference{{Kind: "MobileClient", Name: "myapp-android", UID: "myapp-android-uid"}}
+		params := func(name string) []v1beta1.ParametersFromSource {
+			return []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: name, Key: "parameters"}}}
+		}
+		return scFake.NewSimpleClientset(
+			&v1beta1.ServiceInstance{ObjectMeta: kMetav1.ObjectMeta{Name: "android-app-abcde", Namespace: "myproject"}, Spec: v1beta1.ServiceInstanceSpec{ParametersFrom: params("myapp-android-apb-params")}},
+			&v1beta1.ServiceInstance{ObjectMeta: kMetav1.ObjectMeta{Name: "owned-instance", Namespace: "myproject", OwnerReferences: owner}, Spec: v1beta1.ServiceInstanceSpec{ParametersFrom: params("owned-params")}},
+			&v1beta1.ServiceInstance{ObjectMeta: kMetav1.ObjectMeta{Name: "other-instance", Namespace: "myproject"}},
+			&v1beta1.ServiceBinding{ObjectMeta: kMetav1.ObjectMeta{Name: "android-app-binding", Namespace: "myproject"},
+				Spec: v1beta1.ServiceBindingSpec{ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "android-app-abcde"}, SecretName: "android-app-binding-secret"}},
+		)
+	},
+	k8Client: func() kubernetes.Interface {
+		secret := func(name string, labels map[string]string, owners []kMetav1.OwnerReference) *corev1.Secret {
+			return &corev1.Secret{ObjectMeta: kMetav1.ObjectMeta{Name: name, Namespace: "myproject", Labels: labels, OwnerReferences: owners}}
+		}
+		return ktFake.NewSimpleClientset(
+			secret("myapp-android-apb-params", nil, nil),
+			secret("owned-params", nil, nil),
+			secret("android-app-binding-secret", nil, nil),
+			secret("keycloak-myapp-android", map[string]string{"clientId": "myapp-android"}, nil),
+			secret("owned-config", nil, []kMetav1.OwnerReference{{Kind: "MobileClient", Name: "myapp-android", UID: "myapp-android-uid"}}),
+			// owned by an earlier mobile client with the same name
+			secret("stale-config", nil, []kMetav1.OwnerReference{{Kind: "MobileClient", Name: "myapp-android", UID: "deleted-uid"}}),
+			secret("other-config", map[string]string{"clientId": "other-android"}, nil),
+			&settings.PodPreset{ObjectMeta: kMetav1.ObjectMeta{Name: "android-app-preset", Namespace: "myproject"},
+				Spec: settings.PodPresetSpec{Volumes: []corev1.Volume{{Name: "binding", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "android-app-binding-secret"}}}}}},
+		)
+	},
+	expectRemaining: func(t *testing.T, mobileClient mc.Interface, scClient sc.Interface, k8Client kubernetes.Interface, clients, instances, bindings, presets, secrets []string) {
+		var remaining []string
+		mcList, _ := mobileClient.MobileV1alpha1().MobileClients("myproject").List(kMetav1.ListOptions{})
+		for _, c := range mcList.Items {
+			remaining = append(remaining, c.Name)
+		}
+		siList, _ := scClient.ServicecatalogV1beta1().ServiceInstances("myproject").List(kMetav1.ListOptions{})
+		var instanceNames []string
+		for _, si := range siList.Items {
+			instanceNames = append(instanceNames, si.Name)
+		}
+		sort.Strings(instanceNames)
+		remaining = append(remaining, instanceNames...)
+		sbList, _ := scClient.ServicecatalogV1beta1().ServiceBindings("myproject").List(kMetav1.ListOptions{})
+		for _, sb := range sbList.Items {
+			remaining = append(remaining, sb.Name)
+		}
+		ppList, _ := k8Client.SettingsV1alpha1().PodPresets("myproject").List(kMetav1.ListOptions{})
+		for _, pp := range ppList.Items {
+			remaining = append(remaining, pp.Name)
+		}
+		secretList, _ := k8Client.CoreV1().Secrets("myproject").List(kMetav1.ListOptions{})
+		var secretNames []string
+		for _, s := range secretList.Items {
+			secretNames = append(secretNames, s.Name)
+		}
+		sort.Strings(secretNames)
+		remaining = append(remaining, secretNames...)
+		var expected []string
+		for _, names := range [][]string{clients, instances, bindings, presets, secrets} {
+			expected = append(expected, names...)
+		}
+		if strings.Join(remaining, ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected the resources %s to remain but got %s", strings.Join(expected, ","), strings.Join(remaining, ","))
+		}
+	},
+}
+
+// defaultPlan is the default plan of the named service class
+func defaultPlan(className string) *v1beta1.ClusterServicePlan {
+	return &v1beta1.ClusterServicePlan{
+		ObjectMeta: kMetav1.ObjectMeta{Name: className + "-default"},
+		Spec:       v1beta1.ClusterServicePlanSpec{ExternalName: "default", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: className}},
+	}
+}
+
+// defaultPlans lists the default plans of the named service classes
+func defaultPlans(classNames ...string) kt.ReactionFunc {
+	return func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+		plans := &v1beta1.ClusterServicePlanList{}
+		for _, className := range classNames {
+			plans.Items = append(plans.Items, *defaultPlan(className))
+		}
+		return true, plans, nil
+	}
+}
+
 func TestMobileClientsCmd_TestCreateClient(t *testing.T) {
 	cases := []struct {
 		Name             string
@@ -682,6 +863,87 @@ func TestMobileClientsCmd_TestCreateClient(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name: "test create mobile client succeeds for a client type tagged on a service class",
+			Args: []string{"test", "react-native", "my.app.org"},
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("get", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1alpha1.MobileClient{
+						Spec: v1alpha1.MobileClientSpec{
+							Name:          "test",
+							ClientType:    "react-native",
+							AppIdentifier: "my.app.org",
+							ApiKey:        "fakeapikey",
+						},
+					}, nil
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				fakeClient := &scFake.Clientset{}
+				fakeClient.AddReactor("list", "clusterserviceclasses", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "rn-apb", ClientType: "react-native"})
+					return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+						{
+							ObjectMeta: kMetav1.ObjectMeta{Name: "test"},
+							Spec: v1beta1.ClusterServiceClassSpec{
+								Tags:             []string{"mobile-client-type"},
+								ExternalMetadata: &runtime.RawExtension{Raw: data},
+							},
+						},
+					}}, nil
+				})
+				fakeClient.AddWatchReactor("serviceinstances", func(action kt.Action) (handled bool, ret watch.Interface, err error) {
+					fakeWatch := watch.NewRaceFreeFake()
+					fakeWatch.Action(watch.Modified, &v1beta1.ServiceInstance{
+						Status: v1beta1.ServiceInstanceStatus{Conditions: []v1beta1.ServiceInstanceCondition{{Status: "True", Type: "Ready"}}},
+					})
+					return true, fakeWatch, nil
+				})
+				return fakeClient
+			},
+			K8Client: func() kubernetes.Interface {
+				fakeClient := &ktFake.Clientset{}
+				fakeClient.AddReactor("create", "secrets", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, nil
+				})
+				return fakeClient
+			},
+			Flags: []string{"--namespace=myproject", "-o=json"},
+			Validate: func(t *testing.T, c *v1alpha1.MobileClient) {
+				if c.Spec.ClientType != "react-native" {
+					t.Fatal("expected the clientType to be react-native but got ", c.Spec.ClientType)
+				}
+			},
+		},
+		{
+			Name:         "test create mobile client fails with a client type no service class provisions",
+			Args:         []string{"test", "flutter", "my.app.org"},
+			ExpectError:  true,
+			ErrorPattern: "^Unknown client type flutter, the available client types are android,react-native$",
+			MobileClient: func() mc.Interface {
+				return &mcFake.Clientset{}
+			},
+			SvcCatalogClient: func() sc.Interface {
+				fakeClient := &scFake.Clientset{}
+				fakeClient.AddReactor("list", "clusterserviceclasses", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					android, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "android-app"})
+					reactNative, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "react-native-app"})
+					keycloak, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "keycloak"})
+					return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+						{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: reactNative}, Tags: []string{"mobile-client-type"}}},
+						{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: android}}},
+						{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: keycloak}, Tags: []string{"mobile-service"}}},
+					}}, nil
+				})
+				return fakeClient
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			Flags: []string{"--namespace=myproject", "-o=json"},
+		},
 		{
 			Name:         "test create mobile client fails with unknown client type",
 			Args:         []string{"test", "firefox", "my.app.org"},
@@ -777,6 +1039,29 @@ func TestMobileClientsCmd_TestCreateClient(t *testing.T) {
 			},
 			Flags: []string{"--namespace=myproject", "-o=json"},
 		},
+		{
+			Name:         "test create mobile client fails when the service class has no default plan",
+			Args:         []string{"test", "android", "org.example.test"},
+			ExpectError:  true,
+			ErrorPattern: "^failed to find ServicePlan: failed to find serviceplan associated with the serviceclass android-class$",
+			MobileClient: func() mc.Interface {
+				return &mcFake.Clientset{}
+			},
+			SvcCatalogClient: func() sc.Interface {
+				fakeClient := &scFake.Clientset{}
+				fakeClient.AddReactor("list", "clusterserviceclasses", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "android-app"})
+					return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+						{ObjectMeta: kMetav1.ObjectMeta{Name: "android-class"}, Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: data}}},
+					}}, nil
+				})
+				return fakeClient
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			Flags: []string{"--namespace=myproject", "-o=json"},
+		},
 		{
 			Name:         "test create mobile client fails when there is no appIdentifier",
 			Args:         []string{"test", "android", ""},
@@ -803,7 +1088,10 @@ func TestMobileClientsCmd_TestCreateClient(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			var stdOut bytes.Buffer
 			root := cmd.NewRootCmd()
-			clientCmd := cmd.NewClientCmd(tc.MobileClient(), tc.SvcCatalogClient(), tc.K8Client(), &stdOut)
+			scClient := tc.SvcCatalogClient()
+			// the service classes of the cases all have a default plan
+			scClient.(*scFake.Clientset).AddReactor("list", "clusterserviceplans", defaultPlans("test"))
+			clientCmd := cmd.NewClientCmd(tc.MobileClient(), scClient, tc.K8Client(), &stdOut)
 			createCmd := clientCmd.CreateClientCmd()
 			root.AddCommand(createCmd)
 
@@ -836,6 +1124,178 @@ func TestMobileClientsCmd_TestCreateClient(t *testing.T) {
 	}
 }
 
+func TestMobileClientsCmd_TestCreateClientDryRun(t *testing.T) {
+	svcCatalogClient := func() sc.Interface {
+		fakeClient := &scFake.Clientset{}
+		fakeClient.AddReactor("list", "clusterserviceclasses", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+			data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "android-app"})
+			return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+				{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "android-class"},
+					Spec:       v1beta1.ClusterServiceClassSpec{ExternalName: "android-app", ExternalMetadata: &runtime.RawExtension{Raw: data}},
+				},
+			}}, nil
+		})
+		fakeClient.AddReactor("list", "clusterserviceplans", defaultPlans("android-class"))
+		fakeClient.AddReactor("create", "serviceinstances", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+			return true, nil, errors.New("should not have been called")
+		})
+		return fakeClient
+	}
+	k8Client := func() kubernetes.Interface {
+		fakeClient := &ktFake.Clientset{}
+		fakeClient.AddReactor("create", "secrets", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+			return true, nil, errors.New("should not have been called")
+		})
+		return fakeClient
+	}
+	cases := []struct {
+		Name         string
+		ExpectError  bool
+		ErrorPattern string
+		Flags        []string
+		Validate     func(t *testing.T, changes []cmd.DryRunChange)
+	}{
+		{
+			Name:  "test create client with --dry-run prints the service instance and params secret as yaml",
+			Flags: []string{"--namespace=myproject", "--dry-run"},
+			Validate: func(t *testing.T, changes []cmd.DryRunChange) {
+				if len(changes) != 2 {
+					t.Fatalf("expected 2 changes but got %v", changes)
+				}
+				si, secret := changes[0], changes[1]
+				if si.Action != "create" || si.Kind != "ServiceInstance" || si.Name != "android-app-xxxxx" || si.Namespace != "myproject" {
+					t.Fatalf("expected the service instance to be created but got %v", si)
+				}
+				object := si.Object.(map[string]interface{})
+				if object["apiVersion"] != "servicecatalog.k8s.io/v1beta1" || object["kind"] != "ServiceInstance" {
+					t.Fatalf("expected the service instance to have its type set but got %v", object)
+				}
+				if secret.Kind != "Secret" || secret.Name != "test-android-apb-params" {
+					t.Fatalf("expected the params secret to be created but got %v", secret)
+				}
+				if object := secret.Object.(map[string]interface{}); object["apiVersion"] != "v1" || object["kind"] != "Secret" {
+					t.Fatalf("expected the secret to have its type set but got %v", object)
+				}
+			},
+		},
+		{
+			Name:         "test create client fails with an unknown --dry-run mode",
+			Flags:        []string{"--namespace=myproject", "--dry-run=always"},
+			ExpectError:  true,
+			ErrorPattern: "^unknown --dry-run mode always",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdOut bytes.Buffer
+			root := cmd.NewRootCmd()
+			clientCmd := cmd.NewClientCmd(&mcFake.Clientset{}, svcCatalogClient(), k8Client(), &stdOut)
+			createCmd := clientCmd.CreateClientCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"test", "android", "my.app.org"})
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ExpectError {
+				if m, regErr := regexp.Match(tc.ErrorPattern, []byte(err.Error())); !m {
+					t.Fatal("expected the error to match the pattern "+tc.ErrorPattern, err, regErr)
+				}
+			}
+			if tc.Validate != nil {
+				var changes []cmd.DryRunChange
+				if err := yaml.Unmarshal(stdOut.Bytes(), &changes); err != nil {
+					t.Fatal("failed to unmarshal the dry run changes", err)
+				}
+				tc.Validate(t, changes)
+			}
+		})
+	}
+}
+
+func TestMobileClientsCmd_TestCreateClientPlanParams(t *testing.T) {
+	svcCatalogClient := func() sc.Interface {
+		data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "android-app"})
+		schema := []byte(`{"required":["REGION"],"properties":{"REGION":{"type":"string"},"LOG_LEVEL":{"type":"string","default":"info"}}}`)
+		return scFake.NewSimpleClientset(
+			&v1beta1.ClusterServiceClass{
+				ObjectMeta: kMetav1.ObjectMeta{Name: "android-class"},
+				Spec:       v1beta1.ClusterServiceClassSpec{ExternalName: "android-app", ExternalMetadata: &runtime.RawExtension{Raw: data}},
+			},
+			&v1beta1.ClusterServicePlan{
+				ObjectMeta: kMetav1.ObjectMeta{Name: "android-class-large"},
+				Spec: v1beta1.ClusterServicePlanSpec{ExternalName: "large", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "android-class"},
+					ServiceInstanceCreateParameterSchema: &runtime.RawExtension{Raw: schema}},
+			},
+		)
+	}
+	cases := []struct {
+		Name         string
+		Flags        []string
+		ErrorPattern string
+		Expected     map[string]string
+	}{
+		{
+			Name:     "test create client sets the defaults of the chosen plan",
+			Flags:    []string{"--namespace=myproject", "--plan=large", "-pREGION=eu", "--dry-run", "-o=json"},
+			Expected: map[string]string{"REGION": "eu", "LOG_LEVEL": "info", "appName": "test", "appIdentifier": "my.app.org"},
+		},
+		{
+			Name:         "test create client checks the required parameters of the chosen plan",
+			Flags:        []string{"--namespace=myproject", "--plan=large", "--dry-run", "-o=json"},
+			ErrorPattern: "^invalid parameters for the plan large: missing required parameter REGION$",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var stdOut bytes.Buffer
+			root := cmd.NewRootCmd()
+			createCmd := cmd.NewClientCmd(&mcFake.Clientset{}, svcCatalogClient(), &ktFake.Clientset{}, &stdOut).CreateClientCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"test", "android", "my.app.org"})
+			if tc.ErrorPattern != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+					t.Fatalf("expected the error to match the pattern %s but got %s", tc.ErrorPattern, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			var changes []cmd.DryRunChange
+			if err := json.Unmarshal(stdOut.Bytes(), &changes); err != nil || len(changes) != 2 {
+				t.Fatalf("expected the service instance and params secret to be printed but got %v %v", changes, err)
+			}
+			raw, _ := json.Marshal(changes[1].Object)
+			secret := corev1.Secret{}
+			if err := json.Unmarshal(raw, &secret); err != nil {
+				t.Fatal("failed to unmarshal the params secret", err)
+			}
+			params := map[string]string{}
+			if err := json.Unmarshal(secret.Data["parameters"], &params); err != nil {
+				t.Fatal("failed to unmarshal the parameters", err)
+			}
+			if fmt.Sprint(params) != fmt.Sprint(tc.Expected) {
+				t.Fatalf("expected the parameters %v but got %v", tc.Expected, params)
+			}
+		})
+	}
+}
+
 func TestMobileClientsCmd_SetClientValueFromJsonCmd(t *testing.T) {
 	cases := []struct {
 		Name             string
@@ -953,6 +1413,25 @@ func TestMobileClientsCmd_SetClientValueFromJsonCmd(t *testing.T) {
 			Flags:       []string{"--namespace=myproject", "-o=json"},
 			ExpectError: true,
 		},
+		{
+			Name: "Test set with --dry-run does not patch the client",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientName: "myapp",
+			PatchFlag:  "--patch={\"spec\": {\"name\": \"my-new-name\"}}",
+			Flags:      []string{"--namespace=myproject", "--dry-run"},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -1074,6 +1553,129 @@ func TestMobileClientsCmd_SetClientSpecValueCmd(t *testing.T) {
 			Flags:       []string{"--namespace=myproject", "-o=json"},
 			ExpectError: true,
 		},
+		{
+			Name: "Test set value with quotes is escaped",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					patch := struct {
+						Spec v1alpha1.MobileClientSpec `json:"spec"`
+					}{}
+					if err := json.Unmarshal(action.(kt.PatchActionImpl).GetPatch(), &patch); err != nil {
+						return true, nil, err
+					}
+					return true, &v1alpha1.MobileClient{Spec: patch.Spec}, nil
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientId:  "--client=myapp-android",
+			ValueName: "--name=name",
+			Value:     `--value=my "app"`,
+			Flags:     []string{"--namespace=myproject", "-o=json"},
+			Validate: func(t *testing.T, client *v1alpha1.MobileClient) {
+				if client.Spec.Name != `my "app"` {
+					t.Fatalf("expected the name to be set with its quotes but got %s", client.Spec.Name)
+				}
+			},
+		},
+		{
+			Name: "Test set value fails for other fields of the spec",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientId:     "--client=myapp-android",
+			ValueName:    "--name=apiKey",
+			Value:        "--value=mine",
+			Flags:        []string{"--namespace=myproject", "-o=json"},
+			ExpectError:  true,
+			ErrorPattern: "^--name must be one of name, appIdentifier or dmzUrl but was apiKey$",
+		},
+		{
+			Name: "Test set value clears the dmz url",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					if patch := string(action.(kt.PatchActionImpl).GetPatch()); patch != `{"spec":{"dmzUrl":null}}` {
+						return true, nil, errors.New("unexpected patch " + patch)
+					}
+					return true, &v1alpha1.MobileClient{Spec: v1alpha1.MobileClientSpec{Name: "myapp"}}, nil
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientId:  "--client=myapp-android",
+			ValueName: "--name=dmzUrl",
+			Value:     "--value=null",
+			Flags:     []string{"--namespace=myproject", "-o=json"},
+			Validate: func(t *testing.T, client *v1alpha1.MobileClient) {
+				if client.Spec.DmzUrl != "" {
+					t.Fatalf("expected the dmz url to be cleared but got %s", client.Spec.DmzUrl)
+				}
+			},
+		},
+		{
+			Name: "Test set value can not clear the app identifier",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientId:     "--client=myapp-android",
+			ValueName:    "--name=appIdentifier",
+			Value:        "--value=",
+			Flags:        []string{"--namespace=myproject", "-o=json"},
+			ExpectError:  true,
+			ErrorPattern: "^invalid value for appIdentifier: expected an appIdentifier to be passed",
+		},
+		{
+			Name: "Test set value with --dry-run does not patch the client",
+			MobileClient: func() mc.Interface {
+				fkMc := &mcFake.Clientset{}
+				fkMc.AddReactor("patch", "mobileclients", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fkMc
+			},
+			SvcCatalogClient: func() sc.Interface {
+				return &scFake.Clientset{}
+			},
+			K8Client: func() kubernetes.Interface {
+				return &ktFake.Clientset{}
+			},
+			ClientId:  "--client=myapp-android",
+			ValueName: "--name=dmzUrl",
+			Value:     "--value=https://dmz.example.com",
+			Flags:     []string{"--namespace=myproject", "--dry-run"},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -1086,9 +1688,7 @@ func TestMobileClientsCmd_SetClientSpecValueCmd(t *testing.T) {
 			setClient.SetOutput(&stdOut)
 			root.AddCommand(setClient)
 
-			flags := append(testCase.Flags, testCase.ClientId)
-			flags = append(testCase.Flags, testCase.ValueName)
-			flags = append(testCase.Flags, testCase.Value)
+			flags := append([]string{testCase.ClientId, testCase.ValueName, testCase.Value}, testCase.Flags...)
 			if err := setClient.ParseFlags(flags); err != nil {
 				t.Fatal("failed to parse flags ", err)
 			}
@@ -1123,3 +1723,391 @@ func TestMobileClientsCmd_SetClientSpecValueCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestMobileClientsCmd_TestCreateClientFrom(t *testing.T) {
+	instance := func(name, ns, service, paramsSecret string) *v1beta1.ServiceInstance {
+		si := &v1beta1.ServiceInstance{
+			ObjectMeta: kMetav1.ObjectMeta{Name: name, Namespace: ns},
+			Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: service, ClusterServicePlanExternalName: "default"}},
+		}
+		if paramsSecret != "" {
+			si.Spec.ParametersFrom = []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: paramsSecret, Key: "parameters"}}}
+		}
+		return si
+	}
+	config := func(name, service string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: kMetav1.ObjectMeta{Name: name, Namespace: "dev", Labels: map[string]string{"clientId": "myapp-android"}},
+			Data:       map[string][]byte{"name": []byte(service), "uri": []byte("https://" + service + ".dev.example.com")},
+		}
+	}
+
+	cases := []struct {
+		Name         string
+		Flags        []string
+		ExpectError  bool
+		ErrorPattern string
+		Validate     func(t *testing.T, mobileClient mc.Interface, k8Client kubernetes.Interface)
+	}{
+		{
+			Name:  "test create client --from copies the client and the configuration of the services in the namespace",
+			Flags: []string{"--namespace=test", "--from=dev/myapp-android", "-p", "REPLICAS=2", "-o=json"},
+			Validate: func(t *testing.T, mobileClient mc.Interface, k8Client kubernetes.Interface) {
+				client, err := mobileClient.MobileV1alpha1().MobileClients("test").Get("myapp-android", kMetav1.GetOptions{})
+				if err != nil {
+					t.Fatal("expected the mobile client to be created", err)
+				}
+				if client.Spec.DmzUrl != "https://dmz.example.com" {
+					t.Fatal("expected the dmzUrl to be copied but got", client.Spec.DmzUrl)
+				}
+				if fmt.Sprint(client.Spec.ExcludedServices) != "[keycloak-test]" {
+					t.Fatal("expected keycloak to be excluded as keycloak-test but got", client.Spec.ExcludedServices)
+				}
+				params, err := k8Client.CoreV1().Secrets("test").Get("myapp-android-apb-params", kMetav1.GetOptions{})
+				if err != nil {
+					t.Fatal("expected the params secret to be created", err)
+				}
+				if string(params.Data["parameters"]) != `{"CUSTOM":"x","REPLICAS":"2","appIdentifier":"org.example.myapp","appName":"myapp"}` {
+					t.Fatal("expected the parameters to be copied and overridden but got", string(params.Data["parameters"]))
+				}
+				if _, err := k8Client.CoreV1().Secrets("test").Get("keycloak-myapp", kMetav1.GetOptions{}); err != nil {
+					t.Fatal("expected the keycloak service config to be copied", err)
+				}
+				if _, err := k8Client.CoreV1().Secrets("test").Get("sync-myapp", kMetav1.GetOptions{}); err == nil {
+					t.Fatal("expected the sync service config not to be copied as there is no sync service in test")
+				}
+			},
+		},
+		{
+			Name:         "test create client --from fails when the client does not exist",
+			Flags:        []string{"--namespace=test", "--from=dev/nope-android"},
+			ExpectError:  true,
+			ErrorPattern: "^failed to get the mobile client nope-android in dev",
+		},
+		{
+			Name:         "test create client --from fails without a namespace",
+			Flags:        []string{"--namespace=test", "--from=myapp-android"},
+			ExpectError:  true,
+			ErrorPattern: "^--from must be <namespace>/<clientID> but was myapp-android$",
+		},
+		{
+			Name:         "test create client --from can not be used with --no-wait",
+			Flags:        []string{"--namespace=test", "--from=dev/myapp-android", "--no-wait"},
+			ExpectError:  true,
+			ErrorPattern: "^--no-wait can not be used with --from",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: "android-app"})
+			scClient := scFake.NewSimpleClientset(
+				&v1beta1.ClusterServiceClass{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "android-class"},
+					Spec:       v1beta1.ClusterServiceClassSpec{ExternalName: "android-app", ExternalMetadata: &runtime.RawExtension{Raw: data}},
+				},
+				&v1beta1.ClusterServicePlan{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "android-default"},
+					Spec:       v1beta1.ClusterServicePlanSpec{ExternalName: "default", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "android-class"}},
+				},
+				instance("android-app-dev", "dev", "android-app", "myapp-android-apb-params"),
+				instance("keycloak-dev", "dev", "keycloak", ""),
+				instance("sync-dev", "dev", "fh-sync-server", ""),
+				instance("keycloak-test", "test", "keycloak", ""),
+			)
+			k8Client := ktFake.NewSimpleClientset(
+				&corev1.Secret{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android-apb-params", Namespace: "dev"},
+					Data:       map[string][]byte{"parameters": []byte(`{"CUSTOM":"x","REPLICAS":"1","appName":"myapp","appIdentifier":"org.example.myapp"}`)},
+				},
+				config("keycloak-myapp", "keycloak"),
+				config("sync-myapp", "fh-sync-server"),
+			)
+			mobileClient := mcFake.NewSimpleClientset(&v1alpha1.MobileClient{
+				ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android", Namespace: "dev"},
+				Spec: v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ApiKey: "dev-key",
+					DmzUrl: "https://dmz.example.com", ExcludedServices: []string{"keycloak-dev", "sync-dev"}},
+			})
+			// like the API the client is empty rather than nil when it is not found
+			provisioned := false
+			mobileClient.PrependReactor("get", "mobileclients", func(action kt.Action) (bool, runtime.Object, error) {
+				if action.GetNamespace() == "test" && !provisioned {
+					return true, &v1alpha1.MobileClient{}, apierrors.NewNotFound(v1alpha1.Resource("mobileclients"), "myapp-android")
+				}
+				return false, nil, nil
+			})
+			// the android-app service creates the mobile client once provisioned
+			scClient.PrependReactor("create", "serviceinstances", func(action kt.Action) (bool, runtime.Object, error) {
+				provisioned = true
+				_, err := mobileClient.MobileV1alpha1().MobileClients("test").Create(&v1alpha1.MobileClient{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android", Namespace: "test"},
+					Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ApiKey: "test-key"},
+				})
+				// the fake does not generate names
+				si := action.(kt.CreateAction).GetObject().(*v1beta1.ServiceInstance)
+				si.Name = si.GenerateName + "abcde"
+				return false, nil, err
+			})
+			scClient.PrependWatchReactor("serviceinstances", func(action kt.Action) (bool, watch.Interface, error) {
+				fakeWatch := watch.NewRaceFreeFake()
+				fakeWatch.Action(watch.Modified, &v1beta1.ServiceInstance{ObjectMeta: kMetav1.ObjectMeta{Name: "android-app-abcde"}, Status: v1beta1.ServiceInstanceStatus{
+					Conditions: []v1beta1.ServiceInstanceCondition{{Type: v1beta1.ServiceInstanceConditionReady, Status: v1beta1.ConditionTrue}},
+				}})
+				return true, fakeWatch, nil
+			})
+
+			var stdOut bytes.Buffer
+			root := cmd.NewRootCmd()
+			clientCmd := cmd.NewClientCmd(mobileClient, scClient, k8Client, &stdOut)
+			createCmd := clientCmd.CreateClientCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := createCmd.RunE(createCmd, []string{})
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ExpectError {
+				if m, regErr := regexp.Match(tc.ErrorPattern, []byte(err.Error())); !m {
+					t.Fatal("expected the error to match the pattern "+tc.ErrorPattern, err, regErr)
+				}
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, mobileClient, k8Client)
+			}
+		})
+	}
+}
+
+func TestMobileClientsCmd_UpdateClientCmd(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Flags        []string
+		ExpectError  bool
+		ErrorPattern string
+		ExpectPatch  string
+		Validate     func(t *testing.T, out string)
+	}{
+		{
+			Name:        "test update client patches the changed fields and labels",
+			Flags:       []string{"--namespace=test", "--dmz-url=https://dmz.example.com", "--name=myapp", "--label", "team=mobile", "--label", "old-", "-o=json"},
+			ExpectPatch: `{"metadata":{"labels":{"old":null,"team":"mobile"}},"spec":{"dmzUrl":"https://dmz.example.com"}}`,
+		},
+		{
+			Name:        "test update client prints a diff of the changes",
+			Flags:       []string{"--namespace=test", "--app-identifier=org.example.other"},
+			ExpectPatch: `{"spec":{"appIdentifier":"org.example.other"}}`,
+			Validate: func(t *testing.T, out string) {
+				if !strings.Contains(out, "--- myapp-android\n+++ myapp-android (updated)\n") || !strings.Contains(out, "\n-  appIdentifier: org.example.myapp\n+  appIdentifier: org.example.other\n") {
+					t.Fatal("expected the diff of the appIdentifier but got", out)
+				}
+			},
+		},
+		{
+			Name:  "test update client does nothing when nothing changes",
+			Flags: []string{"--namespace=test", "--name=myapp", "--label", "old=true"},
+			Validate: func(t *testing.T, out string) {
+				if out != "" {
+					t.Fatal("expected no output but got", out)
+				}
+			},
+		},
+		{
+			Name:  "test update client with --dry-run prints the patch",
+			Flags: []string{"--namespace=test", "--dmz-url=https://dmz.example.com", "--dry-run", "-o=json"},
+			Validate: func(t *testing.T, out string) {
+				var changes []cmd.DryRunChange
+				if err := json.Unmarshal([]byte(out), &changes); err != nil {
+					t.Fatal("failed to unmarshal the dry run changes", err)
+				}
+				if len(changes) != 1 || changes[0].Action != "patch" || changes[0].Name != "myapp-android" {
+					t.Fatalf("expected the mobile client to be patched but got %v", changes)
+				}
+			},
+		},
+		{
+			Name:         "test update client fails with an invalid dmz url",
+			Flags:        []string{"--namespace=test", "--dmz-url=not a url"},
+			ExpectError:  true,
+			ErrorPattern: "^invalid update of mobile client myapp-android: invalid dmzUrl not a url",
+		},
+		{
+			Name:         "test update client fails with an empty app identifier",
+			Flags:        []string{"--namespace=test", "--app-identifier="},
+			ExpectError:  true,
+			ErrorPattern: "^invalid update of mobile client myapp-android: expected an appIdentifier",
+		},
+		{
+			Name:        "test update client removes the dmz url when it is empty",
+			Flags:       []string{"--namespace=test", "--dmz-url=", "-o=json"},
+			ExpectPatch: `{"spec":{"dmzUrl":null}}`,
+		},
+		{
+			Name:         "test update client checks the app identifier against the client type",
+			Flags:        []string{"--namespace=test", "--app-identifier=org.example.my-app"},
+			ExpectError:  true,
+			ErrorPattern: "^invalid update of mobile client myapp-android: invalid appIdentifier org.example.my-app for the android client type",
+		},
+		{
+			Name:         "test update client fails with an invalid label",
+			Flags:        []string{"--namespace=test", "--label", "team"},
+			ExpectError:  true,
+			ErrorPattern: "^invalid label team",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mobileClient := mcFake.NewSimpleClientset(&v1alpha1.MobileClient{
+				ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android", Namespace: "test", Labels: map[string]string{"old": "true"}},
+				Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", DmzUrl: "https://old-dmz.example.com", ApiKey: "1f3a2b4c-5d6e-4f70-8192-a3b4c5d6e7f8"},
+			})
+			var patch string
+			mobileClient.PrependReactor("patch", "mobileclients", func(action kt.Action) (bool, runtime.Object, error) {
+				patch = string(action.(kt.PatchActionImpl).GetPatch())
+				return true, &v1alpha1.MobileClient{ObjectMeta: kMetav1.ObjectMeta{Name: "myapp-android"}}, nil
+			})
+
+			var stdOut bytes.Buffer
+			root := cmd.NewRootCmd()
+			clientCmd := cmd.NewClientCmd(mobileClient, &scFake.Clientset{}, &ktFake.Clientset{}, &stdOut)
+			updateCmd := clientCmd.UpdateClientCmd()
+			root.AddCommand(updateCmd)
+			if err := updateCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := updateCmd.RunE(updateCmd, []string{"myapp-android"})
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ExpectError {
+				if m, regErr := regexp.Match(tc.ErrorPattern, []byte(err.Error())); !m {
+					t.Fatal("expected the error to match the pattern "+tc.ErrorPattern, err, regErr)
+				}
+			}
+			if patch != tc.ExpectPatch {
+				t.Fatalf("expected the patch %s but got %s", tc.ExpectPatch, patch)
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, stdOut.String())
+			}
+		})
+	}
+}
+
+func TestMobileClientsCmd_TestCreateClientPreflight(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Args          []string
+		Quota         *corev1.ResourceQuota
+		FailCreate    bool
+		FailProvision bool
+		ErrorPattern  string
+	}{
+		{
+			Name:         "test create client checks the appIdentifier of an android client is a package name",
+			Args:         []string{"myapp", "android", "org.example.my-app"},
+			ErrorPattern: "^invalid mobile client: invalid appIdentifier org.example.my-app for the android client type, it should be an Android package name",
+		},
+		{
+			Name:         "test create client checks the appIdentifier of a cordova client is valid for android and iOS",
+			Args:         []string{"myapp", "cordova", "org.example.my_app"},
+			ErrorPattern: "^invalid mobile client: invalid appIdentifier org.example.my_app for the cordova client type, it should be a reverse domain name",
+		},
+		{
+			Name:         "test create client checks the client ID is a valid name",
+			Args:         []string{"my_app", "android", "org.example.myapp"},
+			ErrorPattern: "^invalid mobile client: the name my_app can not be used in the client ID my_app-android",
+		},
+		{
+			Name: "test create client creates nothing when the secrets quota is used up",
+			Args: []string{"myapp", "android", "org.example.myapp"},
+			Quota: &corev1.ResourceQuota{
+				ObjectMeta: kMetav1.ObjectMeta{Name: "mobile", Namespace: "myproject"},
+				Status: corev1.ResourceQuotaStatus{
+					Hard: corev1.ResourceList{corev1.ResourceSecrets: resource.MustParse("10")},
+					Used: corev1.ResourceList{corev1.ResourceSecrets: resource.MustParse("10")},
+				},
+			},
+			ErrorPattern: "^can not create the mobile client: the resource quota mobile of the namespace myproject allows no more secrets, 10 of 10 are used$",
+		},
+		{
+			Name:         "test create client deletes the parameters secret when the service instance can not be created",
+			Args:         []string{"myapp", "android", "org.example.myapp"},
+			FailCreate:   true,
+			ErrorPattern: "^failed to create mobile client: forbidden by admission$",
+		},
+		{
+			Name:          "test create client deletes the service instance and parameters secret when provisioning fails",
+			Args:          []string{"myapp", "android", "org.example.myapp"},
+			FailProvision: true,
+			ErrorPattern:  "^Failed to provision android-app: the apb failed$",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			class := func(serviceName string) *v1beta1.ClusterServiceClass {
+				data, _ := json.Marshal(cmd.ExternalServiceMetaData{ServiceName: serviceName})
+				return &v1beta1.ClusterServiceClass{
+					ObjectMeta: kMetav1.ObjectMeta{Name: serviceName + "-class"},
+					Spec:       v1beta1.ClusterServiceClassSpec{ExternalName: serviceName, ExternalMetadata: &runtime.RawExtension{Raw: data}},
+				}
+			}
+			scClient := scFake.NewSimpleClientset(class("android-app"), class("cordova-app"), defaultPlan("android-app-class"), defaultPlan("cordova-app-class"))
+			scClient.PrependReactor("create", "serviceinstances", func(action kt.Action) (handled bool, ret runtime.Object, err error) {
+				if tc.FailCreate {
+					return true, nil, errors.New("forbidden by admission")
+				}
+				// the fake does not generate names
+				action.(kt.CreateAction).GetObject().(*v1beta1.ServiceInstance).Name = "android-app-abcde"
+				return false, nil, nil
+			})
+			scClient.PrependWatchReactor("serviceinstances", func(action kt.Action) (handled bool, ret watch.Interface, err error) {
+				condition := v1beta1.ServiceInstanceCondition{Type: v1beta1.ServiceInstanceConditionReady, Status: v1beta1.ConditionTrue}
+				if tc.FailProvision {
+					condition = v1beta1.ServiceInstanceCondition{Type: v1beta1.ServiceInstanceConditionFailed, Status: v1beta1.ConditionTrue, Message: "the apb failed"}
+				}
+				fakeWatch := watch.NewRaceFreeFake()
+				fakeWatch.Action(watch.Modified, &v1beta1.ServiceInstance{
+					ObjectMeta: kMetav1.ObjectMeta{Name: "android-app-abcde", Namespace: "myproject"},
+					Status:     v1beta1.ServiceInstanceStatus{Conditions: []v1beta1.ServiceInstanceCondition{condition}},
+				})
+				return true, fakeWatch, nil
+			})
+			var objects []runtime.Object
+			if tc.Quota != nil {
+				objects = append(objects, tc.Quota)
+			}
+			k8Client := ktFake.NewSimpleClientset(objects...)
+			mobileClient := mcFake.NewSimpleClientset()
+
+			var stdOut bytes.Buffer
+			root := cmd.NewRootCmd()
+			createCmd := cmd.NewClientCmd(mobileClient, scClient, k8Client, &stdOut).CreateClientCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags([]string{"--namespace=myproject", "-o=json"}); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := createCmd.RunE(createCmd, tc.Args)
+			if err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+				t.Fatalf("expected the error to match the pattern %s but got %s", tc.ErrorPattern, err.Error())
+			}
+			secrets, _ := k8Client.CoreV1().Secrets("myproject").List(kMetav1.ListOptions{})
+			instances, _ := scClient.ServicecatalogV1beta1().ServiceInstances("myproject").List(kMetav1.ListOptions{})
+			if len(secrets.Items) != 0 || len(instances.Items) != 0 {
+				t.Fatalf("expected nothing to be left in the namespace but got %v and %v", secrets.Items, instances.Items)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// diffContext is the number of unchanged lines around the changes in a unified diff
+const diffContext = 3
+
+// diffSnapshot is one side of a diff. The resources are keyed by what identifies them in any namespace so the two sides line up
+type diffSnapshot struct {
+	ServiceConfigs map[string]ManifestServiceConfig `json:"serviceConfigs"`
+	Services       map[string]ManifestService       `json:"services"`
+	Clients        map[string]ManifestClient        `json:"clients"`
+	Integrations   map[string]ManifestIntegration   `json:"integrations"`
+	// ClientConfigs holds the service configs generated for each mobile client by service, it is nil for manifests
+	ClientConfigs map[string]map[string]*ServiceConfig `json:"clientConfigs,omitempty"`
+}
+
+// diffSections are the kinds of resources compared, in the order they are reported
+var diffSections = []struct {
+	kind string
+	key  string
+}{
+	{"ServiceConfig", "serviceConfigs"},
+	{"ServiceInstance", "services"},
+	{"MobileClient", "clients"},
+	{"Integration", "integrations"},
+	{"ClientConfig", "clientConfigs"},
+}
+
+// DiffCmd builds the diff command
+func (mc *ManifestCmd) DiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <namespace> | -f <manifest>",
+		Short: "compare the mobile clients, services and integrations in your namespace with another namespace or a manifest",
+		Long: `diff compares the mobile clients, the plans and parameters of the service instances, the integrations and the external service configs
+in your namespace with those in another namespace, which can be on another kubeconfig context with --to-context, or with a manifest from mobile export.
+When comparing two namespaces the service configs generated for each mobile client, as returned by mobile get clientconfig, are compared too.
+
+Resources are matched by service, client ID and integrated services rather than by the generated names of their instances.
+Parameters that are declared as passwords or look like secrets are not compared.
+
+The differences are printed as a unified diff from your namespace to the other one, or as a list of differences with -o json.`,
+		Example: `  mobile diff prod --namespace=staging
+  mobile diff prod --namespace=staging --to-context=prod-cluster
+  mobile diff -f mobile.yaml --namespace=myproject -o json
+  kubectl plugin mobile diff prod
+  oc plugin mobile diff prod`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			filename, err := cmd.PersistentFlags().GetString("filename")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			toContext, err := cmd.PersistentFlags().GetString("to-context")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if (filename == "") == (len(args) == 0) || len(args) > 1 {
+				return cmd.Usage()
+			}
+
+			var to *diffSnapshot
+			var toName string
+			if filename != "" {
+				manifest, err := readManifest(filename, os.Stdin)
+				if err != nil {
+					return err
+				}
+				to = manifestSnapshot(manifest)
+				toName = filename
+			} else {
+				other := mc
+				toName = args[0]
+				if toContext != "" {
+					if mc.clientsForContext == nil {
+						return errors.New("--to-context is not supported")
+					}
+					mobileClient, scClient, k8Client, err := mc.clientsForContext(toContext)
+					if err != nil {
+						return errors.Wrap(err, "failed to connect to the context "+toContext)
+					}
+					other = NewManifestCmd(mobileClient, scClient, k8Client, nil, nil, ioutil.Discard)
+					toName = toContext + "/" + toName
+				}
+				if to, err = other.snapshot(args[0], true); err != nil {
+					return err
+				}
+			}
+			// the client configs are only generated in namespaces
+			from, err := mc.snapshot(ns, filename == "")
+			if err != nil {
+				return err
+			}
+
+			outType := outputType(cmd.Flags())
+			if outType == "json" {
+				differences, err := diffSnapshots(from, to)
+				if err != nil {
+					return err
+				}
+				if err := mc.Out.Render("diff", outType, differences); err != nil {
+					return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "differences", outType))
+				}
+				return nil
+			}
+			fromYAML, err := yaml.Marshal(from)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			toYAML, err := yaml.Marshal(to)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			diff := unifiedDiff(ns, toName, strings.Split(strings.TrimSuffix(string(fromYAML), "\n"), "\n"), strings.Split(strings.TrimSuffix(string(toYAML), "\n"), "\n"))
+			if err := mc.Out.Render("diff", outType, diff); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "differences", outType))
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringP("filename", "f", "", "-f mobile.yaml compare with the manifest rather than with a namespace, - reads it from stdin")
+	cmd.PersistentFlags().String("to-context", "", "--to-context=prod-cluster the kubeconfig context of the namespace to compare with, defaults to the current context")
+	mc.Out.AddRenderer("diff", "table", func(out io.Writer, data interface{}) error {
+		_, err := fmt.Fprint(out, data.(string))
+		return err
+	})
+	return cmd
+}
+
+// snapshot exports the namespace as the side of a diff, with the service configs generated for each mobile client when clientConfigs is set
+func (mc *ManifestCmd) snapshot(ns string, clientConfigs bool) (*diffSnapshot, error) {
+	manifest, _, err := mc.export(ns)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := manifestSnapshot(manifest)
+	if !clientConfigs {
+		return snapshot, nil
+	}
+	mcList, err := mc.mobileClient.MobileV1alpha1().MobileClients(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list mobile clients")
+	}
+	snapshot.ClientConfigs = map[string]map[string]*ServiceConfig{}
+	for _, client := range mcList.Items {
+		client := client
+		configs, err := clientServiceConfigs(mc.k8Client, ns, client.Name, &client)
+		if err != nil {
+			return nil, err
+		}
+		byService := map[string]*ServiceConfig{}
+		for _, c := range configs {
+			// the ID is the name of the secret the config was generated from
+			c.ID = ""
+			byService[c.Name] = c
+		}
+		snapshot.ClientConfigs[client.Name] = byService
+	}
+	return snapshot, nil
+}
+
+// manifestSnapshot keys the resources of the manifest by what identifies them
+func manifestSnapshot(manifest *Manifest) *diffSnapshot {
+	snapshot := &diffSnapshot{
+		ServiceConfigs: map[string]ManifestServiceConfig{},
+		Services:       map[string]ManifestService{},
+		Clients:        map[string]ManifestClient{},
+		Integrations:   map[string]ManifestIntegration{},
+	}
+	for _, c := range manifest.ServiceConfigs {
+		snapshot.ServiceConfigs[c.Name] = c
+	}
+	for _, s := range manifest.Services {
+		if s.Plan == "" {
+			s.Plan = defaultServicePlan
+		}
+		snapshot.Services[s.Service] = s
+	}
+	for _, c := range manifest.Clients {
+		sort.Strings(c.ExcludedServices)
+		snapshot.Clients[manifestClientID(c)] = c
+	}
+	for _, i := range manifest.Integrations {
+		snapshot.Integrations[integrationKey(i.Consumer, i.Provider)] = i
+	}
+	return snapshot
+}
+
+// diffSnapshots lists the resources added, removed and changed going from one snapshot to the other.
+// Changed resources are reported field by field
+func diffSnapshots(from, to *diffSnapshot) ([]ManifestDifference, error) {
+	fromDoc, err := genericDocument(from)
+	if err != nil {
+		return nil, err
+	}
+	toDoc, err := genericDocument(to)
+	if err != nil {
+		return nil, err
+	}
+	differences := []ManifestDifference{}
+	for _, section := range diffSections {
+		fromResources, _ := fromDoc[section.key].(map[string]interface{})
+		toResources, _ := toDoc[section.key].(map[string]interface{})
+		for _, name := range unionKeys(fromResources, toResources) {
+			fromResource, inFrom := fromResources[name]
+			toResource, inTo := toResources[name]
+			switch {
+			case !inTo:
+				differences = append(differences, ManifestDifference{Kind: section.kind, Name: name, Change: DiffRemoved, From: fromResource})
+			case !inFrom:
+				differences = append(differences, ManifestDifference{Kind: section.kind, Name: name, Change: DiffAdded, To: toResource})
+			default:
+				fromFields := flattenDocument("", fromResource, map[string]interface{}{})
+				toFields := flattenDocument("", toResource, map[string]interface{}{})
+				for _, field := range unionKeys(fromFields, toFields) {
+					fromValue, inFrom := fromFields[field]
+					toValue, inTo := toFields[field]
+					change := DiffChanged
+					switch {
+					case !inFrom:
+						change = DiffAdded
+					case !inTo:
+						change = DiffRemoved
+					case reflect.DeepEqual(fromValue, toValue):
+						continue
+					}
+					differences = append(differences, ManifestDifference{Kind: section.kind, Name: name, Change: change, Field: field, From: fromValue, To: toValue})
+				}
+			}
+		}
+	}
+	return differences, nil
+}
+
+// genericDocument converts the snapshot to the maps and values it is encoded as in JSON
+func genericDocument(snapshot *diffSnapshot) (map[string]interface{}, error) {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return doc, nil
+}
+
+// flattenDocument maps the dotted path of every value in the document to the value. Lists are values
+func flattenDocument(prefix string, value interface{}, fields map[string]interface{}) map[string]interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		fields[prefix] = value
+		return fields
+	}
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flattenDocument(path, v, fields)
+	}
+	return fields
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unifiedDiff returns the changes from one list of lines to the other as a unified diff, or an empty string when they are the same
+func unifiedDiff(fromName, toName string, from, to []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			switch {
+			case from[i] == to[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte
+		line string
+		// from and to are the positions of the edit in each list
+		from, to int
+	}
+	var edits []edit
+	var changes []int
+	for i, j := 0, 0; i < len(from) || j < len(to); {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			edits = append(edits, edit{' ', from[i], i, j})
+			i++
+			j++
+		case i < len(from) && (j == len(to) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, len(edits))
+			edits = append(edits, edit{'-', from[i], i, j})
+			i++
+		default:
+			changes = append(changes, len(edits))
+			edits = append(edits, edit{'+', to[j], i, j})
+			j++
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for c := 0; c < len(changes); {
+		// changes closer together than twice the context share a hunk
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContext {
+			last++
+		}
+		start := changes[c] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := changes[last] + diffContext + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+		fromStart, toStart := edits[start].from+1, edits[start].to+1
+		var fromCount, toCount int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				fromCount++
+			}
+			if e.op != '-' {
+				toCount++
+			}
+		}
+		// an empty range starts at the line before it
+		if fromCount == 0 {
+			fromStart--
+		}
+		if toCount == 0 {
+			toStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		c = last + 1
+	}
+	return out.String()
+}
@@ -0,0 +1,187 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
+	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
+	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kFake "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestManifestCmd_DiffCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal("failed to create temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// namespace returns the objects of a namespace with a keycloak instance on the plan and a mobile client whose keycloak config has the url
+	namespace := func(ns, plan, url string) ([]runtime.Object, []runtime.Object, []runtime.Object) {
+		return []runtime.Object{
+			&v1beta1.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "keycloak-" + ns, Namespace: ns},
+				Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: "keycloak", ClusterServicePlanExternalName: plan}},
+			},
+		}, []runtime.Object{
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "keycloak-client-" + ns, Namespace: ns, Labels: map[string]string{"clientId": "myapp-android"}},
+				Data:       map[string][]byte{"name": []byte("keycloak"), "type": []byte("keycloak"), "uri": []byte(url)},
+			},
+		}, []runtime.Object{
+			&v1alpha1.MobileClient{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp-android", Namespace: ns},
+				Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ApiKey: "key-" + ns},
+			},
+		}
+	}
+	stagingSC, stagingK8, stagingMobile := namespace("staging", "default", "https://keycloak.staging.example.com")
+	prodSC, prodK8, prodMobile := namespace("prod", "large", "https://keycloak.prod.example.com")
+	prodSC = append(prodSC, &v1beta1.ServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "sync-prod", Namespace: "prod"},
+		Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: "fh-sync-server", ClusterServicePlanExternalName: "default"}},
+	})
+	otherCluster := func(context string) (mobile.Interface, versioned.Interface, kubernetes.Interface, error) {
+		if context != "prod-cluster" {
+			return nil, nil, nil, errors.New("context " + context + " does not exist")
+		}
+		return mcFake.NewSimpleClientset(prodMobile...), scFake.NewSimpleClientset(prodSC...), kFake.NewSimpleClientset(prodK8...), nil
+	}
+
+	cases := []struct {
+		Name              string
+		Manifest          string
+		Args              []string
+		Flags             []string
+		ExpectError       bool
+		ErrorPattern      string
+		ExpectDifferences []string
+		ExpectDiff        []string
+	}{
+		{
+			Name:       "test no differences are printed for the same namespace",
+			Args:       []string{"staging"},
+			Flags:      []string{"--namespace=staging"},
+			ExpectDiff: []string{},
+		},
+		{
+			Name:  "test differences between namespaces are listed field by field",
+			Args:  []string{"prod"},
+			Flags: []string{"--namespace=staging", "-o=json"},
+			ExpectDifferences: []string{
+				"added ServiceInstance fh-sync-server ",
+				"changed ServiceInstance keycloak plan",
+				"changed ClientConfig myapp-android keycloak.url",
+			},
+		},
+		{
+			Name:  "test a namespace on another context is compared as a unified diff",
+			Args:  []string{"prod"},
+			Flags: []string{"--namespace=staging", "--to-context=prod-cluster"},
+			ExpectDiff: []string{
+				"--- staging\n+++ prod-cluster/prod\n",
+				"\n+  fh-sync-server:\n",
+				"\n+    plan: large\n",
+				"\n-      url: https://keycloak.staging.example.com\n+      url: https://keycloak.prod.example.com\n",
+			},
+		},
+		{
+			Name:         "test unknown contexts are reported",
+			Args:         []string{"prod"},
+			Flags:        []string{"--namespace=staging", "--to-context=dev-cluster"},
+			ExpectError:  true,
+			ErrorPattern: "^failed to connect to the context dev-cluster: context dev-cluster does not exist$",
+		},
+		{
+			Name: "test a namespace is compared with a manifest without the client configs",
+			Manifest: `kind: MobileManifest
+services:
+- service: keycloak
+  plan: large
+`,
+			Flags: []string{"--namespace=staging", "-o=json"},
+			ExpectDifferences: []string{
+				"changed ServiceInstance keycloak plan",
+				"removed MobileClient myapp-android ",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			objects := func(staging, prod []runtime.Object) []runtime.Object {
+				return append(append([]runtime.Object{}, staging...), prod...)
+			}
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			manifestCmd := cmd.NewManifestCmd(
+				mcFake.NewSimpleClientset(objects(stagingMobile, prodMobile)...),
+				scFake.NewSimpleClientset(objects(stagingSC, prodSC)...),
+				kFake.NewSimpleClientset(objects(stagingK8, prodK8)...),
+				&fakeDeploymentConfigs{}, otherCluster, &out)
+			diffCmd := manifestCmd.DiffCmd()
+			root.AddCommand(diffCmd)
+			flags := tc.Flags
+			if tc.Manifest != "" {
+				file := filepath.Join(dir, "mobile.yaml")
+				if err := ioutil.WriteFile(file, []byte(tc.Manifest), 0600); err != nil {
+					t.Fatal("failed to write manifest", err)
+				}
+				flags = append(flags, "-f", file)
+			}
+			if err := diffCmd.ParseFlags(flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := diffCmd.RunE(diffCmd, tc.Args)
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but got none")
+			} else if tc.ExpectError {
+				if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+					t.Fatalf("expected the error to match %s but got %s", tc.ErrorPattern, err.Error())
+				}
+				return
+			} else if err != nil {
+				t.Fatal("did not expect an error but got one:", err)
+			}
+			if tc.ExpectDifferences != nil {
+				var differences []cmd.ManifestDifference
+				if err := json.Unmarshal(out.Bytes(), &differences); err != nil {
+					t.Fatal("failed to unmarshal the differences", err, out.String())
+				}
+				var actual []string
+				for _, d := range differences {
+					actual = append(actual, d.Change+" "+d.Kind+" "+d.Name+" "+d.Field)
+				}
+				if strings.Join(actual, "\n") != strings.Join(tc.ExpectDifferences, "\n") {
+					t.Fatalf("expected the differences\n%s\nbut got\n%s", strings.Join(tc.ExpectDifferences, "\n"), strings.Join(actual, "\n"))
+				}
+			}
+			if tc.ExpectDiff != nil {
+				if len(tc.ExpectDiff) == 0 && out.Len() != 0 {
+					t.Fatalf("expected no differences but got\n%s", out.String())
+				}
+				for _, expected := range tc.ExpectDiff {
+					if !strings.Contains(out.String(), expected) {
+						t.Fatalf("expected the diff to contain\n%s\nbut got\n%s", expected, out.String())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/pkg/api/v1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
+var (
+	secretsResource          = v1.SchemeGroupVersion.WithResource("secrets")
+	podPresetsResource       = kalpha.SchemeGroupVersion.WithResource("podpresets")
+	serviceInstancesResource = v1beta1.SchemeGroupVersion.WithResource("serviceinstances")
+	serviceBindingsResource  = v1beta1.SchemeGroupVersion.WithResource("servicebindings")
+	mobileClientsResource    = v1alpha1.SchemeGroupVersion.WithResource("mobileclients")
+)
+
+// dryRun records the changes a mutating command would make instead of making them.
+// With --dry-run=server the creates and deletes are also sent to the API server with dryRun=All so they are validated and admitted but never persisted
+type dryRun struct {
+	mode    string
+	ns      string
+	changes []DryRunChange
+	// openAPI maps the paths served by the cluster to the methods supporting dry run
+	openAPI map[string]map[string]bool
+}
+
+// newDryRun returns the dry run requested by the --dry-run flag or nil when the command should make its changes
+func newDryRun(flags *pflag.FlagSet, ns string) (*dryRun, error) {
+	if flags.Lookup("dry-run") == nil {
+		return nil, nil
+	}
+	mode, err := flags.GetString("dry-run")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch mode {
+	case "":
+		return nil, nil
+	case dryRunClient, dryRunServer:
+		return &dryRun{mode: mode, ns: ns}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unknown --dry-run mode %s. Use --dry-run=client or --dry-run=server", mode))
+}
+
+// noDryRun refuses --dry-run for the commands that can not show the changes they would make
+func noDryRun(cmd *cobra.Command) error {
+	if mode, err := cmd.Flags().GetString("dry-run"); err == nil && mode != "" {
+		return errors.New(commandPath(cmd) + " does not support --dry-run")
+	}
+	return nil
+}
+
+// create records the creation of the object and returns the name it would be created with
+func (d *dryRun) create(client rest.Interface, gvr schema.GroupVersionResource, kind string, obj runtime.Object) (string, error) {
+	obj.GetObjectKind().SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	change := DryRunChange{Action: "create", Kind: kind, Namespace: d.ns, Name: accessor.GetName(), Object: obj}
+	if change.Name == "" {
+		// the server generates the rest of the name
+		change.Name = accessor.GetGenerateName() + "xxxxx"
+	}
+	if d.mode == dryRunServer {
+		if err := d.checkServerSupport(client, gvr, "post"); err != nil {
+			return "", err
+		}
+		raw, err := client.Post().Namespace(d.ns).Resource(gvr.Resource).Param("dryRun", "All").Body(obj).DoRaw()
+		if err != nil {
+			return "", errors.Wrap(err, fmt.Sprintf("the server rejected the %s", kind))
+		}
+		submitted := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &submitted); err != nil {
+			return "", errors.Wrap(err, "failed to read the dry run response of the server")
+		}
+		change.Object = submitted
+		if metadata, ok := submitted["metadata"].(map[string]interface{}); ok {
+			if name, ok := metadata["name"].(string); ok {
+				change.Name = name
+			}
+		}
+	}
+	d.changes = append(d.changes, change)
+	return change.Name, nil
+}
+
+// delete records the deletion of the named object
+func (d *dryRun) delete(client rest.Interface, gvr schema.GroupVersionResource, kind, name string) error {
+	if d.mode == dryRunServer {
+		if err := d.checkServerSupport(client, gvr, "delete"); err != nil {
+			return err
+		}
+		if _, err := client.Delete().Namespace(d.ns).Resource(gvr.Resource).Name(name).Param("dryRun", "All").DoRaw(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("the server rejected the deletion of %s %s", kind, name))
+		}
+	}
+	d.changes = append(d.changes, DryRunChange{Action: "delete", Kind: kind, Namespace: d.ns, Name: name})
+	return nil
+}
+
+// patch records the merge patch of the named object. Patches are only ever printed
+func (d *dryRun) patch(kind, name string, patch interface{}) {
+	d.changes = append(d.changes, DryRunChange{Action: "patch", Kind: kind, Namespace: d.ns, Name: name, Patch: patch})
+}
+
+// render prints the recorded changes as JSON with -o json and as YAML otherwise
+func (d *dryRun) render(out *output.Renderer, flags *pflag.FlagSet) error {
+	outType := outputType(flags)
+	if outType != "json" {
+		outType = "yaml"
+	}
+	changes := d.changes
+	if changes == nil {
+		changes = []DryRunChange{}
+	}
+	if err := out.Render("dryrun", outType, changes); err != nil {
+		return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "dry run", outType))
+	}
+	return nil
+}
+
+// checkServerSupport refuses to send a change to an API server that does not support dry run as it would make the change for real.
+// Support is read from the dryRun parameter of the operation in the OpenAPI document of the cluster
+func (d *dryRun) checkServerSupport(client rest.Interface, gvr schema.GroupVersionResource, method string) error {
+	if d.openAPI == nil {
+		raw, err := client.Get().AbsPath("/openapi/v2").DoRaw()
+		if err != nil {
+			return errors.Wrap(err, "failed to check whether the server supports dry run")
+		}
+		if d.openAPI, err = dryRunOperations(raw); err != nil {
+			return err
+		}
+	}
+	prefix := "/apis/" + gvr.Group + "/" + gvr.Version
+	if gvr.Group == "" {
+		prefix = "/api/" + gvr.Version
+	}
+	path := prefix + "/namespaces/{namespace}/" + gvr.Resource
+	if method != "post" {
+		path += "/{name}"
+	}
+	if !d.openAPI[path][method] {
+		resource := gvr.GroupResource()
+		return errors.New(fmt.Sprintf("the server does not support dry run for %s. Use --dry-run=client instead", resource.String()))
+	}
+	return nil
+}
+
+// dryRunOperations lists the methods of each path in the OpenAPI document that accept the dryRun parameter
+func dryRunOperations(raw []byte) (map[string]map[string]bool, error) {
+	type parameter struct {
+		Name string `json:"name"`
+		Ref  string `json:"$ref"`
+	}
+	type operation struct {
+		Parameters []parameter `json:"parameters"`
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to read the OpenAPI document of the server")
+	}
+	isDryRun := func(params []parameter) bool {
+		for _, p := range params {
+			if p.Name == "dryRun" || strings.HasPrefix(p.Ref, "#/parameters/dryRun") {
+				return true
+			}
+		}
+		return false
+	}
+	operations := map[string]map[string]bool{}
+	for path, item := range doc.Paths {
+		// parameters can be shared by all the operations of a path
+		var shared []parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, errors.Wrap(err, "failed to read the OpenAPI document of the server")
+			}
+		}
+		operations[path] = map[string]bool{}
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, errors.Wrap(err, "failed to read the OpenAPI document of the server")
+			}
+			operations[path][method] = isDryRun(shared) || isDryRun(op.Parameters)
+		}
+	}
+	return operations, nil
+}
@@ -15,31 +15,72 @@
 package input
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	"github.com/pkg/errors"
 )
 
-func ValidateMobileClient(client *v1alpha1.MobileClient) error {
-	if !ValidClients.Contains(client.Spec.ClientType) {
-		return errors.New("invalid clientType " + client.Spec.ClientType + " valid clientTypes are " + strings.Join(ValidClients, ","))
+// ValidateMobileClient checks the mobile client against the MobileClient CRD. Its clientType must be one of clientTypes,
+// the types of mobile client the cluster can provision, unless clientTypes is nil
+func ValidateMobileClient(client *v1alpha1.MobileClient, clientTypes []string) error {
+	if clientTypes != nil && !contains(clientTypes, client.Spec.ClientType) {
+		return errors.New("invalid clientType " + client.Spec.ClientType + " valid clientTypes are " + strings.Join(clientTypes, ","))
 	}
 	if client.Spec.AppIdentifier == "" {
 		return errors.New("expected an appIdentifier to be passed. It should not be empty. This should be the same as your bundleID or package name")
 	}
+	for _, f := range specPatterns {
+		if value := f.value(client.Spec); value != "" && !f.pattern.MatchString(value) {
+			return errors.New("invalid " + f.field + " " + value + " it should match " + f.pattern.String())
+		}
+	}
+	if client.Spec.Name == "" {
+		return errors.New("expected a name to be passed. It should not be empty")
+	}
 	return nil
 }
 
-type validClients []string
+// specPatterns are the patterns of the MobileClient CRD validation, empty values are not checked
+var specPatterns = []struct {
+	field   string
+	pattern *regexp.Regexp
+	value   func(spec v1alpha1.MobileClientSpec) string
+}{
+	{"name", regexp.MustCompile(`([\w-])`), func(spec v1alpha1.MobileClientSpec) string { return spec.Name }},
+	{"apiKey", regexp.MustCompile(`(\w{8}-\w{4}-\w{4}-\w{4}-\w{11})`), func(spec v1alpha1.MobileClientSpec) string { return spec.ApiKey }},
+	{"appIdentifier", regexp.MustCompile(`([\w-])`), func(spec v1alpha1.MobileClientSpec) string { return spec.AppIdentifier }},
+	{"dmzUrl", regexp.MustCompile(`(http(s)?:\/\/.)?(www\.)?[-a-zA-Z0-9@:%._\+~#=]{2,256}\.[a-z]{2,6}\b([-a-zA-Z0-9@:%_\+.~#?&//=]*)`), func(spec v1alpha1.MobileClientSpec) string { return spec.DmzUrl }},
+}
 
-func (vc validClients) Contains(client string) bool {
-	for _, c := range vc {
-		if c == client {
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
 			return true
 		}
 	}
 	return false
 }
 
-var ValidClients = validClients{"iOS", "android", "cordova", "xamarin"}
+// appIdentifierFormats are the formats of the appIdentifier of the platforms, the other client types build apps for both
+var appIdentifierFormats = map[string]struct {
+	pattern     *regexp.Regexp
+	description string
+}{
+	"android": {regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*(\.[a-zA-Z][a-zA-Z0-9_]*)+$`), "an Android package name such as org.example.myapp"},
+	"iOS":     {regexp.MustCompile(`^[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)+$`), "an iOS bundle ID such as org.example.myapp"},
+	"":        {regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*(\.[a-zA-Z][a-zA-Z0-9]*)+$`), "a reverse domain name such as org.example.myapp that is both an Android package name and an iOS bundle ID"},
+}
+
+// ValidateAppIdentifier checks the appIdentifier is a valid package name or bundle ID for the platforms of the client type
+func ValidateAppIdentifier(clientType, appIdentifier string) error {
+	format, ok := appIdentifierFormats[clientType]
+	if !ok {
+		format = appIdentifierFormats[""]
+	}
+	if !format.pattern.MatchString(appIdentifier) {
+		return errors.New("invalid appIdentifier " + appIdentifier + " for the " + clientType + " client type, it should be " + format.description)
+	}
+	return nil
+}
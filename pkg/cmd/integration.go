@@ -21,14 +21,16 @@ import (
 	"io"
 
 	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
 	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
 	sc "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
 	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
@@ -36,12 +38,20 @@ import (
 
 type IntegrationCmd struct {
 	*BaseCmd
-	scClient sc.Interface
-	k8Client kubernetes.Interface
+	scClient     sc.Interface
+	k8Client     kubernetes.Interface
+	mobileClient mobile.Interface
+	workloads    *workloads
 }
 
-func NewIntegrationCmd(scClient sc.Interface, k8Client kubernetes.Interface, out io.Writer) *IntegrationCmd {
-	return &IntegrationCmd{scClient: scClient, k8Client: k8Client, BaseCmd: &BaseCmd{Out: output.NewRenderer(out)}}
+func NewIntegrationCmd(scClient sc.Interface, k8Client kubernetes.Interface, mobileClient mobile.Interface, dcClient DeploymentConfigInterface, out io.Writer) *IntegrationCmd {
+	return &IntegrationCmd{
+		scClient:     scClient,
+		k8Client:     k8Client,
+		mobileClient: mobileClient,
+		workloads:    &workloads{k8Client: k8Client, dcClient: dcClient},
+		BaseCmd:      &BaseCmd{Out: output.NewRenderer(out)},
+	}
 }
 
 func createBindingObject(consumer, provider, bindingName, instance string, bindParams *ServiceParams, secretName string) (*v1beta1.ServiceBinding, error) {
@@ -94,7 +104,7 @@ func podPreset(objectName, secretName, providerSvcName, consumerSvcName string)
 			VolumeMounts: []v1.VolumeMount{
 				{
 					Name:      providerSvcName,
-					MountPath: "/etc/secrets/" + providerSvcName,
+					MountPath: secretMountPath(providerSvcName),
 				},
 			},
 		},
@@ -131,18 +141,17 @@ func (bc *IntegrationCmd) CreateIntegrationCmd() *cobra.Command {
 redeploys the consuming service.
 To get the IDs of your consuming/providing service instances, run the "mobile get serviceinstances <serviceName>" command from this tool.
 
+--auto-redeploy labels the pod template of the Deployment, DeploymentConfig or StatefulSet named after the consuming service, or of those matching
+--redeploy-selector, and waits for the rollout to finish.
 If both the --no-wait and --auto-redeploy flags are set to true, --auto-redeploy will override --no-wait.`,
 		Example: `  mobile create integration <consuming_service_instance_id> <providing_service_instance_id> --namespace=myproject
+  mobile create integration <consuming_service_instance_id> <providing_service_instance_id> --auto-redeploy --redeploy-selector=app=fh-sync-server
   kubectl plugin mobile create integration <consuming_service_instance_id> <providing_service_instance_id>
   oc plugin mobile create integration <consuming_service_instance_id> <providing_service_instance_id>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return cmd.Usage()
 			}
-			quiet, err := cmd.Flags().GetBool("quiet")
-			if err != nil {
-				return errors.Wrap(err, "failed to get quiet flag")
-			}
 			namespace, err := currentNamespace(cmd.Flags())
 			if err != nil {
 				return errors.Wrap(err, "failed to get namespace")
@@ -165,48 +174,36 @@ If both the --no-wait and --auto-redeploy flags are set to true, --auto-redeploy
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			// Get available bind parameters from the provider cluster service plan
-			clusterServiceClass, err := findServiceClassByName(bc.scClient, providerServiceName)
+			flagParams, err := paramsFromFlags(cmd.Flags())
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			clusterServicePlan, err := findServicePlanByNameAndClass(bc.scClient, "default", clusterServiceClass.Name)
+			bindParams, err := bc.bindParams(providerSvcInst, providerServiceName, flagParams, isInteractive(cmd.Flags()))
 			if err != nil {
-				return errors.WithStack(err)
-			}
-
-			bindParams := &ServiceParams{}
-			if err := json.Unmarshal(clusterServicePlan.Spec.ServiceBindingCreateParameterSchema.Raw, bindParams); err != nil {
-				return errors.WithStack(err)
+				return err
 			}
 
-			flagParams, err := cmd.Flags().GetStringArray("params")
+			preset, binding, err := buildIntegration(consumerSvcInstName, providerSvcInstName, consumerServiceName, providerServiceName, bindParams)
 			if err != nil {
-				return errors.WithStack(err)
+				return err
 			}
-
-			// Get bind parameters value from user input
-			bindParams, err = GetParams(flagParams, bindParams)
+			dryRun, err := newDryRun(cmd.Flags(), namespace)
 			if err != nil {
-				return errors.WithStack(err)
+				return err
 			}
-
-			objectName := objectName(consumerSvcInstName, providerSvcInstName)
-			preset := podPreset(objectName, objectName, providerServiceName, consumerServiceName)
-
-			// Create Pod Preset for service
-			if _, err := bc.k8Client.SettingsV1alpha1().PodPresets(namespace).Create(preset); err != nil {
-				return errors.Wrap(err, "failed to create pod preset for service ")
-			}
-			// prepare and create our binding
-			binding, err := createBindingObject(consumerServiceName, providerServiceName, objectName, providerSvcInstName, bindParams, objectName)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			sb, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Create(binding)
-			if err != nil {
-				return errors.WithStack(err)
+			if dryRun != nil {
+				if _, err := dryRun.create(bc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", preset); err != nil {
+					return err
+				}
+				if _, err := dryRun.create(bc.scClient.ServicecatalogV1beta1().RESTClient(), serviceBindingsResource, "ServiceBinding", binding); err != nil {
+					return err
+				}
+				if err := bc.dryRunRedeploy(dryRun, cmd.Flags(), namespace, consumerSvcInstName, consumerServiceName, providerServiceName, "enabled"); err != nil {
+					return err
+				}
+				return dryRun.render(bc.Out, cmd.Flags())
 			}
+
 			// check if a redeploy was asked for
 			redeploy, err := cmd.PersistentFlags().GetBool("auto-redeploy")
 			if err != nil {
@@ -216,68 +213,197 @@ If both the --no-wait and --auto-redeploy flags are set to true, --auto-redeploy
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			// the objects created and the labels set are reverted if a later step fails
+			steps := newJournal(cmd.Flags())
+			sb, err := bc.createIntegration(namespace, preset, binding, steps)
+			if err != nil {
+				return steps.finish(err)
+			}
 			if noWait && !redeploy {
 				fmt.Println("you will need to redeploy your service/pod to pick up the changes")
-				return nil
+				return steps.finish(nil)
 			}
-			w, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Watch(metav1.ListOptions{})
+			waiter, err := newWaiter(cmd.Flags(), sb.Name, bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Watch)
 			if err != nil {
-				return errors.WithStack(err)
+				return steps.finish(err)
 			}
-			for u := range w.ResultChan() {
-				o := u.Object.(*v1beta1.ServiceBinding)
-				if o.Name != sb.Name {
-					continue
-				}
-				switch u.Type {
-				case watch.Error:
-					w.Stop()
-					return errors.New("unexpected error watching service binding " + err.Error())
-				case watch.Modified:
-					for _, c := range o.Status.Conditions {
-						if !quiet {
-							fmt.Println("status: " + c.Message)
-						}
-						if c.Type == "Ready" && c.Status == "True" {
-							w.Stop()
-						}
-						if c.Type == "Failed" {
-							w.Stop()
-							return errors.New("Failed to create integration: " + c.Message)
-						}
-					}
-				case watch.Deleted:
-					w.Stop()
-				}
+			if err := waiter.Until(wait.ServiceBindingReady()); err != nil {
+				return steps.finish(errors.Wrap(err, "Failed to create integration"))
 			}
-			// once the binding is finished update the deployment to cause a redeploy
+			// once the binding is finished label the consuming workloads so the pod preset is injected when they roll out
 			if redeploy {
-
-				// update the deployment with an annotation
-				dep, err := bc.k8Client.AppsV1beta1().Deployments(namespace).Get(consumerServiceName, metav1.GetOptions{})
-				if err != nil {
-					return errors.Wrap(err, "failed to get deployment for service "+consumerSvcInstName)
-				}
-				dep.Spec.Template.Labels[providerServiceName] = "enabled"
-				if _, err := bc.k8Client.AppsV1beta1().Deployments(namespace).Update(dep); err != nil {
-					return errors.Wrap(err, "failed to update deployment for service "+consumerSvcInstName)
+				if err := bc.redeploy(cmd.Flags(), namespace, consumerSvcInstName, consumerServiceName, providerServiceName, "enabled", steps); err != nil {
+					return steps.finish(err)
 				}
 			}
 
-			return nil
+			return steps.finish(nil)
 		},
 	}
 	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the binding is complete")
-	cmd.PersistentFlags().Bool("auto-redeploy", false, "--auto-redeploy=true will cause a backing deployment to be rolled out")
-	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set the parameters needed to set up the integration programatically rather than being prompted for them: -p PARAM1=val -p PARAM2=val2")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the binding to complete and for the redeploy to roll out before giving up. 0 waits forever")
+	addRedeployFlags(cmd)
+	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set the parameters needed to set up the integration programatically rather than being prompted for them: -p PARAM1=val -p PARAM2=val2. Values can be read from a file with -p PARAM=@file or from the environment with -p PARAM=env:VAR, start the value with \\ to use it as it is: -p PARAM=\\@value")
+	cmd.PersistentFlags().String("params-file", "", "--params-file=params.yaml read the parameters from a YAML or JSON file. Values set with --params override the values in the file")
+	addNoRollbackFlag(cmd)
 
 	return cmd
 }
 
+// bindParams reads the bind parameters from the plan the provider was provisioned with and fills in their values
+func (bc *IntegrationCmd) bindParams(providerSvcInst *v1beta1.ServiceInstance, providerServiceName string, given map[string]string, interactive bool) (*ServiceParams, error) {
+	clusterServiceClass, err := findServiceClassByName(bc.scClient, providerServiceName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	clusterServicePlan, err := findServicePlanForInstance(bc.scClient, providerSvcInst, clusterServiceClass.Name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	bindParams, err := schemaParams(clusterServicePlan.Spec.ServiceBindingCreateParameterSchema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Get bind parameters value from user input
+	bindParams, err = GetParams(given, bindParams, interactive)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return bindParams, nil
+}
+
+// buildIntegration builds the pod preset and the binding making up the integration between the service instances
+func buildIntegration(consumerSvcInstName, providerSvcInstName, consumerServiceName, providerServiceName string, bindParams *ServiceParams) (*kalpha.PodPreset, *v1beta1.ServiceBinding, error) {
+	objectName := objectName(consumerSvcInstName, providerSvcInstName)
+	preset := podPreset(objectName, objectName, providerServiceName, consumerServiceName)
+
+	// prepare our binding
+	binding, err := createBindingObject(consumerServiceName, providerServiceName, objectName, providerSvcInstName, bindParams, objectName)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return preset, binding, nil
+}
+
+// createIntegration creates the pod preset and then the binding of an integration, recording them in steps
+func (bc *IntegrationCmd) createIntegration(ns string, preset *kalpha.PodPreset, binding *v1beta1.ServiceBinding, steps *journal) (*v1beta1.ServiceBinding, error) {
+	// Create Pod Preset for service
+	presets := bc.k8Client.SettingsV1alpha1().PodPresets(ns)
+	err := steps.do("pod preset", func() (string, error) {
+		if _, err := presets.Create(preset); err != nil {
+			return "", errors.Wrap(err, "failed to create pod preset for service ")
+		}
+		return preset.Name, nil
+	}, func(name string) error {
+		return presets.Delete(name, metav1.NewDeleteOptions(0))
+	})
+	if err != nil {
+		return nil, err
+	}
+	// create our binding
+	bindings := bc.scClient.ServicecatalogV1beta1().ServiceBindings(ns)
+	var sb *v1beta1.ServiceBinding
+	err = steps.do("service binding", func() (string, error) {
+		var err error
+		if sb, err = bindings.Create(binding); err != nil {
+			return "", errors.WithStack(err)
+		}
+		return sb.Name, nil
+	}, func(name string) error {
+		return bindings.Delete(name, metav1.NewDeleteOptions(0))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sb, nil
+}
+
+// deleteIntegration deletes the pod preset and the binding of the named integration
+func (bc *IntegrationCmd) deleteIntegration(ns, objectName string) error {
+	if err := bc.k8Client.SettingsV1alpha1().PodPresets(ns).Delete(objectName, metav1.NewDeleteOptions(0)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Delete(objectName, metav1.NewDeleteOptions(0)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// secretMountPath is where the pod preset of an integration mounts the binding secret of the provider
+func secretMountPath(providerSvcName string) string {
+	return "/etc/secrets/" + providerSvcName
+}
+
 func objectName(consumer, provider string) string {
 	return consumer + "-" + provider
 }
 
+func addRedeployFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("auto-redeploy", false, "--auto-redeploy=true will cause the Deployment, DeploymentConfig or StatefulSet of the consuming service to be rolled out")
+	cmd.PersistentFlags().String("redeploy-selector", "", "--redeploy-selector=app=myservice will roll out the Deployments, DeploymentConfigs and StatefulSets matching the label selector rather than the one named after the consuming service")
+}
+
+// redeploy sets, or removes when the value is empty, the <provider> label on the pod template of the consuming workloads
+// so the pod preset of the integration is injected or removed, then waits for them to roll out
+// The label changes are recorded in steps so they are set back to their previous value on a rollback
+func (bc *IntegrationCmd) redeploy(flags *pflag.FlagSet, ns, consumerSvcInstName, consumerServiceName, providerServiceName, value string, steps *journal) error {
+	selector, err := flags.GetString("redeploy-selector")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	workloads, err := bc.workloads.find(ns, consumerServiceName, selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to get deployment for service "+consumerSvcInstName)
+	}
+	for _, w := range workloads {
+		w := w
+		err := steps.do(w.Kind, func() (string, error) {
+			return w.Name, bc.workloads.setTemplateLabel(ns, w, providerServiceName, value)
+		}, func(name string) error {
+			return bc.workloads.setTemplateLabel(ns, w, providerServiceName, w.TemplateLabels[providerServiceName])
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to update deployment for service "+consumerSvcInstName)
+		}
+	}
+	poller, err := newPoller(flags)
+	if err != nil {
+		return err
+	}
+	// when adding an integration check the new pods have the secret of the provider mounted by the pod preset
+	mountPath := ""
+	if value != "" {
+		mountPath = secretMountPath(providerServiceName)
+	}
+	for _, w := range workloads {
+		if err := poller.Until(bc.workloads.rolloutStatus(ns, w, mountPath)); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to roll out %s %s", w.Kind, w.Name))
+		}
+	}
+	return nil
+}
+
+// dryRunRedeploy records the label patches redeploy would make to the consuming workloads when --auto-redeploy is set
+func (bc *IntegrationCmd) dryRunRedeploy(d *dryRun, flags *pflag.FlagSet, ns, consumerSvcInstName, consumerServiceName, providerServiceName, value string) error {
+	if redeploy, err := flags.GetBool("auto-redeploy"); err != nil || !redeploy {
+		return errors.WithStack(err)
+	}
+	selector, err := flags.GetString("redeploy-selector")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	workloads, err := bc.workloads.find(ns, consumerServiceName, selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to get deployment for service "+consumerSvcInstName)
+	}
+	for _, w := range workloads {
+		d.patch(w.Kind, w.Name, templateLabelPatch(providerServiceName, value))
+	}
+	return nil
+}
+
 func (bc *IntegrationCmd) DeleteIntegrationCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "integration <consuming_service_instance_id> <providing_service_instance_id>",
@@ -317,11 +443,24 @@ oc plugin mobile delete integration <consuming_service_instance_id> <providing_s
 				return errors.WithStack(err)
 			}
 			objectName := objectName(consumerSvcInstName, providerSvcInstName)
-			if err := bc.k8Client.SettingsV1alpha1().PodPresets(namespace).Delete(objectName, metav1.NewDeleteOptions(0)); err != nil {
-				return errors.WithStack(err)
+			dryRun, err := newDryRun(cmd.Flags(), namespace)
+			if err != nil {
+				return err
 			}
-			if err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Delete(objectName, metav1.NewDeleteOptions(0)); err != nil {
-				return errors.WithStack(err)
+			if dryRun != nil {
+				if err := dryRun.delete(bc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", objectName); err != nil {
+					return err
+				}
+				if err := dryRun.delete(bc.scClient.ServicecatalogV1beta1().RESTClient(), serviceBindingsResource, "ServiceBinding", objectName); err != nil {
+					return err
+				}
+				if err := bc.dryRunRedeploy(dryRun, cmd.Flags(), namespace, consumerSvcInstName, consumerServiceName, providerServiceName, ""); err != nil {
+					return err
+				}
+				return dryRun.render(bc.Out, cmd.Flags())
+			}
+			if err := bc.deleteIntegration(namespace, objectName); err != nil {
+				return err
 			}
 			redeploy, err := cmd.PersistentFlags().GetBool("auto-redeploy")
 			if err != nil {
@@ -332,39 +471,15 @@ oc plugin mobile delete integration <consuming_service_instance_id> <providing_s
 				return errors.WithStack(err)
 			}
 			if noWait && !redeploy {
-				fmt.Sprintln(bc.Out, "you will need to redeploy your service to pick up the changes")
+				fmt.Println("you will need to redeploy your service to pick up the changes")
 				return nil
 			}
 
-			w, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Watch(metav1.ListOptions{})
-			if err != nil {
-				return errors.WithStack(err)
+			getBinding := func() (runtime.Object, error) {
+				return bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Get(objectName, metav1.GetOptions{})
 			}
-			for u := range w.ResultChan() {
-				o := u.Object.(*v1beta1.ServiceBinding)
-				if o.Name != objectName {
-					continue
-				}
-				switch u.Type {
-				case watch.Error:
-					w.Stop()
-					return errors.New("unexpected error watching service binding " + err.Error())
-				case watch.Modified:
-					for _, c := range o.Status.Conditions {
-						if !quiet {
-							fmt.Println("status: " + c.Message)
-						}
-						if c.Type == "Ready" && c.Status == "True" {
-							w.Stop()
-						}
-						if c.Type == "Failed" {
-							w.Stop()
-							return errors.New("Failed to create integration: " + c.Message)
-						}
-					}
-				case watch.Deleted:
-					w.Stop()
-				}
+			if err := waitForDeletion(cmd.Flags(), objectName, getBinding, bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).Watch); err != nil {
+				return errors.Wrap(err, "Failed to delete integration")
 			}
 
 			if !quiet {
@@ -372,21 +487,62 @@ oc plugin mobile delete integration <consuming_service_instance_id> <providing_s
 			}
 
 			if redeploy {
-				//update the deployment with an annotation
-				dep, err := bc.k8Client.AppsV1beta1().Deployments(namespace).Get(consumerServiceName, metav1.GetOptions{})
-				if err != nil {
-					return errors.Wrap(err, "service "+consumerSvcInstName)
-				}
-				delete(dep.Spec.Template.Labels, providerServiceName)
-				if _, err := bc.k8Client.AppsV1beta1().Deployments(namespace).Update(dep); err != nil {
-					return errors.Wrap(err, "failed to update deployment for service "+consumerSvcInstName)
+				if err := bc.redeploy(cmd.Flags(), namespace, consumerSvcInstName, consumerServiceName, providerServiceName, "", nil); err != nil {
+					return err
 				}
 			}
 			return nil
 		},
 	}
 	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the binding is complete")
-	cmd.PersistentFlags().Bool("auto-redeploy", false, "--auto-redeploy=true will cause a backing deployment to be rolled out")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the binding to be deleted and for the redeploy to roll out before giving up. 0 waits forever")
+	addRedeployFlags(cmd)
+	return cmd
+}
+
+// WaitIntegrationCmd waits for an integration to reach a condition
+func (bc *IntegrationCmd) WaitIntegrationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "integration <integrationID>",
+		Short: "wait for an integration to reach a condition",
+		Long: `wait integration blocks until the integration reaches the condition set with --for or the timeout is reached.
+Run the "mobile get integrations" command from this tool to get the integration ID.`,
+		Example: `  mobile wait integration <integrationID> --for=condition=Ready --timeout=5m --namespace=myproject
+  mobile wait integration <integrationID> --for=delete
+  kubectl plugin mobile wait integration <integrationID>
+  oc plugin mobile wait integration <integrationID>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			name := args[0]
+			namespace, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			condition, err := waitFor(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			bindings := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace)
+			if condition == waitForDelete {
+				getBinding := func() (runtime.Object, error) {
+					return bindings.Get(name, metav1.GetOptions{})
+				}
+				return errors.Wrap(waitForDeletion(cmd.Flags(), name, getBinding, bindings.Watch), "failed waiting for integration "+name+" to be deleted")
+			}
+			sb, err := bindings.Get(name, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get integration "+name)
+			}
+			waiter, err := newWaiter(cmd.Flags(), name, bindings.Watch)
+			if err != nil {
+				return err
+			}
+			return errors.Wrap(waitFrom(waiter, sb, wait.ServiceBindingCondition(v1beta1.ServiceBindingConditionType(condition))), "failed waiting for integration "+name+" to be "+condition)
+		},
+	}
+	addWaitFlags(cmd)
 	return cmd
 }
 
@@ -405,23 +561,51 @@ func (bc *IntegrationCmd) ListIntegrationsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "integrations",
 		Short: "get a list of the current integrations between services",
+		Long: `get integrations lists the integrations between the services in your namespace.
+
+The integrations can also be drawn as a graph of the service instances, the mobile clients using them and the integrations between them
+with -o=dot for Graphviz, -o=mermaid for Mermaid or -o=graph-json for the graph as JSON.
+--highlight-broken colours the integrations whose binding or pod preset is missing or not ready.`,
+		Example: `  mobile get integrations --namespace=myproject
+  mobile get integrations -o=dot --highlight-broken | dot -Tsvg > integrations.svg
+  mobile get integrations -o=mermaid
+  kubectl plugin mobile get integrations
+  oc plugin mobile get integrations`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// list services bincinbx show their annotation values
 			namespace, err := currentNamespace(cmd.Flags())
 			if err != nil {
 				return errors.Wrap(err, "failed to get namespace")
 			}
+			outType := outputType(cmd.Flags())
+			if graphOutputTypes[outType] {
+				graph, err := bc.buildIntegrationGraph(namespace)
+				if err != nil {
+					return err
+				}
+				graph.HighlightBroken, err = cmd.PersistentFlags().GetBool("highlight-broken")
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if err := bc.Out.Render("list"+cmd.Name(), outType, graph); err != nil {
+					return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "integrations", outType))
+				}
+				return nil
+			}
 			sbList, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(namespace).List(metav1.ListOptions{})
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			outType := outputType(cmd.Flags())
 			if err := bc.Out.Render("list"+cmd.Name(), outType, sbList); err != nil {
 				return errors.WithStack(err)
 			}
 			return nil
 		},
 	}
+	cmd.PersistentFlags().Bool("highlight-broken", false, "--highlight-broken will colour the integrations whose binding or pod preset is missing or not ready when drawing them with -o=dot or -o=mermaid")
+	bc.Out.AddRenderer("list"+cmd.Name(), "dot", renderIntegrationGraphDot)
+	bc.Out.AddRenderer("list"+cmd.Name(), "mermaid", renderIntegrationGraphMermaid)
+	bc.Out.AddRenderer("list"+cmd.Name(), "graph-json", renderIntegrationGraphJSON)
 	bc.Out.AddRenderer("list"+cmd.Name(), "table", func(out io.Writer, dataList interface{}) error {
 		bindingList := dataList.(*v1beta1.ServiceBindingList)
 		var data [][]string
@@ -0,0 +1,313 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
+)
+
+// CheckIntegrationsCmd reports the pod presets, bindings and workload labels of integrations that no longer match up and optionally repairs them
+func (bc *IntegrationCmd) CheckIntegrationsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "integrations",
+		Short: "check the integrations between services for missing or leftover pieces",
+		Long: `check integrations cross references the pod presets, the bindings and their secrets and the <provider>=enabled labels on the pod templates
+of Deployments, DeploymentConfigs and StatefulSets that make up the integrations in your namespace and reports every inconsistency, such as those left behind by an integration that failed to delete.
+
+--fix will repair the integrations by recreating missing pod presets and labels and by removing the leftover pieces.
+Problems that cannot be repaired automatically are reported with the steps to fix them.`,
+		Example: `  mobile check integrations --namespace=myproject
+  mobile check integrations --fix
+  kubectl plugin mobile check integrations
+  oc plugin mobile check integrations`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			fix, err := cmd.PersistentFlags().GetBool("fix")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			problems, err := bc.checkIntegrations(namespace)
+			if err != nil {
+				return err
+			}
+			dryRun, err := newDryRun(cmd.Flags(), namespace)
+			if err != nil {
+				return err
+			}
+			if fix && dryRun != nil {
+				for _, p := range problems {
+					if p.fix == nil {
+						continue
+					}
+					if err := p.fix(dryRun); err != nil {
+						return err
+					}
+				}
+				return dryRun.render(bc.Out, cmd.Flags())
+			}
+			if fix {
+				for _, p := range problems {
+					if p.fix == nil {
+						continue
+					}
+					if err := p.fix(nil); err != nil {
+						p.FixError = err.Error()
+						continue
+					}
+					p.Fixed = true
+				}
+			}
+			outType := outputType(cmd.Flags())
+			if err := bc.Out.Render("check"+cmd.Name(), outType, problems); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "integration problems", outType))
+			}
+			var failed, remaining int
+			for _, p := range problems {
+				if p.FixError != "" {
+					failed++
+				}
+				if !p.Fixed {
+					remaining++
+				}
+			}
+			if failed > 0 {
+				return errors.New(fmt.Sprintf("failed to fix %d of the integration problems", failed))
+			}
+			if remaining > 0 && !fix {
+				return errors.New(fmt.Sprintf("found %d integration problems. Run the command again with --fix to repair them", remaining))
+			}
+			if remaining > 0 {
+				return errors.New(fmt.Sprintf("%d integration problems have to be fixed by hand", remaining))
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().Bool("fix", false, "--fix will repair the integrations by recreating the missing pieces and removing the leftover ones")
+	bc.Out.AddRenderer("check"+cmd.Name(), "table", func(out io.Writer, data interface{}) error {
+		problems := data.([]*IntegrationProblem)
+		if len(problems) == 0 {
+			fmt.Fprintln(out, "no problems found with the integrations")
+			return nil
+		}
+		var rows [][]string
+		for _, p := range problems {
+			fix := p.Fix
+			if p.Fixed {
+				fix = "fixed: " + fix
+			}
+			if p.FixError != "" {
+				fix = "failed to " + fix + ": " + p.FixError
+			}
+			rows = append(rows, []string{p.Kind, p.Name, p.Problem, fix})
+		}
+		table := tablewriter.NewWriter(out)
+		table.AppendBulk(rows)
+		table.SetHeader([]string{"Kind", "Name", "Problem", "Fix"})
+		table.Render()
+		return nil
+	})
+	return cmd
+}
+
+// checkIntegrations cross references the pod presets, bindings, binding secrets and workload labels making up the integrations
+func (bc *IntegrationCmd) checkIntegrations(ns string) ([]*IntegrationProblem, error) {
+	sbList, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list integrations")
+	}
+	ppList, err := bc.k8Client.SettingsV1alpha1().PodPresets(ns).List(metav1.ListOptions{LabelSelector: "group=mobile"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pod presets")
+	}
+	workloads, err := bc.workloads.list(ns, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	presets := ppList.Items
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	bindings := sbList.Items
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].Name < bindings[j].Name })
+
+	var problems []*IntegrationProblem
+	providers := map[string]bool{}
+	for name := range capabilities {
+		providers[name] = true
+	}
+	presetsBySecret := map[string]kalpha.PodPreset{}
+	for _, p := range presets {
+		providers[p.Labels["service"]] = true
+		for _, v := range p.Spec.Volumes {
+			if v.Secret != nil {
+				presetsBySecret[v.Secret.SecretName] = p
+			}
+		}
+	}
+
+	boundSecrets := map[string]bool{}
+	// the pod presets --fix recreates, so the labels selecting them are not reported as left over
+	var recreated []kalpha.PodPreset
+	for _, b := range bindings {
+		b := b
+		boundSecrets[b.Spec.SecretName] = true
+		consumer, provider := b.Annotations["consumer"], b.Annotations["provider"]
+		if consumer == "" || provider == "" {
+			// not created by this tool
+			continue
+		}
+		providers[provider] = true
+		if _, ok := presetsBySecret[b.Spec.SecretName]; !ok {
+			preset := podPreset(b.Name, b.Spec.SecretName, provider, consumer)
+			recreated = append(recreated, *preset)
+			problems = append(problems, &IntegrationProblem{
+				Kind:    "ServiceBinding",
+				Name:    b.Name,
+				Problem: fmt.Sprintf("the pod preset injecting the binding into %s is missing", consumer),
+				Fix:     "recreate the pod preset " + b.Name,
+				fix: func(dryRun *dryRun) error {
+					if dryRun != nil {
+						_, err := dryRun.create(bc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", preset)
+						return err
+					}
+					_, err := bc.k8Client.SettingsV1alpha1().PodPresets(ns).Create(preset)
+					return err
+				},
+			})
+		}
+		if reason := bindingProblem(b); reason != "" {
+			problems = append(problems, &IntegrationProblem{
+				Kind:    "ServiceBinding",
+				Name:    b.Name,
+				Problem: reason,
+				Fix:     "delete the integration with mobile delete integration and create it again",
+			})
+			continue
+		}
+		if _, err := bc.k8Client.CoreV1().Secrets(ns).Get(b.Spec.SecretName, metav1.GetOptions{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, errors.Wrap(err, "failed to get binding secret "+b.Spec.SecretName)
+			}
+			problems = append(problems, &IntegrationProblem{
+				Kind:    "ServiceBinding",
+				Name:    b.Name,
+				Problem: fmt.Sprintf("the binding secret %s is missing", b.Spec.SecretName),
+				Fix:     "delete the integration with mobile delete integration and create it again",
+			})
+		}
+	}
+
+	orphaned := map[string]bool{}
+	for _, p := range presets {
+		name := p.Name
+		for _, v := range p.Spec.Volumes {
+			if v.Secret == nil || boundSecrets[v.Secret.SecretName] {
+				continue
+			}
+			orphaned[name] = true
+			problems = append(problems, &IntegrationProblem{
+				Kind:    "PodPreset",
+				Name:    name,
+				Problem: fmt.Sprintf("the binding creating the secret %s is missing", v.Secret.SecretName),
+				Fix:     "delete the pod preset " + name,
+				fix: func(dryRun *dryRun) error {
+					if dryRun != nil {
+						return dryRun.delete(bc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", name)
+					}
+					return bc.k8Client.SettingsV1alpha1().PodPresets(ns).Delete(name, metav1.NewDeleteOptions(0))
+				},
+			})
+			break
+		}
+	}
+
+	selecting := append(append([]kalpha.PodPreset{}, presets...), recreated...)
+	for _, w := range workloads {
+		w := w
+		templateLabels := labels.Set(w.TemplateLabels)
+		// labels for integrations whose pod preset is gone
+		for key, value := range w.TemplateLabels {
+			if value != "enabled" || !providers[key] {
+				continue
+			}
+			injected := false
+			for _, p := range selecting {
+				if !orphaned[p.Name] && p.Labels["service"] == key && labels.SelectorFromSet(p.Spec.Selector.MatchLabels).Matches(templateLabels) {
+					injected = true
+				}
+			}
+			if injected {
+				continue
+			}
+			key := key
+			problems = append(problems, &IntegrationProblem{
+				Kind:    w.Kind,
+				Name:    w.Name,
+				Problem: fmt.Sprintf("the pod template is labelled %s=enabled but no integration with %s is injected into it", key, key),
+				Fix:     fmt.Sprintf("remove the label %s from the pod template", key),
+				fix: func(dryRun *dryRun) error {
+					if dryRun != nil {
+						dryRun.patch(w.Kind, w.Name, templateLabelPatch(key, ""))
+						return nil
+					}
+					return bc.workloads.setTemplateLabel(ns, w, key, "")
+				},
+			})
+		}
+		// integrations that are not injected as the workload was never labelled
+		for _, p := range presets {
+			provider := p.Labels["service"]
+			if orphaned[p.Name] || p.Spec.Selector.MatchLabels["run"] != w.Name || templateLabels[provider] == "enabled" {
+				continue
+			}
+			if _, ok := p.Spec.Selector.MatchLabels[provider]; !ok {
+				continue
+			}
+			problems = append(problems, &IntegrationProblem{
+				Kind:    w.Kind,
+				Name:    w.Name,
+				Problem: fmt.Sprintf("the pod template is not labelled %s=enabled so the pod preset %s is not injected into it", provider, p.Name),
+				Fix:     fmt.Sprintf("add the label %s=enabled to the pod template", provider),
+				fix: func(dryRun *dryRun) error {
+					if dryRun != nil {
+						dryRun.patch(w.Kind, w.Name, templateLabelPatch(provider, "enabled"))
+						return nil
+					}
+					return bc.workloads.setTemplateLabel(ns, w, provider, "enabled")
+				},
+			})
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Kind != problems[j].Kind {
+			return problems[i].Kind < problems[j].Kind
+		}
+		return problems[i].Name < problems[j].Name
+	})
+	return problems, nil
+}
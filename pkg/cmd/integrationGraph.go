@@ -0,0 +1,292 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
+)
+
+// graphOutputTypes are the output types of get integrations that draw the integration graph rather than list the bindings
+var graphOutputTypes = map[string]bool{"dot": true, "mermaid": true, "graph-json": true}
+
+// buildIntegrationGraph links the service instances in the namespace through their bindings and pod presets, and the mobile clients to the services they use
+func (bc *IntegrationCmd) buildIntegrationGraph(ns string) (*IntegrationGraph, error) {
+	siList, err := bc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances")
+	}
+	sbList, err := bc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list integrations")
+	}
+	ppList, err := bc.k8Client.SettingsV1alpha1().PodPresets(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pod presets")
+	}
+	mcList, err := bc.mobileClient.MobileV1alpha1().MobileClients(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list mobile clients")
+	}
+
+	graph := &IntegrationGraph{Namespace: ns, Nodes: []IntegrationGraphNode{}, Edges: []IntegrationGraphEdge{}}
+	instances := siList.Items
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+
+	// the service instances provisioned for mobile clients are drawn as the clients themselves
+	clientInstances := map[string]bool{}
+	for _, mc := range mcList.Items {
+		for _, si := range instances {
+			for _, pf := range si.Spec.ParametersFrom {
+				if pf.SecretKeyRef != nil && pf.SecretKeyRef.Name == clientParamsSecretName(mc.Name) {
+					clientInstances[si.Name] = true
+				}
+			}
+		}
+	}
+
+	nodes := map[string]bool{}
+	for _, si := range instances {
+		if clientInstances[si.Name] {
+			continue
+		}
+		nodes[si.Name] = true
+		graph.Nodes = append(graph.Nodes, IntegrationGraphNode{
+			ID:     si.Name,
+			Label:  si.Spec.ClusterServiceClassExternalName,
+			Kind:   GraphNodeServiceInstance,
+			Status: serviceInstanceStatus(si),
+		})
+	}
+	// serviceNode finds the instance of the named service or adds a node for a service with no instance in the namespace
+	serviceNode := func(serviceName string) string {
+		for _, si := range instances {
+			if !clientInstances[si.Name] && si.Spec.ClusterServiceClassExternalName == serviceName {
+				return si.Name
+			}
+		}
+		id := GraphNodeService + ":" + serviceName
+		if !nodes[id] {
+			nodes[id] = true
+			graph.Nodes = append(graph.Nodes, IntegrationGraphNode{ID: id, Label: serviceName, Kind: GraphNodeService, Status: "Missing"})
+		}
+		return id
+	}
+
+	presetsBySecret := map[string]kalpha.PodPreset{}
+	for _, p := range ppList.Items {
+		for _, v := range p.Spec.Volumes {
+			if v.Secret != nil {
+				presetsBySecret[v.Secret.SecretName] = p
+			}
+		}
+	}
+
+	boundSecrets := map[string]bool{}
+	for _, b := range sbList.Items {
+		boundSecrets[b.Spec.SecretName] = true
+		if b.Annotations["consumer"] == "" || b.Annotations["provider"] == "" {
+			// not created by this tool
+			continue
+		}
+		provider := b.Spec.ServiceInstanceRef.Name
+		if !nodes[provider] {
+			provider = serviceNode(b.Annotations["provider"])
+		}
+		// integrations are named <consumer instance>-<provider instance>, see objectName
+		consumer := strings.TrimSuffix(b.Name, "-"+b.Spec.ServiceInstanceRef.Name)
+		if consumer == b.Name || !nodes[consumer] {
+			consumer = serviceNode(b.Annotations["consumer"])
+		}
+		edge := IntegrationGraphEdge{From: consumer, To: provider, Kind: GraphEdgeIntegration, Binding: b.Name}
+		if p, ok := presetsBySecret[b.Spec.SecretName]; ok {
+			edge.PodPreset = p.Name
+		} else {
+			edge.Broken = true
+			edge.Reason = "pod preset missing"
+		}
+		if reason := bindingProblem(b); reason != "" {
+			edge.Broken = true
+			edge.Reason = reason
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	// pod presets left behind by integrations whose binding has gone
+	for _, p := range ppList.Items {
+		if p.Labels["group"] != "mobile" {
+			continue
+		}
+		orphaned := false
+		for _, v := range p.Spec.Volumes {
+			if v.Secret != nil && !boundSecrets[v.Secret.SecretName] {
+				orphaned = true
+			}
+		}
+		if !orphaned {
+			continue
+		}
+		graph.Edges = append(graph.Edges, IntegrationGraphEdge{
+			From:      serviceNode(p.Spec.Selector.MatchLabels["run"]),
+			To:        serviceNode(p.Labels["service"]),
+			Kind:      GraphEdgeIntegration,
+			PodPreset: p.Name,
+			Broken:    true,
+			Reason:    "binding missing",
+		})
+	}
+
+	clients := mcList.Items
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+	for _, mc := range clients {
+		graph.Nodes = append(graph.Nodes, IntegrationGraphNode{
+			ID:    mc.Name,
+			Label: fmt.Sprintf("%s (%s)", mc.Spec.Name, mc.Spec.ClientType),
+			Kind:  GraphNodeMobileClient,
+		})
+		excluded := map[string]bool{}
+		for _, e := range mc.Spec.ExcludedServices {
+			excluded[e] = true
+		}
+		for _, si := range instances {
+			if clientInstances[si.Name] || excluded[si.Name] || excluded[si.Spec.ClusterServiceClassExternalName] {
+				continue
+			}
+			graph.Edges = append(graph.Edges, IntegrationGraphEdge{From: mc.Name, To: si.Name, Kind: GraphEdgeClient})
+		}
+	}
+	return graph, nil
+}
+
+// bindingProblem returns why the binding is not usable or an empty string when it is Ready
+func bindingProblem(b v1beta1.ServiceBinding) string {
+	if b.DeletionTimestamp != nil {
+		return "binding is being deleted"
+	}
+	for _, c := range b.Status.Conditions {
+		if c.Type == v1beta1.ServiceBindingConditionFailed && c.Status == v1beta1.ConditionTrue {
+			return "binding failed: " + c.Message
+		}
+	}
+	for _, c := range b.Status.Conditions {
+		if c.Type == v1beta1.ServiceBindingConditionReady {
+			if c.Status == v1beta1.ConditionTrue {
+				return ""
+			}
+			if c.Reason != "" {
+				return "binding not ready: " + c.Reason
+			}
+		}
+	}
+	return "binding not ready"
+}
+
+func renderIntegrationGraphJSON(out io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "	")
+	return encoder.Encode(data)
+}
+
+func renderIntegrationGraphDot(out io.Writer, data interface{}) error {
+	graph := data.(*IntegrationGraph)
+	fmt.Fprintf(out, "digraph %q {\n", graph.Namespace)
+	fmt.Fprintf(out, "\trankdir=LR;\n")
+	for _, n := range graph.Nodes {
+		shape := "box"
+		style := ""
+		switch n.Kind {
+		case GraphNodeMobileClient:
+			shape = "ellipse"
+		case GraphNodeService:
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(out, "\t%q [label=%q, shape=%s%s];\n", n.ID, n.Label+"\n"+n.ID, shape, style)
+	}
+	for _, e := range graph.Edges {
+		var attrs []string
+		if e.Binding != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", e.Binding))
+		}
+		if e.Kind == GraphEdgeClient {
+			attrs = append(attrs, "style=dotted")
+		}
+		if graph.HighlightBroken && e.Broken {
+			attrs = append(attrs, "color=red", "fontcolor=red", fmt.Sprintf("tooltip=%q", e.Reason))
+			if e.Binding == "" {
+				attrs = append(attrs, fmt.Sprintf("label=%q", e.Reason))
+			}
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(out, "\t%q -> %q [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+			continue
+		}
+		fmt.Fprintf(out, "\t%q -> %q;\n", e.From, e.To)
+	}
+	fmt.Fprintf(out, "}\n")
+	return nil
+}
+
+func renderIntegrationGraphMermaid(out io.Writer, data interface{}) error {
+	graph := data.(*IntegrationGraph)
+	// mermaid ids cannot contain most punctuation so the nodes are numbered
+	ids := map[string]string{}
+	fmt.Fprintf(out, "graph LR\n")
+	for i, n := range graph.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		label := mermaidEscape(n.Label) + "<br/>" + mermaidEscape(n.ID)
+		switch n.Kind {
+		case GraphNodeMobileClient:
+			fmt.Fprintf(out, "\t%s([\"%s\"])\n", ids[n.ID], label)
+		case GraphNodeService:
+			fmt.Fprintf(out, "\t%s{{\"%s\"}}\n", ids[n.ID], label)
+		default:
+			fmt.Fprintf(out, "\t%s[\"%s\"]\n", ids[n.ID], label)
+		}
+	}
+	var broken []string
+	for i, e := range graph.Edges {
+		arrow := "-->"
+		if e.Kind == GraphEdgeClient {
+			arrow = "-.->"
+		}
+		label := e.Binding
+		if graph.HighlightBroken && e.Broken {
+			broken = append(broken, fmt.Sprintf("%d", i))
+			if label == "" {
+				label = e.Reason
+			}
+		}
+		if label != "" {
+			arrow += "|\"" + mermaidEscape(label) + "\"|"
+		}
+		fmt.Fprintf(out, "\t%s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	if len(broken) > 0 {
+		fmt.Fprintf(out, "\tlinkStyle %s stroke:red,stroke-width:2px,color:red\n", strings.Join(broken, ","))
+	}
+	return nil
+}
+
+func mermaidEscape(s string) string {
+	return strings.Replace(s, "\"", "#quot;", -1)
+}
@@ -4,23 +4,122 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
 	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
 	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	kFake "k8s.io/client-go/kubernetes/fake"
 	corev1 "k8s.io/client-go/pkg/api/v1"
 	kbeta "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	kext "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
 	ktesting "k8s.io/client-go/testing"
 )
 
+// fakeDeploymentConfigs is an in memory cmd.DeploymentConfigInterface which records the patches made
+type fakeDeploymentConfigs struct {
+	items   []cmd.DeploymentConfig
+	patches map[string]string
+}
+
+func (f *fakeDeploymentConfigs) Get(namespace, name string) (*cmd.DeploymentConfig, error) {
+	for _, dc := range f.items {
+		if dc.Name == name {
+			return &dc, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: "apps.openshift.io", Resource: "deploymentconfigs"}, name)
+}
+
+func (f *fakeDeploymentConfigs) List(namespace string, options metav1.ListOptions) (*cmd.DeploymentConfigList, error) {
+	selector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &cmd.DeploymentConfigList{}
+	for _, dc := range f.items {
+		if selector.Matches(labels.Set(dc.Labels)) {
+			list.Items = append(list.Items, dc)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeDeploymentConfigs) Patch(namespace, name string, pt types.PatchType, data []byte) (*cmd.DeploymentConfig, error) {
+	if f.patches == nil {
+		f.patches = map[string]string{}
+	}
+	f.patches[name] = string(data)
+	return f.Get(namespace, name)
+}
+
+// rolledOutDeployment fakes a deployment whose latest replica set is available and runs the pods
+func rolledOutDeployment(pods ...corev1.Pod) *kFake.Clientset {
+	fake := &kFake.Clientset{}
+	revision := map[string]string{"deployment.kubernetes.io/revision": "2"}
+	fake.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &kbeta.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: action.(ktesting.GetAction).GetName(), Annotations: revision},
+			Spec: kbeta.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{},
+					},
+				},
+			},
+			Status: kbeta.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		}, nil
+	})
+	fake.AddReactor("list", "replicasets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &kext.ReplicaSetList{Items: []kext.ReplicaSet{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "old", Annotations: map[string]string{"deployment.kubernetes.io/revision": "1"}},
+				Spec:       kext.ReplicaSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pod-template-hash": "1"}}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "new", Annotations: revision},
+				Spec:       kext.ReplicaSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pod-template-hash": "2"}}},
+				Status:     kext.ReplicaSetStatus{AvailableReplicas: 1},
+			},
+		}}, nil
+	})
+	// the fake filters the pods by the selector of the new replica set
+	for i := range pods {
+		pods[i].Labels = map[string]string{"pod-template-hash": "2"}
+	}
+	fake.AddReactor("list", "pods", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &corev1.PodList{Items: append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "old", Labels: map[string]string{"pod-template-hash": "1"}}})}, nil
+	})
+	return fake
+}
+
+// integratedPod is a pod whose container mounts the mountPath, if one is given
+func integratedPod(name, mountPath string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	if mountPath != "" {
+		pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "secret", MountPath: mountPath}}
+	}
+	return pod
+}
+
 func TestIntegrationCmd_CreateIntegrationCmd(t *testing.T) {
 	var defaultServiceBinding = &v1beta1.ServiceBinding{
 		Status: v1beta1.ServiceBindingStatus{
@@ -35,6 +134,75 @@ func TestIntegrationCmd_CreateIntegrationCmd(t *testing.T) {
 
 	fmt.Print(defaultServiceBinding)
 
+	redeployServiceCatalog := func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object) {
+		fake := &scFake.Clientset{}
+		fakeWatch := watch.NewFake()
+		fake.AddWatchReactor("servicebindings", ktesting.DefaultWatchReactor(fakeWatch, nil))
+		fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "keycloak",
+				},
+				Spec: v1beta1.ServiceInstanceSpec{
+					PlanReference: v1beta1.PlanReference{
+						ClusterServiceClassExternalName: "keycloak",
+					},
+					ClusterServiceClassRef: &v1beta1.ClusterObjectReference{
+						Name: "id",
+					},
+				},
+			}, nil
+		})
+		fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "fh-sync-server",
+				},
+				Spec: v1beta1.ServiceInstanceSpec{
+					PlanReference: v1beta1.PlanReference{
+						ClusterServiceClassExternalName: "fh-sync-server",
+					},
+					ClusterServiceClassRef: &v1beta1.ClusterObjectReference{
+						Name: "id",
+					},
+				},
+			}, nil
+		})
+		fake.AddReactor("get", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ClusterServiceClass{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"test"}`)}}}, nil
+		})
+		fake.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			externalData := cmd.ExternalServiceMetaData{
+				ServiceName: "test",
+			}
+			data, _ := json.Marshal(externalData)
+			return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+					Spec: v1beta1.ClusterServiceClassSpec{
+						ExternalMetadata: &runtime.RawExtension{Raw: data},
+					},
+				},
+			}}, nil
+		})
+		fake.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			params := &cmd.ServiceParams{Required: []string{"CLIENT_NAME"}, Properties: map[string]map[string]interface{}{"CLIENT_NAME": {"value": "", "default": "test-client-name"}}}
+			b, _ := json.Marshal(params)
+			return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{{
+				Spec: v1beta1.ClusterServicePlanSpec{
+					ServiceBindingCreateParameterSchema: &runtime.RawExtension{Raw: b},
+					ClusterServiceClassRef:              v1beta1.ClusterObjectReference{Name: "test"},
+					ExternalName:                        "default"},
+			},
+			},
+			}, nil
+		})
+		return fake, fakeWatch, []runtime.Object{
+			defaultServiceBinding,
+		}
+	}
 	cases := []struct {
 		Name             string
 		SvcCatalogClient func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object)
@@ -252,90 +420,49 @@ func TestIntegrationCmd_CreateIntegrationCmd(t *testing.T) {
 			Flags: []string{"--namespace=test", "--auto-redeploy=true", "--no-wait=true"},
 		},
 		{
-			Name: "should pass when serviceinstances exist and auto-redeploy is set",
-			SvcCatalogClient: func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object) {
-				fake := &scFake.Clientset{}
-				fakeWatch := watch.NewFake()
-				fake.AddWatchReactor("servicebindings", ktesting.DefaultWatchReactor(fakeWatch, nil))
-				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, &v1beta1.ServiceInstance{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "keycloak",
-						},
-						Spec: v1beta1.ServiceInstanceSpec{
-							PlanReference: v1beta1.PlanReference{
-								ClusterServiceClassExternalName: "keycloak",
-							},
-							ClusterServiceClassRef: &v1beta1.ClusterObjectReference{
-								Name: "id",
-							},
-						},
-					}, nil
-				})
-				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, &v1beta1.ServiceInstance{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "fh-sync-server",
-						},
-						Spec: v1beta1.ServiceInstanceSpec{
-							PlanReference: v1beta1.PlanReference{
-								ClusterServiceClassExternalName: "fh-sync-server",
-							},
-							ClusterServiceClassRef: &v1beta1.ClusterObjectReference{
-								Name: "id",
-							},
-						},
-					}, nil
-				})
-				fake.AddReactor("get", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, &v1beta1.ClusterServiceClass{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"test"}`)}}}, nil
-				})
-				fake.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					externalData := cmd.ExternalServiceMetaData{
-						ServiceName: "test",
-					}
-					data, _ := json.Marshal(externalData)
-					return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
-						{
-							ObjectMeta: metav1.ObjectMeta{
-								Name: "test",
-							},
-							Spec: v1beta1.ClusterServiceClassSpec{
-								ExternalMetadata: &runtime.RawExtension{Raw: data},
-							},
-						},
-					}}, nil
-				})
-				fake.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					params := &cmd.ServiceParams{Required: []string{"CLIENT_NAME"}, Properties: map[string]map[string]interface{}{"CLIENT_NAME": {"value": "", "default": "test-client-name"}}}
-					b, _ := json.Marshal(params)
-					return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{{
-						Spec: v1beta1.ClusterServicePlanSpec{
-							ServiceBindingCreateParameterSchema: &runtime.RawExtension{Raw: b},
-							ClusterServiceClassRef:              v1beta1.ClusterObjectReference{Name: "test"},
-							ExternalName:                        "default"},
-					},
-					},
-					}, nil
-				})
-				return fake, fakeWatch, []runtime.Object{
-					defaultServiceBinding,
+			Name:             "should pass when serviceinstances exist and auto-redeploy is set",
+			SvcCatalogClient: redeployServiceCatalog,
+			K8Client: func() kubernetes.Interface {
+				return rolledOutDeployment(integratedPod("test-1", "/etc/secrets/test"))
+			},
+			Args:  []string{"keycloak", "fh-sync-server"},
+			Flags: []string{"--namespace=test", "-pCLIENT_NAME=test", "--auto-redeploy=true", "--no-wait=true"},
+		},
+		{
+			Name:             "should fail when none of the new pods mount the secret of the provider",
+			SvcCatalogClient: redeployServiceCatalog,
+			K8Client: func() kubernetes.Interface {
+				return rolledOutDeployment(integratedPod("test-1", ""))
+			},
+			ExpectError: true,
+			ValidateErr: func(t *testing.T, err error) {
+				expectedErr := "failed to roll out Deployment test: none of the new pods of Deployment test mount /etc/secrets/test. Check the pod preset of the integration selects the pods of Deployment test"
+				if err.Error() != expectedErr {
+					t.Fatalf("expected error to be '%s' but got '%v'", expectedErr, err)
 				}
 			},
+			Args:  []string{"keycloak", "fh-sync-server"},
+			Flags: []string{"--namespace=test", "-pCLIENT_NAME=test", "--auto-redeploy=true", "--no-wait=true"},
+		},
+		{
+			Name:             "should fail when the new pods crash loop after the integration",
+			SvcCatalogClient: redeployServiceCatalog,
 			K8Client: func() kubernetes.Interface {
-				fake := &kFake.Clientset{}
-				fake.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, &kbeta.Deployment{
-						Spec: kbeta.DeploymentSpec{
-							Template: corev1.PodTemplateSpec{
-								ObjectMeta: metav1.ObjectMeta{
-									Labels: map[string]string{},
-								},
-							},
-						},
-					}, nil
-				})
-				return fake
+				pod := integratedPod("test-1", "/etc/secrets/test")
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					Name:                 "keycloak",
+					RestartCount:         3,
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "invalid sync config"}},
+				}}
+				return rolledOutDeployment(pod)
+			},
+			ExpectError: true,
+			ValidateErr: func(t *testing.T, err error) {
+				expectedErr := "failed to roll out Deployment test: pod test-1 of Deployment test is crash looping after the integration: container keycloak restarted 3 times, last exit code 1: invalid sync config"
+				if err.Error() != expectedErr {
+					t.Fatalf("expected error to be '%s' but got '%v'", expectedErr, err)
+				}
 			},
 			Args:  []string{"keycloak", "fh-sync-server"},
 			Flags: []string{"--namespace=test", "-pCLIENT_NAME=test", "--auto-redeploy=true", "--no-wait=true"},
@@ -354,7 +481,7 @@ func TestIntegrationCmd_CreateIntegrationCmd(t *testing.T) {
 					}
 				}()
 			}
-			integrationCmd := cmd.NewIntegrationCmd(scClient, tc.K8Client(), &out)
+			integrationCmd := cmd.NewIntegrationCmd(scClient, tc.K8Client(), &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
 			createCmd := integrationCmd.CreateIntegrationCmd()
 			createCmd.SetOutput(&out)
 			root.AddCommand(createCmd)
@@ -379,13 +506,222 @@ func TestIntegrationCmd_CreateIntegrationCmd(t *testing.T) {
 	}
 }
 
+// integrationCatalog is a service catalog with the keycloak instance to integrate, its binding becomes ready once created
+func integrationCatalog() (*scFake.Clientset, *watch.FakeWatcher) {
+	fake := &scFake.Clientset{}
+	fakeWatch := watch.NewFake()
+	fake.AddWatchReactor("servicebindings", ktesting.DefaultWatchReactor(fakeWatch, nil))
+	fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: action.(ktesting.GetAction).GetName()},
+			Spec: v1beta1.ServiceInstanceSpec{
+				PlanReference:          v1beta1.PlanReference{ClusterServiceClassExternalName: "keycloak"},
+				ClusterServiceClassRef: &v1beta1.ClusterObjectReference{Name: "id"},
+			},
+		}, nil
+	})
+	fake.AddReactor("get", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ClusterServiceClass{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"test"}`)}}}, nil
+	})
+	fake.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"test"}`)}},
+		}}}, nil
+	})
+	fake.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{{
+			Spec: v1beta1.ClusterServicePlanSpec{ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "test"}, ExternalName: "default"},
+		}}}, nil
+	})
+	fake.AddReactor("create", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		binding := action.(ktesting.CreateAction).GetObject().(*v1beta1.ServiceBinding)
+		go fakeWatch.Modify(&v1beta1.ServiceBinding{
+			ObjectMeta: binding.ObjectMeta,
+			Status: v1beta1.ServiceBindingStatus{Conditions: []v1beta1.ServiceBindingCondition{
+				{Status: v1beta1.ConditionTrue, Type: v1beta1.ServiceBindingConditionReady},
+			}},
+		})
+		return true, binding, nil
+	})
+	return fake, fakeWatch
+}
+
+// integrationChanges lists the objects created, updated and deleted through the fake, with the label the updates set on the pod template
+func integrationChanges(actions []ktesting.Action) []string {
+	var changes []string
+	for _, a := range actions {
+		switch a.GetVerb() {
+		case "create":
+			changes = append(changes, "create "+a.GetResource().Resource)
+		case "update":
+			dep, ok := a.(ktesting.UpdateAction).GetObject().(*kbeta.Deployment)
+			if !ok {
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("update %s test=%s", a.GetResource().Resource, dep.Spec.Template.Labels["test"]))
+		case "delete":
+			changes = append(changes, "delete "+a.GetResource().Resource+" "+a.(ktesting.DeleteAction).GetName())
+		}
+	}
+	return changes
+}
+
+func TestIntegrationCmd_CreateIntegrationCmdRollback(t *testing.T) {
+	cases := []struct {
+		Name                 string
+		K8Client             func() *kFake.Clientset
+		BindingErr           error
+		Flags                []string
+		ErrorPattern         string
+		ExpectK8Changes      []string
+		ExpectCatalogChanges []string
+	}{
+		{
+			Name:                 "test the pod preset is deleted when the binding can not be created",
+			K8Client:             func() *kFake.Clientset { return &kFake.Clientset{} },
+			BindingErr:           errors.New("forbidden by admission"),
+			Flags:                []string{"--namespace=test", "-pCLIENT_NAME=test"},
+			ErrorPattern:         "^forbidden by admission$",
+			ExpectK8Changes:      []string{"create podpresets", "delete podpresets keycloak-fh-sync-server"},
+			ExpectCatalogChanges: []string{"create servicebindings"},
+		},
+		{
+			Name:                 "test the pod preset is kept with --no-rollback",
+			K8Client:             func() *kFake.Clientset { return &kFake.Clientset{} },
+			BindingErr:           errors.New("forbidden by admission"),
+			Flags:                []string{"--namespace=test", "-pCLIENT_NAME=test", "--no-rollback"},
+			ErrorPattern:         "^forbidden by admission$",
+			ExpectK8Changes:      []string{"create podpresets"},
+			ExpectCatalogChanges: []string{"create servicebindings"},
+		},
+		{
+			Name: "test the label is removed and the integration deleted when the redeploy fails",
+			K8Client: func() *kFake.Clientset {
+				return rolledOutDeployment(integratedPod("test-1", ""))
+			},
+			Flags:                []string{"--namespace=test", "-pCLIENT_NAME=test", "--auto-redeploy"},
+			ErrorPattern:         "^failed to roll out Deployment test: none of the new pods of Deployment test mount /etc/secrets/test",
+			ExpectK8Changes:      []string{"create podpresets", "update deployments test=enabled", "update deployments test=", "delete podpresets keycloak-fh-sync-server"},
+			ExpectCatalogChanges: []string{"create servicebindings", "delete servicebindings keycloak-fh-sync-server"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			var out bytes.Buffer
+			scClient, _ := integrationCatalog()
+			if tc.BindingErr != nil {
+				scClient.PrependReactor("create", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, tc.BindingErr
+				})
+			}
+			k8Client := tc.K8Client()
+			createCmd := cmd.NewIntegrationCmd(scClient, k8Client, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out).CreateIntegrationCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"keycloak", "fh-sync-server"})
+			if err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if m, _ := regexp.MatchString(tc.ErrorPattern, err.Error()); !m {
+				t.Fatalf("expected the error to match the pattern %s but got %s", tc.ErrorPattern, err.Error())
+			}
+			if changes := integrationChanges(k8Client.Actions()); !reflect.DeepEqual(changes, tc.ExpectK8Changes) {
+				t.Fatalf("expected the changes %v but got %v", tc.ExpectK8Changes, changes)
+			}
+			if changes := integrationChanges(scClient.Actions()); !reflect.DeepEqual(changes, tc.ExpectCatalogChanges) {
+				t.Fatalf("expected the service catalog changes %v but got %v", tc.ExpectCatalogChanges, changes)
+			}
+		})
+	}
+}
+
 func TestIntegrationCmd_ListIntegrationCmd(t *testing.T) {
+	graphSvcCatalogClient := func() versioned.Interface {
+		fake := &scFake.Clientset{}
+		fake.AddReactor("list", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceInstanceList{
+				Items: []v1beta1.ServiceInstance{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "keycloak-x1"},
+						Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: "keycloak"}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server-x2"},
+						Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: "fh-sync-server"}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "myapp-android-x3"},
+						Spec: v1beta1.ServiceInstanceSpec{
+							PlanReference:  v1beta1.PlanReference{ClusterServiceClassExternalName: "android-app"},
+							ParametersFrom: []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "myapp-android-apb-params", Key: "parameters"}}},
+						},
+					},
+				},
+			}, nil
+		})
+		fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceBindingList{
+				Items: []v1beta1.ServiceBinding{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "fh-sync-server-x2-keycloak-x1",
+							Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"},
+						},
+						Spec: v1beta1.ServiceBindingSpec{
+							ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "keycloak-x1"},
+							SecretName:         "fh-sync-server-x2-keycloak-x1",
+						},
+						Status: v1beta1.ServiceBindingStatus{
+							Conditions: []v1beta1.ServiceBindingCondition{{Type: v1beta1.ServiceBindingConditionReady, Status: v1beta1.ConditionFalse, Reason: "ErrorInstanceNotReady"}},
+						},
+					},
+					{
+						// not created by this tool so it is left out of the graph
+						ObjectMeta: metav1.ObjectMeta{Name: "keycloak-binding"},
+						Spec: v1beta1.ServiceBindingSpec{
+							ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "keycloak-x1"},
+							SecretName:         "keycloak-binding",
+						},
+					},
+				},
+			}, nil
+		})
+		return fake
+	}
+	graphK8Client := func() kubernetes.Interface {
+		fake := &kFake.Clientset{}
+		fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &kalpha.PodPresetList{}, nil
+		})
+		return fake
+	}
+	graphMobileClient := func() mc.Interface {
+		fake := &mcFake.Clientset{}
+		fake.AddReactor("list", "mobileclients", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1alpha1.MobileClientList{
+				Items: []v1alpha1.MobileClient{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "myapp-android"},
+						Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", ExcludedServices: []string{"fh-sync-server"}},
+					},
+				},
+			}, nil
+		})
+		return fake
+	}
 	cases := []struct {
 		Name             string
 		SvcCatalogClient func() versioned.Interface
 		K8Client         func() kubernetes.Interface
+		MobileClient     func() mc.Interface
 		ExpectError      bool
 		ValidateErr      func(t *testing.T, err error)
+		ValidateOut      func(t *testing.T, out string)
 		Flags            []string
 	}{
 		{
@@ -428,13 +764,74 @@ func TestIntegrationCmd_ListIntegrationCmd(t *testing.T) {
 			},
 			Flags: []string{"--namespace=test", "-o=json"},
 		},
+		{
+			Name:             "should build the integration graph with the broken integrations and the services used by mobile clients, leaving out bindings not created by this tool",
+			SvcCatalogClient: graphSvcCatalogClient,
+			K8Client:         graphK8Client,
+			MobileClient:     graphMobileClient,
+			ValidateOut: func(t *testing.T, out string) {
+				graph := &cmd.IntegrationGraph{}
+				if err := json.Unmarshal([]byte(out), graph); err != nil {
+					t.Fatal("failed to unmarshal the graph", err)
+				}
+				if len(graph.Nodes) != 3 {
+					t.Fatalf("expected 2 service instances and 1 mobile client in the graph but got %v", graph.Nodes)
+				}
+				if len(graph.Edges) != 2 {
+					t.Fatalf("expected 1 integration and 1 client edge but got %v", graph.Edges)
+				}
+				integration := graph.Edges[0]
+				if integration.From != "fh-sync-server-x2" || integration.To != "keycloak-x1" {
+					t.Fatalf("expected the integration to go from fh-sync-server-x2 to keycloak-x1 but got %v", integration)
+				}
+				if !integration.Broken || integration.Reason != "binding not ready: ErrorInstanceNotReady" {
+					t.Fatalf("expected the integration to be broken as the binding is not ready but got %v", integration)
+				}
+				client := graph.Edges[1]
+				if client.From != "myapp-android" || client.To != "keycloak-x1" || client.Broken {
+					t.Fatalf("expected the client to use keycloak-x1 only but got %v", client)
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=graph-json"},
+		},
+		{
+			Name:             "should colour broken integrations in dot output when highlighting",
+			SvcCatalogClient: graphSvcCatalogClient,
+			K8Client:         graphK8Client,
+			MobileClient:     graphMobileClient,
+			ValidateOut: func(t *testing.T, out string) {
+				expected := `"fh-sync-server-x2" -> "keycloak-x1" [label="fh-sync-server-x2-keycloak-x1", color=red, fontcolor=red, tooltip="binding not ready: ErrorInstanceNotReady"];`
+				if !strings.Contains(out, expected) {
+					t.Fatalf("expected the output to contain %s but got %s", expected, out)
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=dot", "--highlight-broken"},
+		},
+		{
+			Name:             "should style broken integrations in mermaid output when highlighting",
+			SvcCatalogClient: graphSvcCatalogClient,
+			K8Client:         graphK8Client,
+			MobileClient:     graphMobileClient,
+			ValidateOut: func(t *testing.T, out string) {
+				for _, expected := range []string{"graph LR", `n0 -->|"fh-sync-server-x2-keycloak-x1"| n1`, "n2 -.-> n1", "linkStyle 0 stroke:red"} {
+					if !strings.Contains(out, expected) {
+						t.Fatalf("expected the output to contain %s but got %s", expected, out)
+					}
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=mermaid", "--highlight-broken"},
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			root := cmd.NewRootCmd()
 			var out bytes.Buffer
-			integrationCmd := cmd.NewIntegrationCmd(tc.SvcCatalogClient(), tc.K8Client(), &out)
+			var mobileClient mc.Interface = &mcFake.Clientset{}
+			if tc.MobileClient != nil {
+				mobileClient = tc.MobileClient()
+			}
+			integrationCmd := cmd.NewIntegrationCmd(tc.SvcCatalogClient(), tc.K8Client(), mobileClient, &fakeDeploymentConfigs{}, &out)
 			listCmd := integrationCmd.ListIntegrationsCmd()
 			root.AddCommand(listCmd)
 			if err := listCmd.ParseFlags(tc.Flags); err != nil {
@@ -451,6 +848,9 @@ func TestIntegrationCmd_ListIntegrationCmd(t *testing.T) {
 			if tc.ValidateErr != nil {
 				tc.ValidateErr(t, err)
 			}
+			if tc.ValidateOut != nil {
+				tc.ValidateOut(t, out.String())
+			}
 		})
 	}
 }
@@ -470,15 +870,37 @@ func TestIntegrationCmd_DeleteIntegrationCmd(t *testing.T) {
 		},
 	}
 
+	redeployServiceCatalog := func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object) {
+		fake := &scFake.Clientset{}
+		fakeWatch := watch.NewFake()
+		fake.AddWatchReactor("servicebindings", ktesting.DefaultWatchReactor(fakeWatch, nil))
+		fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: action.(ktesting.GetAction).GetName()},
+				Spec:       v1beta1.ServiceInstanceSpec{ClusterServiceClassRef: &v1beta1.ClusterObjectReference{Name: "id"}},
+			}, nil
+		})
+		fake.AddReactor("get", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ClusterServiceClass{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"fh-sync-server"}`)}}}, nil
+		})
+		return fake, fakeWatch, []runtime.Object{defaultServiceBinding}
+	}
+	notFound := func(resource string) ktesting.ReactionFunc {
+		return func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: resource}, action.(ktesting.GetAction).GetName())
+		}
+	}
 	cases := []struct {
-		Name             string
-		SvcCatalogClient func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object)
-		K8Client         func() kubernetes.Interface
-		ExpectError      bool
-		ExpectUsage      bool
-		ValidateErr      func(t *testing.T, err error)
-		Args             []string
-		Flags            []string
+		Name              string
+		SvcCatalogClient  func() (versioned.Interface, *watch.FakeWatcher, []runtime.Object)
+		K8Client          func() kubernetes.Interface
+		DeploymentConfigs *fakeDeploymentConfigs
+		ExpectError       bool
+		ExpectUsage       bool
+		ValidateErr       func(t *testing.T, err error)
+		Validate          func(t *testing.T, dcs *fakeDeploymentConfigs)
+		Args              []string
+		Flags             []string
 	}{
 		{
 			Name: "test returns usage if missing arguments",
@@ -571,7 +993,7 @@ func TestIntegrationCmd_DeleteIntegrationCmd(t *testing.T) {
 			},
 			ExpectError: true,
 			ValidateErr: func(t *testing.T, err error) {
-				expectedErr := "service keycloak: failed to get deployment"
+				expectedErr := "failed to get deployment for service keycloak: failed to get deployment"
 				if err.Error() != expectedErr {
 					t.Fatalf("expected error to be '%s' but got '%v'", expectedErr, err)
 				}
@@ -693,23 +1115,102 @@ func TestIntegrationCmd_DeleteIntegrationCmd(t *testing.T) {
 					defaultServiceBinding,
 				}
 			},
+			K8Client: func() kubernetes.Interface {
+				return rolledOutDeployment(integratedPod("fh-sync-server-1", ""))
+			},
+			Args:  []string{"keycloak", "fh-sync-server"},
+			Flags: []string{"--namespace=test", "--auto-redeploy=true", "--no-wait=true"},
+		},
+		{
+			Name:             "should redeploy the deploymentconfig named after the consuming service",
+			SvcCatalogClient: redeployServiceCatalog,
 			K8Client: func() kubernetes.Interface {
 				fake := &kFake.Clientset{}
-				fake.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, &kbeta.Deployment{
-						Spec: kbeta.DeploymentSpec{
-							Template: corev1.PodTemplateSpec{
-								ObjectMeta: metav1.ObjectMeta{
-									Labels: map[string]string{},
-								},
-							},
-						},
+				fake.AddReactor("get", "deployments", notFound("deployments"))
+				fake.AddReactor("get", "replicationcontrollers", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					if name := action.(ktesting.GetAction).GetName(); name != "fh-sync-server-3" {
+						t.Fatalf("expected the replication controller of the latest version to be checked but got %s", name)
+					}
+					return true, &corev1.ReplicationController{
+						Spec:   corev1.ReplicationControllerSpec{Selector: map[string]string{"deployment": "fh-sync-server-3"}},
+						Status: corev1.ReplicationControllerStatus{AvailableReplicas: 1},
 					}, nil
 				})
 				return fake
 			},
+			DeploymentConfigs: &fakeDeploymentConfigs{items: []cmd.DeploymentConfig{{
+				ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server", Generation: 2},
+				Spec:       cmd.DeploymentConfigSpec{Replicas: 1},
+				Status:     cmd.DeploymentConfigStatus{LatestVersion: 3, ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			}}},
+			Validate: func(t *testing.T, dcs *fakeDeploymentConfigs) {
+				expected := `{"spec":{"template":{"metadata":{"labels":{"fh-sync-server":null}}}}}`
+				if dcs.patches["fh-sync-server"] != expected {
+					t.Fatalf("expected the deploymentconfig to be patched with %s but got %v", expected, dcs.patches)
+				}
+			},
 			Args:  []string{"keycloak", "fh-sync-server"},
-			Flags: []string{"--namespace=test", "--auto-redeploy=true", "--no-wait=true"},
+			Flags: []string{"--namespace=test", "--auto-redeploy=true"},
+		},
+		{
+			Name:             "should redeploy the statefulsets matching the redeploy selector",
+			SvcCatalogClient: redeployServiceCatalog,
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				replicas := int32(1)
+				statefulSet := &kbeta.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-db", Labels: map[string]string{"app": "sync"}},
+					Spec: kbeta.StatefulSetSpec{
+						Replicas: &replicas,
+						Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"fh-sync-server": "enabled"}}},
+					},
+				}
+				fake.AddReactor("list", "statefulsets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					if selector := action.(ktesting.ListAction).GetListRestrictions().Labels.String(); selector != "app=sync" {
+						t.Fatalf("expected the statefulsets to be listed with the selector app=sync but got %s", selector)
+					}
+					return true, &kbeta.StatefulSetList{Items: []kbeta.StatefulSet{*statefulSet}}, nil
+				})
+				fake.AddReactor("get", "statefulsets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, statefulSet, nil
+				})
+				fake.AddReactor("update", "statefulsets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					updated := action.(ktesting.UpdateAction).GetObject().(*kbeta.StatefulSet)
+					if _, ok := updated.Spec.Template.Labels["fh-sync-server"]; ok {
+						t.Fatalf("expected the fh-sync-server label to be removed but got %v", updated.Spec.Template.Labels)
+					}
+					observed := int64(0)
+					statefulSet.Status = kbeta.StatefulSetStatus{ObservedGeneration: &observed, Replicas: 1, ReadyReplicas: 1, UpdatedReplicas: 1}
+					return true, updated, nil
+				})
+				return fake
+			},
+			Args:  []string{"keycloak", "fh-sync-server"},
+			Flags: []string{"--namespace=test", "--auto-redeploy=true", "--redeploy-selector=app=sync"},
+		},
+		{
+			Name:             "should fail when the rollout of the deploymentconfig fails",
+			SvcCatalogClient: redeployServiceCatalog,
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("get", "deployments", notFound("deployments"))
+				return fake
+			},
+			DeploymentConfigs: &fakeDeploymentConfigs{items: []cmd.DeploymentConfig{{
+				ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server"},
+				Status: cmd.DeploymentConfigStatus{Conditions: []cmd.DeploymentConfigCondition{
+					{Type: "Progressing", Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "replication controller fh-sync-server-2 has failed progressing"},
+				}},
+			}}},
+			ExpectError: true,
+			ValidateErr: func(t *testing.T, err error) {
+				expectedErr := "failed to roll out DeploymentConfig fh-sync-server: rollout of deploymentconfig fh-sync-server failed: replication controller fh-sync-server-2 has failed progressing"
+				if err.Error() != expectedErr {
+					t.Fatalf("expected error to be '%s' but got '%v'", expectedErr, err)
+				}
+			},
+			Args:  []string{"keycloak", "fh-sync-server"},
+			Flags: []string{"--namespace=test", "--auto-redeploy=true"},
 		},
 	}
 
@@ -721,11 +1222,15 @@ func TestIntegrationCmd_DeleteIntegrationCmd(t *testing.T) {
 			if fakeWatch != nil {
 				go func() {
 					for _, u := range updates {
-						fakeWatch.Modify(u)
+						fakeWatch.Delete(u)
 					}
 				}()
 			}
-			integrationCmd := cmd.NewIntegrationCmd(scClient, tc.K8Client(), &out)
+			dcs := tc.DeploymentConfigs
+			if dcs == nil {
+				dcs = &fakeDeploymentConfigs{}
+			}
+			integrationCmd := cmd.NewIntegrationCmd(scClient, tc.K8Client(), &mcFake.Clientset{}, dcs, &out)
 			deleteCmd := integrationCmd.DeleteIntegrationCmd()
 			deleteCmd.SetOutput(&out)
 			root.AddCommand(deleteCmd)
@@ -746,6 +1251,357 @@ func TestIntegrationCmd_DeleteIntegrationCmd(t *testing.T) {
 			if tc.ValidateErr != nil {
 				tc.ValidateErr(t, err)
 			}
+			if tc.Validate != nil {
+				tc.Validate(t, dcs)
+			}
+		})
+	}
+}
+
+func TestIntegrationCmd_DeleteIntegrationCmdDryRun(t *testing.T) {
+	shouldNotBeCalled := func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, errors.New("should not have been called")
+	}
+	scClient := &scFake.Clientset{}
+	scClient.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: action.(ktesting.GetAction).GetName()},
+			Spec:       v1beta1.ServiceInstanceSpec{ClusterServiceClassRef: &v1beta1.ClusterObjectReference{Name: "id"}},
+		}, nil
+	})
+	scClient.AddReactor("get", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ClusterServiceClass{Spec: v1beta1.ClusterServiceClassSpec{ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"fh-sync-server"}`)}}}, nil
+	})
+	scClient.AddReactor("delete", "servicebindings", shouldNotBeCalled)
+	k8Client := &kFake.Clientset{}
+	k8Client.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &kbeta.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server"}}, nil
+	})
+	k8Client.AddReactor("update", "deployments", shouldNotBeCalled)
+	k8Client.AddReactor("delete", "podpresets", shouldNotBeCalled)
+
+	var out bytes.Buffer
+	root := cmd.NewRootCmd()
+	integrationCmd := cmd.NewIntegrationCmd(scClient, k8Client, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+	deleteCmd := integrationCmd.DeleteIntegrationCmd()
+	root.AddCommand(deleteCmd)
+	if err := deleteCmd.ParseFlags([]string{"--namespace=test", "--auto-redeploy", "--dry-run", "-o=json"}); err != nil {
+		t.Fatal("failed to parse command flags", err)
+	}
+	if err := deleteCmd.RunE(deleteCmd, []string{"keycloak", "fh-sync-server"}); err != nil {
+		t.Fatal("did not expect an error but got one:", err)
+	}
+	var changes []cmd.DryRunChange
+	if err := json.Unmarshal(out.Bytes(), &changes); err != nil {
+		t.Fatal("failed to unmarshal the dry run changes", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes but got %v", changes)
+	}
+	for i, expected := range []string{"delete PodPreset keycloak-fh-sync-server", "delete ServiceBinding keycloak-fh-sync-server", "patch Deployment fh-sync-server"} {
+		if actual := changes[i].Action + " " + changes[i].Kind + " " + changes[i].Name; actual != expected {
+			t.Fatalf("expected change %d to be %s but got %s", i, expected, actual)
+		}
+	}
+	patch, _ := json.Marshal(changes[2].Patch)
+	if expected := `{"spec":{"template":{"metadata":{"labels":{"fh-sync-server":null}}}}}`; string(patch) != expected {
+		t.Fatalf("expected the deployment to be patched with %s but got %s", expected, patch)
+	}
+}
+
+func TestIntegrationCmd_CheckIntegrationsCmdDryRun(t *testing.T) {
+	shouldNotBeCalled := func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, errors.New("should not have been called")
+	}
+	scClient := &scFake.Clientset{}
+	scClient.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{{
+			ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak", Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"}},
+			Spec:       v1beta1.ServiceBindingSpec{SecretName: "sync-keycloak"},
+			Status: v1beta1.ServiceBindingStatus{
+				Conditions: []v1beta1.ServiceBindingCondition{{Type: v1beta1.ServiceBindingConditionReady, Status: v1beta1.ConditionTrue}},
+			},
+		}}}, nil
+	})
+	k8Client := &kFake.Clientset{}
+	k8Client.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &kalpha.PodPresetList{Items: []kalpha.PodPreset{{
+			ObjectMeta: metav1.ObjectMeta{Name: "sync-3scale", Labels: map[string]string{"group": "mobile", "service": "3scale"}},
+			Spec: kalpha.PodPresetSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"run": "fh-sync-server", "3scale": "enabled"}},
+				Volumes:  []corev1.Volume{{Name: "3scale", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "sync-3scale"}}}},
+			},
+		}}}, nil
+	})
+	k8Client.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{{
+			ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server"},
+			Spec:       kbeta.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"run": "fh-sync-server", "3scale": "enabled"}}}},
+		}}}, nil
+	})
+	k8Client.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak"}}, nil
+	})
+	k8Client.AddReactor("create", "podpresets", shouldNotBeCalled)
+	k8Client.AddReactor("delete", "podpresets", shouldNotBeCalled)
+	k8Client.AddReactor("update", "deployments", shouldNotBeCalled)
+
+	var out bytes.Buffer
+	root := cmd.NewRootCmd()
+	integrationCmd := cmd.NewIntegrationCmd(scClient, k8Client, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+	checkCmd := integrationCmd.CheckIntegrationsCmd()
+	root.AddCommand(checkCmd)
+	if err := checkCmd.ParseFlags([]string{"--namespace=test", "--fix", "--dry-run", "-o=json"}); err != nil {
+		t.Fatal("failed to parse command flags", err)
+	}
+	if err := checkCmd.RunE(checkCmd, []string{}); err != nil {
+		t.Fatal("did not expect an error but got one:", err)
+	}
+	var changes []cmd.DryRunChange
+	if err := json.Unmarshal(out.Bytes(), &changes); err != nil {
+		t.Fatal("failed to unmarshal the dry run changes", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes but got %v", changes)
+	}
+	for i, expected := range []string{"patch Deployment fh-sync-server", "delete PodPreset sync-3scale", "create PodPreset sync-keycloak"} {
+		if actual := changes[i].Action + " " + changes[i].Kind + " " + changes[i].Name; actual != expected {
+			t.Fatalf("expected change %d to be %s but got %s", i, expected, actual)
+		}
+	}
+}
+
+func TestIntegrationCmd_CheckIntegrationsCmd(t *testing.T) {
+	binding := func(name, secret string) v1beta1.ServiceBinding {
+		return v1beta1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"},
+			},
+			Spec: v1beta1.ServiceBindingSpec{SecretName: secret},
+			Status: v1beta1.ServiceBindingStatus{
+				Conditions: []v1beta1.ServiceBindingCondition{{Type: v1beta1.ServiceBindingConditionReady, Status: v1beta1.ConditionTrue}},
+			},
+		}
+	}
+	preset := func(name, secret, provider string) kalpha.PodPreset {
+		return kalpha.PodPreset{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"group": "mobile", "service": provider}},
+			Spec: kalpha.PodPresetSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"run": "fh-sync-server", provider: "enabled"}},
+				Volumes:  []corev1.Volume{{Name: provider, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret}}}},
+			},
+		}
+	}
+	deployment := func(labels map[string]string) *kbeta.Deployment {
+		return &kbeta.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server"},
+			Spec:       kbeta.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}}},
+		}
+	}
+	cases := []struct {
+		Name             string
+		SvcCatalogClient func() versioned.Interface
+		K8Client         func() kubernetes.Interface
+		ExpectError      bool
+		ErrorPattern     string
+		ValidateOut      func(t *testing.T, problems []*cmd.IntegrationProblem)
+		Flags            []string
+	}{
+		{
+			Name: "should report no problems when the integrations are consistent",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{binding("sync-keycloak", "sync-keycloak")}}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kalpha.PodPresetList{Items: []kalpha.PodPreset{preset("sync-keycloak", "sync-keycloak", "keycloak")}}, nil
+				})
+				fake.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{*deployment(map[string]string{"run": "fh-sync-server", "keycloak": "enabled"})}}, nil
+				})
+				fake.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak"}}, nil
+				})
+				return fake
+			},
+			ValidateOut: func(t *testing.T, problems []*cmd.IntegrationProblem) {
+				if len(problems) != 0 {
+					t.Fatalf("expected no problems but got %v", problems)
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=json"},
+		},
+		{
+			Name: "should report the leftovers of a half deleted integration and the missing pod preset",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{binding("sync-keycloak", "sync-keycloak")}}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kalpha.PodPresetList{Items: []kalpha.PodPreset{preset("sync-3scale", "sync-3scale", "3scale")}}, nil
+				})
+				fake.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{*deployment(map[string]string{"run": "fh-sync-server", "keycloak": "enabled", "3scale": "enabled"})}}, nil
+				})
+				fake.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, apierrors.NewNotFound(corev1.Resource("secrets"), "sync-keycloak")
+				})
+				return fake
+			},
+			ExpectError:  true,
+			ErrorPattern: "found 4 integration problems. Run the command again with --fix to repair them",
+			ValidateOut: func(t *testing.T, problems []*cmd.IntegrationProblem) {
+				// the keycloak label selects the pod preset --fix recreates so it is not reported
+				expected := []string{
+					"Deployment fh-sync-server: the pod template is labelled 3scale=enabled but no integration with 3scale is injected into it",
+					"PodPreset sync-3scale: the binding creating the secret sync-3scale is missing",
+					"ServiceBinding sync-keycloak: the pod preset injecting the binding into fh-sync-server is missing",
+					"ServiceBinding sync-keycloak: the binding secret sync-keycloak is missing",
+				}
+				var got []string
+				for _, p := range problems {
+					got = append(got, p.Kind+" "+p.Name+": "+p.Problem)
+				}
+				sort.Strings(got)
+				sort.Strings(expected)
+				if !reflect.DeepEqual(got, expected) {
+					t.Fatalf("expected problems %v but got %v", expected, got)
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=json"},
+		},
+		{
+			Name: "should repair the integrations with --fix",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{binding("sync-keycloak", "sync-keycloak")}}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kalpha.PodPresetList{Items: []kalpha.PodPreset{preset("sync-3scale", "sync-3scale", "3scale")}}, nil
+				})
+				fake.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{*deployment(map[string]string{"run": "fh-sync-server", "3scale": "enabled"})}}, nil
+				})
+				fake.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak"}}, nil
+				})
+				fake.AddReactor("create", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					pp := action.(ktesting.CreateAction).GetObject().(*kalpha.PodPreset)
+					if pp.Name != "sync-keycloak" || pp.Spec.Volumes[0].Secret.SecretName != "sync-keycloak" || pp.Spec.Selector.MatchLabels["run"] != "fh-sync-server" {
+						t.Fatalf("unexpected pod preset recreated %v", pp)
+					}
+					return true, pp, nil
+				})
+				fake.AddReactor("delete", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					if name := action.(ktesting.DeleteAction).GetName(); name != "sync-3scale" {
+						t.Fatalf("expected the pod preset sync-3scale to be deleted but got %s", name)
+					}
+					return true, nil, nil
+				})
+				fake.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, deployment(map[string]string{"run": "fh-sync-server", "3scale": "enabled"}), nil
+				})
+				fake.AddReactor("update", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					dep := action.(ktesting.UpdateAction).GetObject().(*kbeta.Deployment)
+					if _, ok := dep.Spec.Template.Labels["3scale"]; ok {
+						t.Fatalf("expected the 3scale label to be removed but got %v", dep.Spec.Template.Labels)
+					}
+					return true, dep, nil
+				})
+				return fake
+			},
+			ValidateOut: func(t *testing.T, problems []*cmd.IntegrationProblem) {
+				if len(problems) != 3 {
+					t.Fatalf("expected 3 problems but got %v", problems)
+				}
+				for _, p := range problems {
+					if !p.Fixed {
+						t.Fatalf("expected the problem to be fixed %v", p)
+					}
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=json", "--fix"},
+		},
+		{
+			Name: "should keep the label selecting the pod preset recreated with --fix",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{binding("sync-keycloak", "sync-keycloak")}}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kalpha.PodPresetList{}, nil
+				})
+				fake.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{*deployment(map[string]string{"run": "fh-sync-server", "keycloak": "enabled"})}}, nil
+				})
+				fake.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sync-keycloak"}}, nil
+				})
+				fake.AddReactor("create", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, action.(ktesting.CreateAction).GetObject(), nil
+				})
+				fake.AddReactor("update", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fake
+			},
+			ValidateOut: func(t *testing.T, problems []*cmd.IntegrationProblem) {
+				if len(problems) != 1 || problems[0].Kind != "ServiceBinding" || !problems[0].Fixed {
+					t.Fatalf("expected only the missing pod preset to be fixed but got %v", problems)
+				}
+			},
+			Flags: []string{"--namespace=test", "-o=json", "--fix"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			var out bytes.Buffer
+			integrationCmd := cmd.NewIntegrationCmd(tc.SvcCatalogClient(), tc.K8Client(), &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+			checkCmd := integrationCmd.CheckIntegrationsCmd()
+			root.AddCommand(checkCmd)
+			if err := checkCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := checkCmd.RunE(checkCmd, []string{})
+			if err != nil && !tc.ExpectError {
+				t.Fatal("did not expect an error but got one:", err)
+			}
+			if err == nil && tc.ExpectError {
+				t.Fatal("expected an error but got none")
+			}
+			if tc.ExpectError && err.Error() != tc.ErrorPattern {
+				t.Fatalf("expected error '%s' but got '%v'", tc.ErrorPattern, err)
+			}
+			if tc.ValidateOut != nil {
+				var problems []*cmd.IntegrationProblem
+				if err := json.Unmarshal(out.Bytes(), &problems); err != nil {
+					t.Fatal("failed to unmarshal the problems", err)
+				}
+				tc.ValidateOut(t, problems)
+			}
 		})
 	}
 }
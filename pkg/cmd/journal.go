@@ -0,0 +1,113 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// journal records the changes a command makes so they can be reverted in reverse order when a later step fails
+// or times out or the user presses Ctrl-C. A nil journal makes the changes without recording them
+type journal struct {
+	steps      []journalStep
+	noRollback bool
+	interrupt  chan os.Signal
+}
+
+type journalStep struct {
+	description string
+	revert      func() error
+}
+
+// newJournal starts a journal of the changes made by a command, keeping them on failure when --no-rollback is set.
+// Ctrl-C no longer stops the command straight away, instead the next change fails and the previous ones are reverted
+func newJournal(flags *pflag.FlagSet) *journal {
+	j := &journal{interrupt: make(chan os.Signal, 1)}
+	if noRollback, err := flags.GetBool("no-rollback"); err == nil {
+		j.noRollback = noRollback
+	}
+	signal.Notify(j.interrupt, os.Interrupt)
+	return j
+}
+
+// do makes a change, which returns the name of the object changed, and records how to revert it.
+// The change is not made once the user has pressed Ctrl-C
+func (j *journal) do(kind string, change func() (string, error), revert func(name string) error) error {
+	if j == nil {
+		_, err := change()
+		return err
+	}
+	select {
+	case <-j.interrupt:
+		return wait.ErrInterrupted
+	default:
+	}
+	name, err := change()
+	if err != nil {
+		return err
+	}
+	j.steps = append(j.steps, journalStep{description: kind + " " + name, revert: func() error { return revert(name) }})
+	return nil
+}
+
+// finish ends the journal and returns err. When err is set the changes are reverted, newest first, unless --no-rollback is set.
+// A Ctrl-C pressed since the last change, such as one only seen by a wait or pressed after it, fails the command too
+func (j *journal) finish(err error) error {
+	signal.Stop(j.interrupt)
+	select {
+	case <-j.interrupt:
+		if err == nil {
+			err = wait.ErrInterrupted
+		}
+	default:
+	}
+	if err == nil || len(j.steps) == 0 {
+		return err
+	}
+	if j.noRollback {
+		var kept []string
+		for _, step := range j.steps {
+			kept = append(kept, step.description)
+		}
+		fmt.Fprintln(os.Stderr, "--no-rollback is set, keeping the "+strings.Join(kept, ", "))
+		return err
+	}
+	var failed []string
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		step := j.steps[i]
+		if rerr := step.revert(); rerr != nil && !apierrors.IsNotFound(rerr) {
+			failed = append(failed, fmt.Sprintf("%s (%v)", step.description, rerr))
+			continue
+		}
+		fmt.Fprintln(os.Stderr, "rolled back the "+step.description)
+	}
+	if len(failed) > 0 {
+		return errors.Wrap(err, "failed to roll back the "+strings.Join(failed, " and ")+", revert them before trying again")
+	}
+	return err
+}
+
+func addNoRollbackFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("no-rollback", false, "--no-rollback keep the objects created so far when the command fails or is interrupted, for example to debug the failure")
+}
@@ -0,0 +1,990 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/input"
+	"github.com/aerogear/mobile-cli/pkg/cmd/output"
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mobile "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
+	"github.com/ghodss/yaml"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// ManifestCmd brings a namespace in line with a manifest of mobile clients, services, integrations and service configs
+type ManifestCmd struct {
+	*BaseCmd
+	mobileClient mobile.Interface
+	scClient     versioned.Interface
+	k8Client     kubernetes.Interface
+	clients      *ClientCmd
+	services     *ServicesCmd
+	integrations *IntegrationCmd
+	// clientsForContext connects to the other kubeconfig contexts diff can compare with
+	clientsForContext ClientsForContext
+}
+
+// ClientsForContext returns the clients of the named kubeconfig context
+type ClientsForContext func(context string) (mobile.Interface, versioned.Interface, kubernetes.Interface, error)
+
+// NewManifestCmd returns a configured ManifestCmd ready for use
+func NewManifestCmd(mobileClient mobile.Interface, scClient versioned.Interface, k8Client kubernetes.Interface, dcClient DeploymentConfigInterface, clientsForContext ClientsForContext, out io.Writer) *ManifestCmd {
+	return &ManifestCmd{
+		BaseCmd:           &BaseCmd{Out: output.NewRenderer(out)},
+		mobileClient:      mobileClient,
+		scClient:          scClient,
+		k8Client:          k8Client,
+		clients:           NewClientCmd(mobileClient, scClient, k8Client, out),
+		services:          NewServicesCmd(scClient, k8Client, dcClient, out),
+		integrations:      NewIntegrationCmd(scClient, k8Client, mobileClient, dcClient, out),
+		clientsForContext: clientsForContext,
+	}
+}
+
+// namespaceState is what is in the namespace to compare a manifest with
+type namespaceState struct {
+	clients map[string]v1alpha1.MobileClient
+	// instances holds the service instances by service, leaving out those provisioning mobile clients
+	instances map[string][]v1beta1.ServiceInstance
+	// instanceServices maps the service instance names to their services
+	instanceServices map[string]string
+	bindings         []v1beta1.ServiceBinding
+	configs          map[string]v1.Secret
+}
+
+// excludedServices returns the services excluded from the mobile client. Services can be excluded by the generated name
+// of their instance which differs in every namespace, so those are returned as the service of the instance
+func (s *namespaceState) excludedServices(client v1alpha1.MobileClient) []string {
+	var excluded []string
+	for _, e := range client.Spec.ExcludedServices {
+		if service, ok := s.instanceServices[e]; ok {
+			e = service
+		}
+		excluded = append(excluded, e)
+	}
+	return excluded
+}
+
+// ApplyCmd builds the apply command
+func (mc *ManifestCmd) ApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply -f <manifest>",
+		Short: "create, update and delete the mobile clients, services and integrations in your namespace to match a manifest",
+		Long: `apply reads a YAML or JSON manifest declaring the mobile clients, service instances, integrations and external service configs of a namespace,
+compares it with what is in your namespace and creates or updates what differs in dependency order: service configs, then services, then mobile clients and finally integrations.
+Every step waits for its change to be ready before the next one starts, so a failed step leaves the namespace with the steps before it applied.
+
+A namespace holds a single instance of each service in the manifest. Service parameters that are not in the manifest keep their current values
+and the parameters of existing integrations are not compared. A mobile client without a dmzUrl in the manifest keeps its current dmzUrl,
+use "mobile set value" to clear it.
+
+Resources in the namespace that are not in the manifest are kept unless --prune is set, in which case they are deleted before anything is created,
+integrations first and service configs last.
+
+--dry-run prints the steps without making them.
+
+An example manifest:
+
+  apiVersion: mobile.k8s.io/v1alpha1
+  kind: MobileManifest
+  services:
+  - service: keycloak
+  - service: fh-sync-server
+    plan: default
+    params:
+      SYNC_LOG_LEVEL: info
+  integrations:
+  - consumer: fh-sync-server
+    provider: keycloak
+  clients:
+  - name: myapp
+    clientType: android
+    appIdentifier: org.example.myapp
+  serviceConfigs:
+  - name: my-api
+    data:
+      name: my-api
+      uri: https://api.example.com`,
+		Example: `  mobile apply -f mobile.yaml --namespace=myproject
+  mobile apply -f mobile.yaml --prune --dry-run
+  cat mobile.yaml | mobile apply -f -
+  kubectl plugin mobile apply -f mobile.yaml
+  oc plugin mobile apply -f mobile.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			filename, err := cmd.PersistentFlags().GetString("filename")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if filename == "" {
+				return errors.New("missing manifest. Set it with -f <file> or read it from stdin with -f -")
+			}
+			manifest, err := readManifest(filename, os.Stdin)
+			if err != nil {
+				return err
+			}
+			prune, err := cmd.PersistentFlags().GetBool("prune")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			// the plan is only worked out on the client
+			if dryRun != nil && dryRun.mode == dryRunServer {
+				return errors.New("apply does not support --dry-run=server. Run it with --dry-run to print the plan")
+			}
+			steps, err := mc.plan(cmd.Flags(), ns, manifest, prune)
+			if err != nil {
+				return err
+			}
+			outType := outputType(cmd.Flags())
+			if err := mc.Out.Render("apply", outType, steps); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "apply steps", outType))
+			}
+			if dryRun != nil {
+				return nil
+			}
+			for _, s := range steps {
+				if s.run == nil {
+					continue
+				}
+				if err := s.run(); err != nil {
+					return errors.Wrap(err, fmt.Sprintf("failed to %s %s %s", s.Action, s.Kind, s.Name))
+				}
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringP("filename", "f", "", "-f mobile.yaml the manifest to apply, - reads it from stdin")
+	cmd.PersistentFlags().Bool("prune", false, "--prune will delete the mobile clients, services, integrations and external service configs in the namespace that are not in the manifest")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for each step to be ready before giving up. 0 waits forever")
+	addNoRollbackFlag(cmd)
+	addRedeployFlags(cmd)
+	mc.Out.AddRenderer("apply", "table", func(out io.Writer, data interface{}) error {
+		steps := data.([]*ApplyStep)
+		var rows [][]string
+		for _, s := range steps {
+			rows = append(rows, []string{s.Action, s.Kind, s.Name, s.Detail})
+		}
+		table := tablewriter.NewWriter(out)
+		table.AppendBulk(rows)
+		table.SetHeader([]string{"Action", "Kind", "Name", "Detail"})
+		table.Render()
+		return nil
+	})
+	return cmd
+}
+
+// ExportCmd builds the export command
+func (mc *ManifestCmd) ExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export the mobile clients, services and integrations in your namespace as a manifest for mobile apply",
+		Long: `export walks the mobile clients, service instances and their parameters, integrations and external service configs in your namespace
+and prints them as a manifest that mobile apply can recreate in another namespace or cluster, for example to clone a working environment into a new project
+or as a backup before an upgrade.
+
+The manifest is portable: services and integrations are referred to by service rather than by the generated names of their instances,
+and UIDs, resource versions and other fields set by the cluster are left out.
+Parameters that are declared as passwords or look like secrets are left out too and listed on stderr, add them to the manifest before applying it.`,
+		Example: `  mobile export --namespace=myproject > mobile.yaml
+  mobile export -o json
+  kubectl plugin mobile export
+  oc plugin mobile export`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			manifest, omitted, err := mc.export(ns)
+			if err != nil {
+				return err
+			}
+			outType := outputType(cmd.Flags())
+			if outType != "json" {
+				outType = "yaml"
+			}
+			if err := mc.Out.Render("export", outType, manifest); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "manifest", outType))
+			}
+			for _, o := range omitted {
+				fmt.Fprintln(os.Stderr, o)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// export builds the manifest of the namespace and lists the secret parameters it leaves out
+func (mc *ManifestCmd) export(ns string) (*Manifest, []string, error) {
+	state, err := mc.readState(ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := &Manifest{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: ManifestKind}
+	var omitted []string
+
+	var services []string
+	for service := range state.instances {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		instances := state.instances[service]
+		if len(instances) > 1 {
+			return nil, nil, errors.New(fmt.Sprintf("the namespace has %d instances of %s but a manifest holds a single instance of each service", len(instances), service))
+		}
+		si := instances[0]
+		values, err := mc.services.instanceParameterValues(ns, &si)
+		if err != nil {
+			return nil, nil, err
+		}
+		passwords := mc.services.passwordParams(&si)
+		s := ManifestService{Service: service, Plan: si.Spec.ClusterServicePlanExternalName}
+		var secret []string
+		for k, v := range values {
+			if passwords[k] || looksSecret(k) {
+				secret = append(secret, k)
+				continue
+			}
+			if s.Params == nil {
+				s.Params = map[string]string{}
+			}
+			s.Params[k] = fmt.Sprint(v)
+		}
+		if len(secret) > 0 {
+			sort.Strings(secret)
+			omitted = append(omitted, fmt.Sprintf("left out the secret parameters %s of the service %s", strings.Join(secret, ", "), service))
+		}
+		manifest.Services = append(manifest.Services, s)
+	}
+
+	for _, b := range state.bindings {
+		provider := state.instanceServices[b.Spec.ServiceInstanceRef.Name]
+		// integrations are named <consumer instance>-<provider instance>, see objectName
+		consumer := state.instanceServices[strings.TrimSuffix(b.Name, "-"+b.Spec.ServiceInstanceRef.Name)]
+		if consumer == "" || provider == "" {
+			omitted = append(omitted, fmt.Sprintf("left out the integration %s as the service instances it integrates were not found", b.Name))
+			continue
+		}
+		i := ManifestIntegration{Consumer: consumer, Provider: provider}
+		params := map[string]interface{}{}
+		if b.Spec.Parameters != nil && len(b.Spec.Parameters.Raw) > 0 {
+			if err := json.Unmarshal(b.Spec.Parameters.Raw, &params); err != nil {
+				return nil, nil, errors.Wrap(err, "failed to read the parameters of the integration "+b.Name)
+			}
+		}
+		var secret []string
+		for k, v := range params {
+			if looksSecret(k) {
+				secret = append(secret, k)
+				continue
+			}
+			if i.Params == nil {
+				i.Params = map[string]string{}
+			}
+			i.Params[k] = fmt.Sprint(v)
+		}
+		if len(secret) > 0 {
+			sort.Strings(secret)
+			omitted = append(omitted, fmt.Sprintf("left out the secret parameters %s of the integration of %s with %s", strings.Join(secret, ", "), consumer, provider))
+		}
+		manifest.Integrations = append(manifest.Integrations, i)
+	}
+	sort.SliceStable(manifest.Integrations, func(i, j int) bool {
+		return integrationKey(manifest.Integrations[i].Consumer, manifest.Integrations[i].Provider) < integrationKey(manifest.Integrations[j].Consumer, manifest.Integrations[j].Provider)
+	})
+
+	var clientIDs []string
+	for id := range state.clients {
+		clientIDs = append(clientIDs, id)
+	}
+	sort.Strings(clientIDs)
+	for _, id := range clientIDs {
+		client := state.clients[id]
+		c := ManifestClient{
+			Name:          client.Spec.Name,
+			ClientType:    client.Spec.ClientType,
+			AppIdentifier: client.Spec.AppIdentifier,
+			DmzURL:        client.Spec.DmzUrl,
+		}
+		c.ExcludedServices = state.excludedServices(client)
+		manifest.Clients = append(manifest.Clients, c)
+	}
+
+	var configs []string
+	for name := range state.configs {
+		configs = append(configs, name)
+	}
+	sort.Strings(configs)
+	for _, name := range configs {
+		secret := state.configs[name]
+		c := ManifestServiceConfig{Name: name, Data: stringData(secret.Data)}
+		for k, v := range secret.Labels {
+			if k == "mobile" || k == "external" {
+				continue
+			}
+			if c.Labels == nil {
+				c.Labels = map[string]string{}
+			}
+			c.Labels[k] = v
+		}
+		manifest.ServiceConfigs = append(manifest.ServiceConfigs, c)
+	}
+	return manifest, omitted, nil
+}
+
+// readManifest reads and validates a YAML or JSON manifest from the file, or from in when the filename is -
+func readManifest(filename string, in io.Reader) (*Manifest, error) {
+	var raw []byte
+	var err error
+	if filename == "-" {
+		raw, err = ioutil.ReadAll(in)
+	} else {
+		raw, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest "+filename)
+	}
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(raw, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest "+filename)
+	}
+	if manifest.Kind != "" && manifest.Kind != ManifestKind {
+		return nil, errors.New(fmt.Sprintf("unknown manifest kind %s, expected %s", manifest.Kind, ManifestKind))
+	}
+	services := map[string]bool{}
+	for _, s := range manifest.Services {
+		if s.Service == "" {
+			return nil, errors.New("invalid manifest: a service is missing its name")
+		}
+		if services[s.Service] {
+			return nil, errors.New(fmt.Sprintf("invalid manifest: the service %s is declared more than once", s.Service))
+		}
+		services[s.Service] = true
+	}
+	integrations := map[string]bool{}
+	for _, i := range manifest.Integrations {
+		for _, s := range []string{i.Consumer, i.Provider} {
+			if !services[s] {
+				return nil, errors.New(fmt.Sprintf("invalid manifest: the integration of %s with %s uses the service %s which is not declared in the services", i.Consumer, i.Provider, s))
+			}
+		}
+		key := integrationKey(i.Consumer, i.Provider)
+		if integrations[key] {
+			return nil, errors.New(fmt.Sprintf("invalid manifest: the integration of %s with %s is declared more than once", i.Consumer, i.Provider))
+		}
+		integrations[key] = true
+	}
+	clients := map[string]bool{}
+	for _, c := range manifest.Clients {
+		if c.Name == "" || c.ClientType == "" || c.AppIdentifier == "" {
+			return nil, errors.New("invalid manifest: mobile clients need a name, clientType and appIdentifier")
+		}
+		id := manifestClientID(c)
+		if clients[id] {
+			return nil, errors.New(fmt.Sprintf("invalid manifest: the mobile client %s is declared more than once", id))
+		}
+		clients[id] = true
+	}
+	configs := map[string]bool{}
+	for _, c := range manifest.ServiceConfigs {
+		if c.Name == "" {
+			return nil, errors.New("invalid manifest: a service config is missing its name")
+		}
+		if configs[c.Name] {
+			return nil, errors.New(fmt.Sprintf("invalid manifest: the service config %s is declared more than once", c.Name))
+		}
+		configs[c.Name] = true
+	}
+	return manifest, nil
+}
+
+// manifestClientID is the ID create client gives the mobile client
+func manifestClientID(c ManifestClient) string {
+	return strings.ToLower(c.Name + "-" + c.ClientType)
+}
+
+func integrationKey(consumer, provider string) string {
+	return consumer + " -> " + provider
+}
+
+// readState lists the mobile clients, service instances, integrations and external service configs in the namespace
+func (mc *ManifestCmd) readState(ns string) (*namespaceState, error) {
+	mcList, err := mc.mobileClient.MobileV1alpha1().MobileClients(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list mobile clients")
+	}
+	siList, err := mc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances")
+	}
+	sbList, err := mc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list integrations")
+	}
+	secrets, err := mc.k8Client.CoreV1().Secrets(ns).List(metav1.ListOptions{LabelSelector: "mobile=enabled,external=true"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service configs")
+	}
+
+	state := &namespaceState{
+		clients:          map[string]v1alpha1.MobileClient{},
+		instances:        map[string][]v1beta1.ServiceInstance{},
+		instanceServices: map[string]string{},
+		configs:          map[string]v1.Secret{},
+	}
+	clientSecrets := map[string]bool{}
+	for _, c := range mcList.Items {
+		state.clients[c.Name] = c
+		clientSecrets[clientParamsSecretName(c.Name)] = true
+	}
+	for _, si := range siList.Items {
+		provisionsClient := false
+		for _, pf := range si.Spec.ParametersFrom {
+			if pf.SecretKeyRef != nil && clientSecrets[pf.SecretKeyRef.Name] {
+				provisionsClient = true
+			}
+		}
+		if provisionsClient {
+			continue
+		}
+		service := si.Spec.ClusterServiceClassExternalName
+		state.instances[service] = append(state.instances[service], si)
+		state.instanceServices[si.Name] = service
+	}
+	for _, b := range sbList.Items {
+		if b.Annotations["consumer"] == "" || b.Annotations["provider"] == "" {
+			// not created by this tool
+			continue
+		}
+		state.bindings = append(state.bindings, b)
+	}
+	for _, s := range secrets.Items {
+		state.configs[s.Name] = s
+	}
+	return state, nil
+}
+
+// plan compares the manifest with the namespace and returns the steps bringing the namespace in line with the manifest.
+// Deletions come first when pruning, in the reverse of the order things are created in
+func (mc *ManifestCmd) plan(flags *pflag.FlagSet, ns string, manifest *Manifest, prune bool) ([]*ApplyStep, error) {
+	state, err := mc.readState(ns)
+	if err != nil {
+		return nil, err
+	}
+	var removed []*ApplyStep
+	remove := func(s *ApplyStep) {
+		if !prune {
+			s.Action = ApplyKeep
+			s.Detail = "not in the manifest, use --prune to delete it"
+			s.run = nil
+		}
+		removed = append(removed, s)
+	}
+
+	// integrations
+	declared := map[string]bool{}
+	for _, i := range manifest.Integrations {
+		declared[integrationKey(i.Consumer, i.Provider)] = true
+	}
+	// existing maps the declared integrations found in the namespace to their bindings
+	existing := map[string]string{}
+	for _, b := range state.bindings {
+		b := b
+		provider := state.instanceServices[b.Spec.ServiceInstanceRef.Name]
+		// integrations are named <consumer instance>-<provider instance>, see objectName
+		consumer := state.instanceServices[strings.TrimSuffix(b.Name, "-"+b.Spec.ServiceInstanceRef.Name)]
+		key := integrationKey(consumer, provider)
+		if consumer != "" && provider != "" && declared[key] && existing[key] == "" {
+			existing[key] = b.Name
+			continue
+		}
+		remove(&ApplyStep{Action: ApplyDelete, Kind: "Integration", Name: b.Name, run: func() error {
+			return mc.deleteIntegration(flags, ns, b)
+		}})
+	}
+
+	// mobile clients
+	declared = map[string]bool{}
+	for _, c := range manifest.Clients {
+		declared[manifestClientID(c)] = true
+	}
+	var clientIDs []string
+	for id := range state.clients {
+		clientIDs = append(clientIDs, id)
+	}
+	sort.Strings(clientIDs)
+	for _, id := range clientIDs {
+		if declared[id] {
+			continue
+		}
+		id := id
+		remove(&ApplyStep{Action: ApplyDelete, Kind: "MobileClient", Name: id, run: func() error {
+			return mc.deleteClient(flags, ns, id)
+		}})
+	}
+
+	// services
+	declared = map[string]bool{}
+	for _, s := range manifest.Services {
+		declared[s.Service] = true
+	}
+	var services []string
+	for service := range state.instances {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		if declared[service] {
+			continue
+		}
+		for _, si := range state.instances[service] {
+			si := si
+			remove(&ApplyStep{Action: ApplyDelete, Kind: "ServiceInstance", Name: si.Name, Detail: service, run: func() error {
+				return mc.deleteService(flags, ns, &si)
+			}})
+		}
+	}
+
+	// external service configs
+	declared = map[string]bool{}
+	for _, c := range manifest.ServiceConfigs {
+		declared[c.Name] = true
+	}
+	var configs []string
+	for name := range state.configs {
+		configs = append(configs, name)
+	}
+	sort.Strings(configs)
+	for _, name := range configs {
+		if declared[name] {
+			continue
+		}
+		name := name
+		remove(&ApplyStep{Action: ApplyDelete, Kind: "ServiceConfig", Name: name, run: func() error {
+			return mc.k8Client.CoreV1().Secrets(ns).Delete(name, &metav1.DeleteOptions{})
+		}})
+	}
+
+	var steps []*ApplyStep
+	for _, c := range manifest.ServiceConfigs {
+		steps = append(steps, mc.serviceConfigStep(ns, c, state))
+	}
+	for _, s := range manifest.Services {
+		step, err := mc.serviceStep(flags, ns, s, state)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	for _, c := range manifest.Clients {
+		step, err := mc.clientStep(flags, ns, c, state)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	for _, i := range manifest.Integrations {
+		i := i
+		key := integrationKey(i.Consumer, i.Provider)
+		if binding, ok := existing[key]; ok {
+			steps = append(steps, &ApplyStep{Action: ApplyUnchanged, Kind: "Integration", Name: key, Detail: binding})
+			continue
+		}
+		steps = append(steps, &ApplyStep{Action: ApplyCreate, Kind: "Integration", Name: key, run: func() error {
+			return mc.createIntegration(flags, ns, i)
+		}})
+	}
+	return append(removed, steps...), nil
+}
+
+// serviceConfigStep creates the external service config or updates its data and labels when they differ from the manifest
+func (mc *ManifestCmd) serviceConfigStep(ns string, c ManifestServiceConfig, state *namespaceState) *ApplyStep {
+	labels := map[string]string{}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels["mobile"] = "enabled"
+	labels["external"] = "true"
+	data := map[string][]byte{}
+	for k, v := range c.Data {
+		data[k] = []byte(v)
+	}
+
+	secret, ok := state.configs[c.Name]
+	if !ok {
+		return &ApplyStep{Action: ApplyCreate, Kind: "ServiceConfig", Name: c.Name, run: func() error {
+			_, err := mc.k8Client.CoreV1().Secrets(ns).Create(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: c.Name, Labels: labels},
+				Data:       data,
+			})
+			return errors.WithStack(err)
+		}}
+	}
+	var changed []string
+	if !reflect.DeepEqual(stringData(secret.Data), c.Data) {
+		changed = append(changed, "data")
+	}
+	for k, v := range labels {
+		if secret.Labels[k] != v {
+			changed = append(changed, "labels")
+			break
+		}
+	}
+	if len(changed) == 0 {
+		return &ApplyStep{Action: ApplyUnchanged, Kind: "ServiceConfig", Name: c.Name}
+	}
+	return &ApplyStep{Action: ApplyUpdate, Kind: "ServiceConfig", Name: c.Name, Detail: strings.Join(changed, ", "), run: func() error {
+		for k, v := range labels {
+			secret.Labels[k] = v
+		}
+		secret.Data = data
+		_, err := mc.k8Client.CoreV1().Secrets(ns).Update(&secret)
+		return errors.WithStack(err)
+	}}
+}
+
+func stringData(data map[string][]byte) map[string]string {
+	values := map[string]string{}
+	for k, v := range data {
+		values[k] = string(v)
+	}
+	return values
+}
+
+// serviceStep provisions the service or updates the plan and parameters of its instance when they differ from the manifest
+func (mc *ManifestCmd) serviceStep(flags *pflag.FlagSet, ns string, s ManifestService, state *namespaceState) (*ApplyStep, error) {
+	instances := state.instances[s.Service]
+	if len(instances) > 1 {
+		var names []string
+		for _, si := range instances {
+			names = append(names, si.Name)
+		}
+		return nil, errors.New(fmt.Sprintf("the namespace has %d instances of %s (%s) but a manifest declares a single instance of each service", len(instances), s.Service, strings.Join(names, ", ")))
+	}
+	if len(instances) == 0 {
+		planName := s.Plan
+		if planName == "" {
+			planName = defaultServicePlan
+		}
+		return &ApplyStep{Action: ApplyCreate, Kind: "ServiceInstance", Name: s.Service, Detail: "plan " + planName, run: func() error {
+			return mc.createService(flags, ns, s.Service, planName, s.Params)
+		}}, nil
+	}
+
+	si := instances[0]
+	values, err := mc.services.instanceParameterValues(ns, &si)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	params := map[string]string{}
+	for _, k := range sortedKeys(s.Params) {
+		if v, ok := values[k]; ok && fmt.Sprint(v) == s.Params[k] {
+			continue
+		}
+		changed = append(changed, k)
+		params[k] = s.Params[k]
+	}
+	planChanged := s.Plan != "" && s.Plan != si.Spec.ClusterServicePlanExternalName
+	if !planChanged && len(changed) == 0 {
+		return &ApplyStep{Action: ApplyUnchanged, Kind: "ServiceInstance", Name: si.Name, Detail: s.Service}, nil
+	}
+	var detail []string
+	if planChanged {
+		detail = append(detail, fmt.Sprintf("plan %s -> %s", si.Spec.ClusterServicePlanExternalName, s.Plan))
+	}
+	if len(changed) > 0 {
+		detail = append(detail, "params "+strings.Join(changed, ", "))
+	}
+	return &ApplyStep{Action: ApplyUpdate, Kind: "ServiceInstance", Name: si.Name, Detail: strings.Join(detail, ", "), run: func() error {
+		return mc.updateService(flags, ns, si.Name, s.Plan, planChanged, params)
+	}}, nil
+}
+
+// clientStep creates the mobile client or updates its spec when it differs from the manifest
+func (mc *ManifestCmd) clientStep(flags *pflag.FlagSet, ns string, c ManifestClient, state *namespaceState) (*ApplyStep, error) {
+	id := manifestClientID(c)
+	existing, ok := state.clients[id]
+	if !ok {
+		// building the client up front validates it before any step is run
+		client, err := mc.clients.buildClient(ns, c.Name, c.ClientType, c.AppIdentifier, "", map[string]string{}, false)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid mobile client "+id)
+		}
+		return &ApplyStep{Action: ApplyCreate, Kind: "MobileClient", Name: id, Detail: c.ClientType + " " + c.AppIdentifier, run: func() error {
+			return mc.createClient(flags, ns, client, c)
+		}}, nil
+	}
+	var changed []string
+	if existing.Spec.AppIdentifier != c.AppIdentifier {
+		changed = append(changed, "appIdentifier")
+	}
+	if c.DmzURL != "" && existing.Spec.DmzUrl != c.DmzURL {
+		changed = append(changed, "dmzUrl")
+	}
+	if !sameServices(state.excludedServices(existing), c.ExcludedServices) {
+		changed = append(changed, "excludedServices")
+	}
+	if len(changed) == 0 {
+		return &ApplyStep{Action: ApplyUnchanged, Kind: "MobileClient", Name: id}, nil
+	}
+	return &ApplyStep{Action: ApplyUpdate, Kind: "MobileClient", Name: id, Detail: strings.Join(changed, ", "), run: func() error {
+		return mc.updateClientSpec(ns, id, c)
+	}}, nil
+}
+
+func sameServices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+func (mc *ManifestCmd) createService(flags *pflag.FlagSet, ns, service, planName string, given map[string]string) error {
+	clusterServiceClass, err := findServiceClassByName(mc.scClient, service)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	clusterServicePlan, err := findServicePlanByNameAndClass(mc.scClient, planName, clusterServiceClass.Name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	parameters, err := instanceParams(clusterServicePlan, given, false)
+	if err != nil {
+		return err
+	}
+	steps := newJournal(flags)
+	created, err := mc.services.provision(ns, clusterServiceClass, clusterServicePlan.Spec.ExternalName, parameters, steps)
+	if err != nil {
+		return steps.finish(err)
+	}
+	return steps.finish(mc.waitForInstance(flags, ns, created.Name, 0))
+}
+
+func (mc *ManifestCmd) updateService(flags *pflag.FlagSet, ns, sid, planName string, planChanged bool, params map[string]string) error {
+	si, err := mc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Get(sid, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get service instance "+sid)
+	}
+	if si.Spec.ClusterServiceClassRef == nil {
+		return errors.New("the service instance " + sid + " has not been resolved to a serviceclass yet")
+	}
+	var clusterServicePlan *v1beta1.ClusterServicePlan
+	if planChanged {
+		clusterServicePlan, err = findServicePlanByNameAndClass(mc.scClient, planName, si.Spec.ClusterServiceClassRef.Name)
+	} else {
+		clusterServicePlan, err = findServicePlanForInstance(mc.scClient, si, si.Spec.ClusterServiceClassRef.Name)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	updated, err := mc.services.updateInstance(ns, si, clusterServicePlan, planChanged, params, nil)
+	if err != nil {
+		return err
+	}
+	return mc.waitForInstance(flags, ns, updated.Name, updated.Generation)
+}
+
+func (mc *ManifestCmd) deleteService(flags *pflag.FlagSet, ns string, si *v1beta1.ServiceInstance) error {
+	if err := mc.services.deprovision(ns, si.Name, si.Spec.ParametersFrom); err != nil {
+		return err
+	}
+	return mc.waitForDeprovision(flags, ns, si)
+}
+
+func (mc *ManifestCmd) waitForDeprovision(flags *pflag.FlagSet, ns string, si *v1beta1.ServiceInstance) error {
+	waiter, err := newWaiter(flags, si.Name, mc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
+	if err != nil {
+		return err
+	}
+	waiter.ResourceVersion = si.ResourceVersion
+	return waiter.Until(wait.ServiceInstanceDeprovisioned())
+}
+
+func (mc *ManifestCmd) waitForInstance(flags *pflag.FlagSet, ns, name string, generation int64) error {
+	waiter, err := newWaiter(flags, name, mc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
+	if err != nil {
+		return err
+	}
+	return waiter.Until(wait.ServiceInstanceReady(generation))
+}
+
+func (mc *ManifestCmd) createClient(flags *pflag.FlagSet, ns string, client *clientProvision, c ManifestClient) error {
+	steps := newJournal(flags)
+	created, err := mc.clients.provisionClient(ns, client, steps)
+	if err != nil {
+		return steps.finish(err)
+	}
+	if err := mc.waitForInstance(flags, ns, created.Name, 0); err != nil {
+		return steps.finish(errors.Wrap(err, "Failed to provision "+client.ServiceName))
+	}
+	if c.DmzURL == "" && len(c.ExcludedServices) == 0 {
+		return steps.finish(nil)
+	}
+	// the mobile client is only there once the service provisioning it is ready
+	return steps.finish(mc.updateClientSpec(ns, client.ID, c))
+}
+
+func (mc *ManifestCmd) updateClientSpec(ns, id string, c ManifestClient) error {
+	client, err := mc.mobileClient.MobileV1alpha1().MobileClients(ns).Get(id, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get mobile client with clientID "+id)
+	}
+	if client.Spec.AppIdentifier != c.AppIdentifier {
+		if err := input.ValidateAppIdentifier(client.Spec.ClientType, c.AppIdentifier); err != nil {
+			return errors.Wrap(err, "invalid mobile client "+id)
+		}
+		client.Spec.AppIdentifier = c.AppIdentifier
+	}
+	if c.DmzURL != "" {
+		client.Spec.DmzUrl = c.DmzURL
+	}
+	// the manifest excludes services by name while mobile clients exclude them by the name of their instance
+	instances, err := mc.clients.instancesByService(ns)
+	if err != nil {
+		return err
+	}
+	client.Spec.ExcludedServices = nil
+	for _, service := range c.ExcludedServices {
+		if instance, ok := instances[service]; ok {
+			service = instance
+		}
+		client.Spec.ExcludedServices = append(client.Spec.ExcludedServices, service)
+	}
+	if _, err := mc.mobileClient.MobileV1alpha1().MobileClients(ns).Update(client); err != nil {
+		return errors.Wrap(err, "failed to update mobile client "+id)
+	}
+	return nil
+}
+
+// deleteClient deletes the mobile client and the resources it owns, as delete client does, and waits for its service instances to be deprovisioned
+func (mc *ManifestCmd) deleteClient(flags *pflag.FlagSet, ns, id string) error {
+	owned, err := mc.clients.findClientResources(ns, id, false)
+	if err != nil {
+		return err
+	}
+	if err := mc.clients.deleteClientResources(ns, id, owned); err != nil {
+		return err
+	}
+	for _, si := range owned.Instances {
+		if err := mc.waitForDeprovision(flags, ns, &si); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceInstance returns the instance of the service in the namespace
+func (mc *ManifestCmd) serviceInstance(ns, service string) (*v1beta1.ServiceInstance, error) {
+	instances, err := findServiceInstanceByExternalName(mc.scClient, ns, service)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list service instances")
+	}
+	if len(instances) == 0 {
+		return nil, errors.New("no instance of the service " + service + " found")
+	}
+	return &instances[0], nil
+}
+
+func (mc *ManifestCmd) createIntegration(flags *pflag.FlagSet, ns string, i ManifestIntegration) error {
+	consumerSvcInst, err := mc.serviceInstance(ns, i.Consumer)
+	if err != nil {
+		return err
+	}
+	providerSvcInst, err := mc.serviceInstance(ns, i.Provider)
+	if err != nil {
+		return err
+	}
+	consumerServiceName, err := mc.integrations.getServiceNameFromServiceInst(consumerSvcInst)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	providerServiceName, err := mc.integrations.getServiceNameFromServiceInst(providerSvcInst)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	bindParams, err := mc.integrations.bindParams(providerSvcInst, providerServiceName, i.Params, false)
+	if err != nil {
+		return err
+	}
+	preset, binding, err := buildIntegration(consumerSvcInst.Name, providerSvcInst.Name, consumerServiceName, providerServiceName, bindParams)
+	if err != nil {
+		return err
+	}
+	steps := newJournal(flags)
+	sb, err := mc.integrations.createIntegration(ns, preset, binding, steps)
+	if err != nil {
+		return steps.finish(err)
+	}
+	waiter, err := newWaiter(flags, sb.Name, mc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Watch)
+	if err != nil {
+		return steps.finish(err)
+	}
+	if err := waiter.Until(wait.ServiceBindingReady()); err != nil {
+		return steps.finish(errors.Wrap(err, "Failed to create integration"))
+	}
+	if redeploy, err := flags.GetBool("auto-redeploy"); err != nil || !redeploy {
+		return steps.finish(errors.WithStack(err))
+	}
+	return steps.finish(mc.integrations.redeploy(flags, ns, consumerSvcInst.Name, consumerServiceName, providerServiceName, "enabled", steps))
+}
+
+func (mc *ManifestCmd) deleteIntegration(flags *pflag.FlagSet, ns string, b v1beta1.ServiceBinding) error {
+	if err := mc.integrations.deleteIntegration(ns, b.Name); err != nil {
+		return err
+	}
+	getBinding := func() (runtime.Object, error) {
+		return mc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Get(b.Name, metav1.GetOptions{})
+	}
+	if err := waitForDeletion(flags, b.Name, getBinding, mc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Watch); err != nil {
+		return errors.Wrap(err, "Failed to delete integration")
+	}
+	if redeploy, err := flags.GetBool("auto-redeploy"); err != nil || !redeploy {
+		return errors.WithStack(err)
+	}
+	consumerSvcInstName := strings.TrimSuffix(b.Name, "-"+b.Spec.ServiceInstanceRef.Name)
+	return mc.integrations.redeploy(flags, ns, consumerSvcInstName, b.Annotations["consumer"], b.Annotations["provider"], "", nil)
+}
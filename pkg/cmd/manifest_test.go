@@ -0,0 +1,464 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
+	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	kFake "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestManifestCmd_ApplyCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal("failed to create temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+
+	instance := func(name, service, paramsSecret string) *v1beta1.ServiceInstance {
+		return &v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec: v1beta1.ServiceInstanceSpec{
+				PlanReference: v1beta1.PlanReference{
+					ClusterServiceClassExternalName: service,
+					ClusterServicePlanExternalName:  "default",
+				},
+				ParametersFrom: []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: paramsSecret, Key: "parameters"}}},
+			},
+		}
+	}
+	serviceConfig := func(name string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: map[string]string{"mobile": "enabled", "external": "true"}},
+			Data:       map[string][]byte{"name": []byte(name), "uri": []byte("https://" + name + ".example.com")},
+		}
+	}
+	// the namespace has keycloak integrated with fh-sync-server, a metrics service, two mobile clients and an external service config
+	scClient := func() *scFake.Clientset {
+		return scFake.NewSimpleClientset(
+			instance("keycloak-abc", "keycloak", "keycloak-params"),
+			instance("sync-xyz", "fh-sync-server", "sync-params"),
+			instance("metrics-123", "metrics", "metrics-params"),
+			instance("android-app-456", "android-app", "myapp-android-apb-params"),
+			&v1beta1.ServiceBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "sync-xyz-keycloak-abc", Namespace: "test", Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"}},
+				Spec:       v1beta1.ServiceBindingSpec{ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "keycloak-abc"}},
+			},
+		)
+	}
+	k8Client := func() *kFake.Clientset {
+		return kFake.NewSimpleClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "keycloak-params", Namespace: "test"},
+				Data:       map[string][]byte{"parameters": []byte(`{"ADMIN_NAME":"admin"}`)},
+			},
+			serviceConfig("old-api"),
+		)
+	}
+	mobileClient := func() *mcFake.Clientset {
+		return mcFake.NewSimpleClientset(
+			&v1alpha1.MobileClient{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp-android", Namespace: "test"},
+				Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp"},
+			},
+			&v1alpha1.MobileClient{
+				ObjectMeta: metav1.ObjectMeta{Name: "oldapp-cordova", Namespace: "test"},
+				Spec:       v1alpha1.MobileClientSpec{Name: "oldapp", ClientType: "cordova", AppIdentifier: "org.example.oldapp"},
+			},
+		)
+	}
+	manifest := `apiVersion: mobile.k8s.io/v1alpha1
+kind: MobileManifest
+services:
+- service: keycloak
+  params:
+    ADMIN_NAME: root
+- service: fh-sync-server
+integrations:
+- consumer: fh-sync-server
+  provider: keycloak
+clients:
+- name: myapp
+  clientType: android
+  appIdentifier: org.example.renamed
+serviceConfigs:
+- name: my-api
+  data:
+    name: my-api
+    uri: https://my-api.example.com
+`
+
+	// the client oldapp-cordova was provisioned by an instance that has a binding injected by a pod preset and it has a secret labelled with its clientId
+	var prunedClientSvcCatalog *scFake.Clientset
+	prunedClientSvcCatalogClient := func() *scFake.Clientset {
+		prunedClientSvcCatalog = scFake.NewSimpleClientset(
+			instance("cordova-app-789", "cordova-app", "oldapp-cordova-apb-params"),
+			&v1beta1.ServiceBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "cordova-app-789-push", Namespace: "test"},
+				Spec:       v1beta1.ServiceBindingSpec{ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "cordova-app-789"}, SecretName: "cordova-app-789-push"},
+			},
+		)
+		prunedClientSvcCatalog.PrependWatchReactor("serviceinstances", func(action ktesting.Action) (bool, watch.Interface, error) {
+			fakeWatch := watch.NewRaceFreeFake()
+			fakeWatch.Action(watch.Deleted, &v1beta1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "cordova-app-789"}})
+			return true, fakeWatch, nil
+		})
+		return prunedClientSvcCatalog
+	}
+
+	var excludingMobile *mcFake.Clientset
+	excludingMobileClient := func() *mcFake.Clientset {
+		excludingMobile = mobileClient()
+		return excludingMobile
+	}
+
+	cases := []struct {
+		Name             string
+		Manifest         string
+		SvcCatalogClient func() *scFake.Clientset
+		K8Client         func() *kFake.Clientset
+		MobileClient     func() *mcFake.Clientset
+		Flags            []string
+		ExpectError      string
+		ExpectedSteps    []string
+		Validate         func(t *testing.T, k8Client kubernetes.Interface)
+	}{
+		{
+			Name:     "test apply plans the changes and keeps what is not in the manifest",
+			Manifest: manifest,
+			K8Client: k8Client,
+			Flags:    []string{"--namespace=test", "--dry-run", "-o=json"},
+			ExpectedSteps: []string{
+				"keep MobileClient oldapp-cordova",
+				"keep ServiceInstance metrics-123",
+				"keep ServiceConfig old-api",
+				"create ServiceConfig my-api",
+				"update ServiceInstance keycloak-abc",
+				"unchanged ServiceInstance sync-xyz",
+				"update MobileClient myapp-android",
+				"unchanged Integration fh-sync-server -> keycloak",
+			},
+		},
+		{
+			Name:     "test apply with prune deletes what is not in the manifest first",
+			Manifest: manifest,
+			K8Client: k8Client,
+			Flags:    []string{"--namespace=test", "--dry-run", "--prune", "-o=json"},
+			ExpectedSteps: []string{
+				"delete MobileClient oldapp-cordova",
+				"delete ServiceInstance metrics-123",
+				"delete ServiceConfig old-api",
+				"create ServiceConfig my-api",
+				"update ServiceInstance keycloak-abc",
+				"unchanged ServiceInstance sync-xyz",
+				"update MobileClient myapp-android",
+				"unchanged Integration fh-sync-server -> keycloak",
+			},
+		},
+		{
+			Name:     "test apply creates and prunes the external service configs",
+			Manifest: "kind: MobileManifest\nserviceConfigs:\n- name: my-api\n  data:\n    name: my-api\n",
+			K8Client: func() *kFake.Clientset {
+				return kFake.NewSimpleClientset(serviceConfig("old-api"))
+			},
+			SvcCatalogClient: func() *scFake.Clientset { return scFake.NewSimpleClientset() },
+			MobileClient:     func() *mcFake.Clientset { return mcFake.NewSimpleClientset() },
+			Flags:            []string{"--namespace=test", "--prune", "-o=json"},
+			ExpectedSteps: []string{
+				"delete ServiceConfig old-api",
+				"create ServiceConfig my-api",
+			},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				secrets, err := k8Client.CoreV1().Secrets("test").List(metav1.ListOptions{LabelSelector: "mobile=enabled,external=true"})
+				if err != nil {
+					t.Fatal("failed to list service configs", err)
+				}
+				if len(secrets.Items) != 1 || secrets.Items[0].Name != "my-api" || string(secrets.Items[0].Data["name"]) != "my-api" {
+					t.Fatalf("expected only the service config my-api but got %v", secrets.Items)
+				}
+			},
+		},
+		{
+			Name:             "test apply with prune deletes the resources owned by the pruned clients",
+			Manifest:         "kind: MobileManifest\n",
+			SvcCatalogClient: prunedClientSvcCatalogClient,
+			K8Client: func() *kFake.Clientset {
+				return kFake.NewSimpleClientset(
+					&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "oldapp-cordova-apb-params", Namespace: "test"}},
+					&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "oldapp-cordova-config", Namespace: "test", Labels: map[string]string{"clientId": "oldapp-cordova"}}},
+					&kalpha.PodPreset{
+						ObjectMeta: metav1.ObjectMeta{Name: "cordova-app-789-push", Namespace: "test"},
+						Spec:       kalpha.PodPresetSpec{Volumes: []corev1.Volume{{Name: "push", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "cordova-app-789-push"}}}}},
+					},
+				)
+			},
+			MobileClient: func() *mcFake.Clientset {
+				return mcFake.NewSimpleClientset(&v1alpha1.MobileClient{
+					ObjectMeta: metav1.ObjectMeta{Name: "oldapp-cordova", Namespace: "test"},
+					Spec:       v1alpha1.MobileClientSpec{Name: "oldapp", ClientType: "cordova", AppIdentifier: "org.example.oldapp"},
+				})
+			},
+			Flags:         []string{"--namespace=test", "--prune", "-o=json"},
+			ExpectedSteps: []string{"delete MobileClient oldapp-cordova"},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				if secrets, _ := k8Client.CoreV1().Secrets("test").List(metav1.ListOptions{}); len(secrets.Items) != 0 {
+					t.Fatalf("expected the secrets of the client to be deleted but got %v", secrets.Items)
+				}
+				if presets, _ := k8Client.SettingsV1alpha1().PodPresets("test").List(metav1.ListOptions{}); len(presets.Items) != 0 {
+					t.Fatalf("expected the pod presets of the client to be deleted but got %v", presets.Items)
+				}
+				if bindings, _ := prunedClientSvcCatalog.ServicecatalogV1beta1().ServiceBindings("test").List(metav1.ListOptions{}); len(bindings.Items) != 0 {
+					t.Fatalf("expected the bindings of the client to be deleted but got %v", bindings.Items)
+				}
+				if instances, _ := prunedClientSvcCatalog.ServicecatalogV1beta1().ServiceInstances("test").List(metav1.ListOptions{}); len(instances.Items) != 0 {
+					t.Fatalf("expected the service instance of the client to be deleted but got %v", instances.Items)
+				}
+			},
+		},
+		{
+			Name:         "test apply excludes the services from the mobile client by the name of their instance",
+			Manifest:     "kind: MobileManifest\nclients:\n- name: myapp\n  clientType: android\n  appIdentifier: org.example.myapp\n  excludedServices:\n  - keycloak\n",
+			K8Client:     k8Client,
+			MobileClient: excludingMobileClient,
+			Flags:        []string{"--namespace=test", "-o=json"},
+			ExpectedSteps: []string{
+				"keep Integration sync-xyz-keycloak-abc",
+				"keep MobileClient oldapp-cordova",
+				"keep ServiceInstance sync-xyz",
+				"keep ServiceInstance keycloak-abc",
+				"keep ServiceInstance metrics-123",
+				"keep ServiceConfig old-api",
+				"update MobileClient myapp-android",
+			},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				client, err := excludingMobile.MobileV1alpha1().MobileClients("test").Get("myapp-android", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal("failed to get the mobile client", err)
+				}
+				if !reflect.DeepEqual(client.Spec.ExcludedServices, []string{"keycloak-abc"}) {
+					t.Fatalf("expected the keycloak instance keycloak-abc to be excluded but got %v", client.Spec.ExcludedServices)
+				}
+			},
+		},
+		{
+			Name:        "test apply checks the app identifier of an updated mobile client against its client type",
+			Manifest:    "kind: MobileManifest\nclients:\n- name: myapp\n  clientType: android\n  appIdentifier: org.example.my-app\n",
+			K8Client:    k8Client,
+			Flags:       []string{"--namespace=test"},
+			ExpectError: "invalid mobile client myapp-android: invalid appIdentifier org.example.my-app for the android client type",
+		},
+		{
+			Name:        "test apply rejects --dry-run=server",
+			Manifest:    manifest,
+			K8Client:    k8Client,
+			Flags:       []string{"--namespace=test", "--dry-run=server"},
+			ExpectError: "apply does not support --dry-run=server. Run it with --dry-run to print the plan",
+		},
+		{
+			Name:        "test apply rejects integrations of services not declared in the manifest",
+			Manifest:    "kind: MobileManifest\nservices:\n- service: fh-sync-server\nintegrations:\n- consumer: fh-sync-server\n  provider: keycloak\n",
+			K8Client:    k8Client,
+			Flags:       []string{"--namespace=test"},
+			ExpectError: "invalid manifest: the integration of fh-sync-server with keycloak uses the service keycloak which is not declared in the services",
+		},
+		{
+			Name:        "test apply rejects manifests of another kind",
+			Manifest:    "kind: Deployment\n",
+			K8Client:    k8Client,
+			Flags:       []string{"--namespace=test"},
+			ExpectError: "unknown manifest kind Deployment, expected MobileManifest",
+		},
+		{
+			Name:        "test apply returns an error when missing the manifest",
+			K8Client:    k8Client,
+			Flags:       []string{"--namespace=test"},
+			ExpectError: "missing manifest. Set it with -f <file> or read it from stdin with -f -",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			flags := tc.Flags
+			if tc.Manifest != "" {
+				file := filepath.Join(dir, fmt.Sprintf("mobile%d.yaml", i))
+				if err := ioutil.WriteFile(file, []byte(tc.Manifest), 0600); err != nil {
+					t.Fatal("failed to write manifest", err)
+				}
+				flags = append(flags, "-f", file)
+			}
+			if tc.SvcCatalogClient == nil {
+				tc.SvcCatalogClient = scClient
+			}
+			if tc.MobileClient == nil {
+				tc.MobileClient = mobileClient
+			}
+			var out bytes.Buffer
+			k8 := tc.K8Client()
+			root := cmd.NewRootCmd()
+			manifestCmd := cmd.NewManifestCmd(tc.MobileClient(), tc.SvcCatalogClient(), k8, &fakeDeploymentConfigs{}, nil, &out)
+			applyCmd := manifestCmd.ApplyCmd()
+			root.AddCommand(applyCmd)
+			if err := applyCmd.ParseFlags(flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := applyCmd.RunE(applyCmd, []string{})
+			if tc.ExpectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectError) {
+					t.Fatalf("expected error %q but got %v", tc.ExpectError, err)
+				}
+			} else if err != nil {
+				t.Fatal("did not expect an error but got one:", err)
+			}
+			if tc.ExpectedSteps == nil {
+				return
+			}
+			var steps []cmd.ApplyStep
+			if err := json.NewDecoder(&out).Decode(&steps); err != nil {
+				t.Fatal("failed to unmarshal the apply steps", err, out.String())
+			}
+			var actual []string
+			for _, s := range steps {
+				actual = append(actual, s.Action+" "+s.Kind+" "+s.Name)
+			}
+			if strings.Join(actual, "\n") != strings.Join(tc.ExpectedSteps, "\n") {
+				t.Fatalf("expected the steps\n%s\nbut got\n%s", strings.Join(tc.ExpectedSteps, "\n"), strings.Join(actual, "\n"))
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, k8)
+			}
+		})
+	}
+}
+
+func TestManifestCmd_ExportCmd(t *testing.T) {
+	scClient := scFake.NewSimpleClientset(
+		&v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "keycloak-abc", Namespace: "test", UID: "1234", ResourceVersion: "42"},
+			Spec: v1beta1.ServiceInstanceSpec{
+				PlanReference:  v1beta1.PlanReference{ClusterServiceClassExternalName: "keycloak", ClusterServicePlanExternalName: "default"},
+				ParametersFrom: []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "keycloak-params", Key: "parameters"}}},
+			},
+		},
+		&v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "sync-xyz", Namespace: "test"},
+			Spec:       v1beta1.ServiceInstanceSpec{PlanReference: v1beta1.PlanReference{ClusterServiceClassExternalName: "fh-sync-server", ClusterServicePlanExternalName: "default"}},
+		},
+		&v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "android-app-456", Namespace: "test"},
+			Spec: v1beta1.ServiceInstanceSpec{
+				PlanReference:  v1beta1.PlanReference{ClusterServiceClassExternalName: "android-app", ClusterServicePlanExternalName: "default"},
+				ParametersFrom: []v1beta1.ParametersFromSource{{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "myapp-android-apb-params", Key: "parameters"}}},
+			},
+		},
+		&v1beta1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "sync-xyz-keycloak-abc", Namespace: "test", Annotations: map[string]string{"consumer": "fh-sync-server", "provider": "keycloak"}},
+			Spec: v1beta1.ServiceBindingSpec{
+				ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "keycloak-abc"},
+				Parameters:         &runtime.RawExtension{Raw: []byte(`{"CLIENT_ID":"sync","CLIENT_SECRET":"shh"}`)},
+			},
+		},
+	)
+	k8Client := kFake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keycloak-params", Namespace: "test"},
+			Data:       map[string][]byte{"parameters": []byte(`{"ADMIN_NAME":"admin","ADMIN_PASSWORD":"secret"}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-api", Namespace: "test", UID: "5678", Labels: map[string]string{"mobile": "enabled", "external": "true", "team": "a"}},
+			Data:       map[string][]byte{"name": []byte("my-api"), "uri": []byte("https://my-api.example.com")},
+		},
+	)
+	mobileClient := mcFake.NewSimpleClientset(&v1alpha1.MobileClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp-android", Namespace: "test", UID: "91011"},
+		Spec:       v1alpha1.MobileClientSpec{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ApiKey: "the-api-key", ExcludedServices: []string{"keycloak-abc"}},
+	})
+
+	var out bytes.Buffer
+	root := cmd.NewRootCmd()
+	manifestCmd := cmd.NewManifestCmd(mobileClient, scClient, k8Client, &fakeDeploymentConfigs{}, nil, &out)
+	exportCmd := manifestCmd.ExportCmd()
+	root.AddCommand(exportCmd)
+	if err := exportCmd.ParseFlags([]string{"--namespace=test"}); err != nil {
+		t.Fatal("failed to parse command flags", err)
+	}
+	if err := exportCmd.RunE(exportCmd, []string{}); err != nil {
+		t.Fatal("did not expect an error but got one:", err)
+	}
+	exported := out.String()
+	for _, cluster := range []string{"1234", "5678", "91011", "resourceVersion", "keycloak-abc", "android-app", "the-api-key"} {
+		if strings.Contains(exported, cluster) {
+			t.Fatalf("expected the manifest to leave out %s but got\n%s", cluster, exported)
+		}
+	}
+	for _, secret := range []string{"ADMIN_PASSWORD", "CLIENT_SECRET"} {
+		if strings.Contains(exported, secret) {
+			t.Fatalf("expected the manifest to leave out the secret parameter %s but got\n%s", secret, exported)
+		}
+	}
+	manifest := cmd.Manifest{}
+	if err := yaml.Unmarshal(out.Bytes(), &manifest); err != nil {
+		t.Fatal("failed to unmarshal the manifest", err)
+	}
+	expected := cmd.Manifest{
+		APIVersion: "mobile.k8s.io/v1alpha1",
+		Kind:       "MobileManifest",
+		Clients:    []cmd.ManifestClient{{Name: "myapp", ClientType: "android", AppIdentifier: "org.example.myapp", ExcludedServices: []string{"keycloak"}}},
+		Services: []cmd.ManifestService{
+			{Service: "fh-sync-server", Plan: "default"},
+			{Service: "keycloak", Plan: "default", Params: map[string]string{"ADMIN_NAME": "admin"}},
+		},
+		Integrations:   []cmd.ManifestIntegration{{Consumer: "fh-sync-server", Provider: "keycloak", Params: map[string]string{"CLIENT_ID": "sync"}}},
+		ServiceConfigs: []cmd.ManifestServiceConfig{{Name: "my-api", Labels: map[string]string{"team": "a"}, Data: map[string]string{"name": "my-api", "uri": "https://my-api.example.com"}}},
+	}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Fatalf("expected the manifest %+v but got %+v", expected, manifest)
+	}
+
+	// applying the export to the namespace it came from changes nothing
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal("failed to create temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mobile.yaml")
+	if err := ioutil.WriteFile(file, out.Bytes(), 0600); err != nil {
+		t.Fatal("failed to write manifest", err)
+	}
+	out.Reset()
+	applyCmd := manifestCmd.ApplyCmd()
+	root.AddCommand(applyCmd)
+	if err := applyCmd.ParseFlags([]string{"--namespace=test", "--prune", "-o=json", "-f", file}); err != nil {
+		t.Fatal("failed to parse command flags", err)
+	}
+	if err := applyCmd.RunE(applyCmd, []string{}); err != nil {
+		t.Fatal("did not expect an error but got one:", err)
+	}
+	var steps []cmd.ApplyStep
+	if err := json.Unmarshal(out.Bytes(), &steps); err != nil {
+		t.Fatal("failed to unmarshal the apply steps", err)
+	}
+	for _, s := range steps {
+		if s.Action != cmd.ApplyUnchanged {
+			t.Fatalf("expected applying the export to change nothing but got %v", steps)
+		}
+	}
+}
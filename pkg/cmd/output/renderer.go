@@ -19,6 +19,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 )
 
@@ -32,6 +33,14 @@ func (r *Renderer) Render(cmd, outputType string, data interface{}) error {
 		encoder.SetIndent("", "	")
 		return encoder.Encode(data)
 	}
+	if strings.ToLower(outputType) == "yaml" {
+		b, err := yaml.Marshal(data)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = r.out.Write(b)
+		return errors.WithStack(err)
+	}
 
 	if render, ok := renderers[cmd+outputType]; ok {
 		if err := render(r.out, data); err != nil {
@@ -2,11 +2,19 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // ServiceParams for creating integration and binding
@@ -17,14 +25,124 @@ type ServiceParams struct {
 	Type                 string                            `json:"type"`
 }
 
+const (
+	paramFileSource = "@"
+	paramEnvSource  = "env:"
+	// paramEscape starts a value that is used as it is, so values such as passwords can begin with @ or env:
+	paramEscape = "\\"
+)
+
+// parseParams parses KEY=VALUE pairs. A value of @path is read from the file at path and a value of
+// env:VAR is read from the environment variable VAR, so secrets don't have to be passed on the command line.
+// A value starting with \ is used as it is without the \, so \@value passes @value
 func parseParams(keyVals []string) (map[string]string, error) {
 	params := map[string]string{}
 	for _, p := range keyVals {
-		kv := strings.Split(p, "=")
+		kv := strings.SplitN(p, "=", 2)
 		if len(kv) != 2 {
 			return nil, NewIncorrectParameterFormat("key value pairs are needed failed to find one: " + p)
 		}
-		params[strings.TrimSpace(kv[0])] = kv[1]
+		key := strings.TrimSpace(kv[0])
+		val, err := resolveParamValue(kv[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read value for parameter "+key)
+		}
+		params[key] = val
+	}
+	return params, nil
+}
+
+// resolveParamValue returns the value a parameter refers to when it uses the @path or env:VAR sources
+func resolveParamValue(val string) (string, error) {
+	switch {
+	case strings.HasPrefix(val, paramEscape):
+		return strings.TrimPrefix(val, paramEscape), nil
+	case strings.HasPrefix(val, paramFileSource):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(val, paramFileSource))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(val, paramEnvSource):
+		name := strings.TrimPrefix(val, paramEnvSource)
+		envVal, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.New("environment variable " + name + " is not set")
+		}
+		return envVal, nil
+	}
+	return val, nil
+}
+
+// readParamsFile reads a flat map of parameters from a YAML or JSON file. String values can use the same
+// @path and env:VAR sources and \ escape as the params flag
+func readParamsFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read params file "+path)
+	}
+	fileParams := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &fileParams); err != nil {
+		return nil, errors.Wrap(err, "failed to parse params file "+path)
+	}
+	params := map[string]string{}
+	for k, v := range fileParams {
+		var val string
+		switch fv := v.(type) {
+		case nil:
+			continue
+		case string:
+			if val, err = resolveParamValue(fv); err != nil {
+				return nil, errors.Wrap(err, "failed to read value for parameter "+k)
+			}
+		case float64:
+			val = strconv.FormatFloat(fv, 'f', -1, 64)
+		case bool:
+			val = strconv.FormatBool(fv)
+		default:
+			// nested values are passed on as json
+			jsonVal, err := json.Marshal(fv)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read value for parameter "+k)
+			}
+			val = string(jsonVal)
+		}
+		params[k] = val
+	}
+	return params, nil
+}
+
+// paramsFromFlags reads the params-file and params flags. Values set with the params flag override
+// values read from the file
+func paramsFromFlags(flags *pflag.FlagSet) (map[string]string, error) {
+	params := map[string]string{}
+	if paramsFile, err := flags.GetString("params-file"); err == nil && paramsFile != "" {
+		if params, err = readParamsFile(paramsFile); err != nil {
+			return nil, err
+		}
+	}
+	flagParams, err := flags.GetStringArray("params")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	parsedParams, err := parseParams(flagParams)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for k, v := range parsedParams {
+		params[k] = v
+	}
+	return params, nil
+}
+
+// schemaParams reads the parameters described by a plan schema. Plans without a schema take no parameters
+func schemaParams(schema *runtime.RawExtension) (*ServiceParams, error) {
+	params := &ServiceParams{Properties: map[string]map[string]interface{}{}}
+	if schema == nil || len(schema.Raw) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(schema.Raw, params); err != nil {
+		return nil, err
 	}
 	return params, nil
 }
@@ -38,60 +156,122 @@ func isRequired(params ServiceParams, key string) bool {
 	return false
 }
 
-// GetParams - Gets the service parameters (i.e. for provision/bind service) from the params
-//             flag or as a user input
-func GetParams(flagParams []string, params *ServiceParams) (*ServiceParams, error) {
-	parsedParams, err := parseParams(flagParams)
-	if err != nil {
-		return params, errors.WithStack(err)
+// isPassword checks if the schema marks the property as a password so its value is never echoed
+func isPassword(property map[string]interface{}) bool {
+	return property["format"] == "password" || property["display_type"] == "password"
+}
+
+// orderedParamKeys returns the property names with the required ones first, so prompts are always asked in the same order
+func orderedParamKeys(params ServiceParams) []string {
+	var required, optional []string
+	for k := range params.Properties {
+		if isRequired(params, k) {
+			required = append(required, k)
+		} else {
+			optional = append(optional, k)
+		}
 	}
+	sort.Strings(required)
+	sort.Strings(optional)
+	return append(required, optional...)
+}
 
-	if len(parsedParams) > 0 {
-		for k, v := range params.Properties {
-			defaultVal := v["default"]
-			if pVal, ok := parsedParams[k]; !ok && isRequired(*params, k) || isRequired(*params, k) && pVal == "" {
-				if defaultVal != nil {
-					//use default
-					v["value"] = defaultVal
-					continue
-				}
-				return params, errors.New(fmt.Sprintf("missing required parameter %s", k))
+func defaultValue(property map[string]interface{}) (string, bool) {
+	if property["default"] == nil {
+		return "", false
+	}
+	return fmt.Sprint(property["default"]), true
+}
+
+// GetParams - Gets the service parameters (i.e. for provision/bind service) from the values
+//
+//	passed as flags or a params file, or as a user input when interactive is true
+func GetParams(parsedParams map[string]string, params *ServiceParams, interactive bool) (*ServiceParams, error) {
+	if len(parsedParams) > 0 || !interactive {
+		var missing []string
+		for _, k := range orderedParamKeys(*params) {
+			v := params.Properties[k]
+			if v == nil {
+				v = map[string]interface{}{}
+				params.Properties[k] = v
+			}
+			if pVal := parsedParams[k]; pVal != "" {
+				v["value"] = pVal
+				continue
 			}
-			v["value"] = parsedParams[k]
+			if defaultVal, ok := defaultValue(v); ok {
+				//use default
+				v["value"] = defaultVal
+				continue
+			}
+			if isRequired(*params, k) {
+				missing = append(missing, k)
+				continue
+			}
+			v["value"] = ""
+		}
+		if len(missing) == 1 {
+			return params, errors.New(fmt.Sprintf("missing required parameter %s", missing[0]))
+		}
+		if len(missing) > 1 {
+			return params, errors.New(fmt.Sprintf("missing required parameters %s", strings.Join(missing, ", ")))
+		}
+		return params, nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for _, k := range orderedParamKeys(*params) {
+		v := params.Properties[k]
+		if v == nil {
+			v = map[string]interface{}{}
 			params.Properties[k] = v
 		}
-	} else {
-		scanner := bufio.NewScanner(os.Stdin)
-		for k, v := range params.Properties {
-			validInput := false
-			val := ""
-			for validInput == false {
-				questionFormat := "Set value for %s [default value: %s, required: %v]"
-				if v["default"] != nil {
-					fmt.Println(fmt.Sprintf(questionFormat, k, v["default"], isRequired(*params, k)))
-				} else {
-					fmt.Println(fmt.Sprintf(questionFormat, k, "<no default value>", isRequired(*params, k)))
+		password := isPassword(v)
+		defaultVal, hasDefault := defaultValue(v)
+		validInput := false
+		val := ""
+		for validInput == false {
+			questionFormat := "Set value for %s [default value: %s, required: %v]"
+			switch {
+			case hasDefault && password:
+				fmt.Println(fmt.Sprintf(questionFormat, k, "<hidden>", isRequired(*params, k)))
+			case hasDefault:
+				fmt.Println(fmt.Sprintf(questionFormat, k, defaultVal, isRequired(*params, k)))
+			default:
+				fmt.Println(fmt.Sprintf(questionFormat, k, "<no default value>", isRequired(*params, k)))
+			}
+			if password {
+				input, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if err != nil {
+					return params, errors.Wrap(err, "failed to read value for "+k)
+				}
+				val = strings.TrimSpace(string(input))
+			} else {
+				if !scanner.Scan() {
+					return params, errors.New("failed to read value for " + k)
 				}
-				scanner.Scan()
-
 				val = strings.TrimSpace(scanner.Text())
+			}
 
-				if len(val) > 0 {
-					validInput = true
-				}
-				if validInput == false && val == "" && v["default"] != nil {
-					val = v["default"].(string)
-					validInput = true
-				}
-				if validInput == false && val == "" && !isRequired(*params, k) {
-					validInput = true
-				}
-				if validInput == false {
-					fmt.Println("Invalid option for required field.")
-				}
+			if len(val) > 0 {
+				validInput = true
 			}
-			v["value"] = val
-			params.Properties[k] = v
+			if validInput == false && val == "" && hasDefault {
+				val = defaultVal
+				validInput = true
+			}
+			if validInput == false && val == "" && !isRequired(*params, k) {
+				validInput = true
+			}
+			if validInput == false {
+				fmt.Println("Invalid option for required field.")
+			}
+		}
+		v["value"] = val
+		if password {
+			fmt.Println(fmt.Sprintf("Value for %s set", k))
+		} else {
 			fmt.Println(fmt.Sprintf("Value for %s set to: %s", k, val))
 		}
 	}
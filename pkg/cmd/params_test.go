@@ -0,0 +1,99 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+)
+
+func TestGetParams(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Params      func() *cmd.ServiceParams
+		FlagParams  map[string]string
+		ExpectError string
+		Expected    map[string]interface{}
+	}{
+		{
+			Name: "should list all missing required params when not interactive",
+			Params: func() *cmd.ServiceParams {
+				return &cmd.ServiceParams{
+					Required: []string{"ADMIN_PASSWORD", "ADMIN_NAME"},
+					Properties: map[string]map[string]interface{}{
+						"ADMIN_NAME":     {},
+						"ADMIN_PASSWORD": {"format": "password"},
+						"REALM":          {},
+					},
+				}
+			},
+			FlagParams:  map[string]string{},
+			ExpectError: "missing required parameters ADMIN_NAME, ADMIN_PASSWORD",
+		},
+		{
+			Name: "should use defaults for params that are not set",
+			Params: func() *cmd.ServiceParams {
+				return &cmd.ServiceParams{
+					Required: []string{"ADMIN_NAME"},
+					Properties: map[string]map[string]interface{}{
+						"ADMIN_NAME": {"default": "admin"},
+						"REPLICAS":   {"default": float64(2)},
+						"REALM":      {},
+					},
+				}
+			},
+			FlagParams: map[string]string{},
+			Expected:   map[string]interface{}{"ADMIN_NAME": "admin", "REPLICAS": "2", "REALM": ""},
+		},
+		{
+			Name: "should prefer flag values over defaults",
+			Params: func() *cmd.ServiceParams {
+				return &cmd.ServiceParams{
+					Required: []string{"ADMIN_NAME"},
+					Properties: map[string]map[string]interface{}{
+						"ADMIN_NAME": {"default": "admin"},
+						"REALM":      {},
+					},
+				}
+			},
+			FlagParams: map[string]string{"ADMIN_NAME": "test", "REALM": "myrealm"},
+			Expected:   map[string]interface{}{"ADMIN_NAME": "test", "REALM": "myrealm"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			params, err := cmd.GetParams(tc.FlagParams, tc.Params(), false)
+			if tc.ExpectError != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if err.Error() != tc.ExpectError {
+					t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			for k, expected := range tc.Expected {
+				if params.Properties[k]["value"] != expected {
+					t.Fatalf("expected value of %s to be '%v' but got '%v'", k, expected, params.Properties[k]["value"])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// clientProvisionQuotas are the resource quotas provisioning a mobile client counts against
+var clientProvisionQuotas = []v1.ResourceName{"count/serviceinstances.servicecatalog.k8s.io", v1.ResourceSecrets, "count/secrets"}
+
+// checkQuotas returns an error when a resource quota of the namespace leaves no room for one more of the resources
+func checkQuotas(k8Client kubernetes.Interface, namespace string, resources []v1.ResourceName) error {
+	quotas, err := k8Client.CoreV1().ResourceQuotas(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list the resource quotas of the namespace "+namespace)
+	}
+	for _, quota := range quotas.Items {
+		for _, resource := range resources {
+			hard, ok := quota.Status.Hard[resource]
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[resource]
+			if used.Value()+1 > hard.Value() {
+				return errors.New(fmt.Sprintf("the resource quota %s of the namespace %s allows no more %s, %d of %d are used", quota.Name, namespace, resource, used.Value(), hard.Value()))
+			}
+		}
+	}
+	return nil
+}
+
+// preflight checks the quotas of the namespace leave room for the mobile client before anything is created.
+// Whether the user is allowed to create it is checked by CheckCommandAccess
+func (cc *ClientCmd) preflight(namespace string) error {
+	return errors.Wrap(checkQuotas(cc.k8Client, namespace, clientProvisionQuotas), "can not create the mobile client")
+}
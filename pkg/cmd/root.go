@@ -16,13 +16,16 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 func NewRootCmd() *cobra.Command {
@@ -34,6 +37,9 @@ func NewRootCmd() *cobra.Command {
 	root.PersistentFlags().String("namespace", "", "--namespace=myproject")
 	root.PersistentFlags().StringP("output", "o", "table", "-o=json -o=template")
 	root.PersistentFlags().BoolP("quiet", "q", false, "-q all non essential output will be stopped")
+	root.PersistentFlags().Bool("non-interactive", false, "--non-interactive never prompt for input, fail with the list of missing required values instead. This is the default when stdin is not a terminal")
+	root.PersistentFlags().String("dry-run", "", "--dry-run[=client|server] print the objects the command would create, delete or patch as YAML, or JSON with -o json, instead of changing them. With server they are also validated by the API server without being persisted")
+	root.PersistentFlags().Lookup("dry-run").NoOptDefVal = dryRunClient
 	cobra.OnInitialize(initConfig)
 	return root
 }
@@ -71,6 +77,14 @@ func currentNamespace(flags *pflag.FlagSet) (string, error) {
 	return ns, err
 }
 
+// isInteractive checks whether the user can be prompted for input
+func isInteractive(flags *pflag.FlagSet) bool {
+	if nonInteractive, err := flags.GetBool("non-interactive"); err == nil && nonInteractive {
+		return false
+	}
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
 func outputType(flags *pflag.FlagSet) string {
 	o, _ := flags.GetString("output")
 
@@ -79,3 +93,29 @@ func outputType(flags *pflag.FlagSet) string {
 	}
 	return o
 }
+
+// newWaiter returns a waiter for the named object using the --timeout and --quiet flags
+func newWaiter(flags *pflag.FlagSet, name string, watchFunc wait.WatchFunc) (*wait.Waiter, error) {
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return wait.New(name, watchFunc, timeout, progressOut(flags)), nil
+}
+
+// newPoller returns a poller using the --timeout and --quiet flags
+func newPoller(flags *pflag.FlagSet) (*wait.Poller, error) {
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return wait.NewPoller(wait.DefaultPollInterval, timeout, progressOut(flags)), nil
+}
+
+// progressOut is where waits report progress, nil when --quiet is set
+func progressOut(flags *pflag.FlagSet) io.Writer {
+	if quiet, err := flags.GetBool("quiet"); err == nil && quiet {
+		return nil
+	}
+	return os.Stdout
+}
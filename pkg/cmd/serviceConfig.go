@@ -175,7 +175,7 @@ func (scc *ServiceConfigCmd) CreateServiceConfigCmd() *cobra.Command {
 		Use:   "serviceconfig",
 		Short: "create a new service config",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return nil
+			return noDryRun(cmd)
 		},
 	}
 	return cmd
@@ -186,7 +186,7 @@ func (scc *ServiceConfigCmd) DeleteServiceConfigCmd() *cobra.Command {
 		Use:   "serviceconfig",
 		Short: "delete a service config",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return nil
+			return noDryRun(cmd)
 		},
 	}
 	return cmd
@@ -0,0 +1,66 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/spf13/cobra"
+	kFake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceConfigCmd_DryRun(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Parent      string
+		Command     func(scc *cmd.ServiceConfigCmd) *cobra.Command
+		ExpectError string
+	}{
+		{
+			Name:        "test create serviceconfig rejects --dry-run",
+			Parent:      "create",
+			Command:     (*cmd.ServiceConfigCmd).CreateServiceConfigCmd,
+			ExpectError: "create serviceconfig does not support --dry-run",
+		},
+		{
+			Name:        "test delete serviceconfig rejects --dry-run",
+			Parent:      "delete",
+			Command:     (*cmd.ServiceConfigCmd).DeleteServiceConfigCmd,
+			ExpectError: "delete serviceconfig does not support --dry-run",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			k8Client := &kFake.Clientset{}
+			root := cmd.NewRootCmd()
+			parent := &cobra.Command{Use: tc.Parent}
+			root.AddCommand(parent)
+			serviceConfigCmd := tc.Command(cmd.NewServiceConfigCommand(k8Client))
+			parent.AddCommand(serviceConfigCmd)
+			if err := serviceConfigCmd.ParseFlags([]string{"--namespace=test", "--dry-run"}); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := serviceConfigCmd.RunE(serviceConfigCmd, []string{})
+			if err == nil || err.Error() != tc.ExpectError {
+				t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+			}
+			if actions := k8Client.Actions(); len(actions) != 0 {
+				t.Fatalf("expected no calls to the API server but got %v", actions)
+			}
+		})
+	}
+}
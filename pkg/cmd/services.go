@@ -27,32 +27,45 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
 
 	"sort"
+	"time"
 
 	"github.com/aerogear/mobile-cli/pkg/cmd/output"
-	"k8s.io/apimachinery/pkg/watch"
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
 )
 
 type ServicesCmd struct {
 	*BaseCmd
-	scClient versioned.Interface
-	k8Client kubernetes.Interface
+	scClient  versioned.Interface
+	k8Client  kubernetes.Interface
+	workloads *workloads
 }
 
-func NewServicesCmd(scClient versioned.Interface, k8Client kubernetes.Interface, out io.Writer) *ServicesCmd {
-	return &ServicesCmd{scClient: scClient, k8Client: k8Client, BaseCmd: &BaseCmd{Out: output.NewRenderer(out)}}
+func NewServicesCmd(scClient versioned.Interface, k8Client kubernetes.Interface, dcClient DeploymentConfigInterface, out io.Writer) *ServicesCmd {
+	return &ServicesCmd{
+		scClient:  scClient,
+		k8Client:  k8Client,
+		workloads: &workloads{k8Client: k8Client, dcClient: dcClient},
+		BaseCmd:   &BaseCmd{Out: output.NewRenderer(out)},
+	}
 }
 
 func (sc *ServicesCmd) ListServicesCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "services",
 		Short: "get mobile aware services that can be provisioned to your namespace",
-		Long:  `get services allows you to get a list of services that can be provisioned in your namespace.`,
+		Long:  `get services allows you to get a list of services that can be provisioned in your namespace, including the types of mobile client.`,
 		Example: `  mobile get services --namespace=myproject 
+  mobile get services -o wide
   kubectl plugin mobile get services
   oc plugin mobile get services`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -71,10 +84,8 @@ func (sc *ServicesCmd) ListServicesCmd() *cobra.Command {
 			if !all {
 				tempList := &v1beta1.ClusterServiceClassList{}
 				for _, item := range scList.Items {
-					for _, tag := range item.Spec.Tags {
-						if tag == "mobile-service" {
-							tempList.Items = append(tempList.Items, item)
-						}
+					if hasTag(item, "mobile-service") || hasTag(item, mobileClientTypeTag) {
+						tempList.Items = append(tempList.Items, item)
 					}
 				}
 				scList = tempList
@@ -86,46 +97,66 @@ func (sc *ServicesCmd) ListServicesCmd() *cobra.Command {
 		},
 	}
 
-	// add our table output renderer
+	// add our table output renderers
 	sc.Out.AddRenderer("list"+cmd.Name(), "table", func(writer io.Writer, serviceClasses interface{}) error {
 		scL := serviceClasses.(*v1beta1.ClusterServiceClassList)
+		plans, err := sc.scClient.ServicecatalogV1beta1().ClusterServicePlans().List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
 		var data [][]string
 		for _, item := range scL.Items {
-			extMeta := item.Spec.ExternalMetadata.Raw
-			extServiceClass := map[string]interface{}{}
-			if err := json.Unmarshal(extMeta, &extServiceClass); err != nil {
+			serviceName, integrations, err := serviceNameAndIntegrations(item)
+			if err != nil {
 				return err
 			}
-			serviceName := ""
-			integrations := ""
-
-			clusterServicePlan, err := findServicePlanByNameAndClass(sc.scClient, "default", item.Name)
+			clusterServicePlan := servicePlanFromList(plans.Items, defaultServicePlan, item.Name)
+			if clusterServicePlan == nil {
+				return errors.New("failed to find serviceplan associated with the serviceclass " + item.Name)
+			}
+			createParams, err := planParamNames(clusterServicePlan)
 			if err != nil {
 				return err
 			}
-
-			params := &ServiceParams{}
-			if err := json.Unmarshal(clusterServicePlan.Spec.ServiceInstanceCreateParameterSchema.Raw, &params); err != nil {
+			data = append(data, []string{serviceName, integrations, strings.Join(createParams, ",\n")})
+		}
+		table := tablewriter.NewWriter(writer)
+		table.AppendBulk(data)
+		table.SetHeader([]string{"Name", "Integrations", "Parameters"})
+		table.Render()
+		return nil
+	})
+	sc.Out.AddRenderer("list"+cmd.Name(), "wide", func(writer io.Writer, serviceClasses interface{}) error {
+		scL := serviceClasses.(*v1beta1.ClusterServiceClassList)
+		plans, err := sc.scClient.ServicecatalogV1beta1().ClusterServicePlans().List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		var data [][]string
+		for _, item := range scL.Items {
+			serviceName, integrations, err := serviceNameAndIntegrations(item)
+			if err != nil {
 				return err
 			}
-			var createParams []string
-			for k := range params.Properties {
-				createParams = append(createParams, k)
+			classPlans := servicePlansForClass(plans.Items, item.Name)
+			if len(classPlans) == 0 {
+				data = append(data, []string{serviceName, integrations, "", ""})
+				continue
 			}
-
-			sort.Strings(createParams)
-			if v, ok := extServiceClass["serviceName"].(string); ok {
-				serviceName = v
-			}
-			if v, ok := extServiceClass["integrations"].(string); ok {
-				integrations = v
+			for i, plan := range classPlans {
+				createParams, err := planParamNames(&plan)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					serviceName, integrations = "", ""
+				}
+				data = append(data, []string{serviceName, integrations, plan.Spec.ExternalName, strings.Join(createParams, ",\n")})
 			}
-
-			data = append(data, []string{serviceName, integrations, strings.Join(createParams, ",\n")})
 		}
 		table := tablewriter.NewWriter(writer)
 		table.AppendBulk(data)
-		table.SetHeader([]string{"Name", "Integrations", "Parameters"})
+		table.SetHeader([]string{"Name", "Integrations", "Plan", "Parameters"})
 		table.Render()
 		return nil
 	})
@@ -133,6 +164,199 @@ func (sc *ServicesCmd) ListServicesCmd() *cobra.Command {
 	return cmd
 }
 
+// DescribeServiceCmd builds the describe service command
+func (sc *ServicesCmd) DescribeServiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service <serviceName>",
+		Short: "describe a mobile aware service, its plans and their parameters",
+		Long: `describe service shows the details of a service that can be provisioned to your namespace, the parameters each of its plans accepts
+when provisioning and binding and the services it can integrate with.
+Run the "mobile get services" command from this tool to see which services are available.`,
+		Example: `  mobile describe service <serviceName> --namespace=myproject
+  kubectl plugin mobile describe service <serviceName>
+  oc plugin mobile describe service <serviceName>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			serviceClass, err := findServiceClassByName(sc.scClient, args[0])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			plans, err := sc.scClient.ServicecatalogV1beta1().ClusterServicePlans().List(metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list service plans")
+			}
+			description, err := describeServiceClass(*serviceClass, servicePlansForClass(plans.Items, serviceClass.Name))
+			if err != nil {
+				return errors.Wrap(err, "failed to describe service "+args[0])
+			}
+			outType := outputType(cmd.Flags())
+			if err := sc.Out.Render("describe"+cmd.Name(), outType, description); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "service", outType))
+			}
+			return nil
+		},
+	}
+	sc.Out.AddRenderer("describe"+cmd.Name(), "table", func(writer io.Writer, serviceDescription interface{}) error {
+		description := serviceDescription.(*ServiceDescription)
+		fmt.Fprintf(writer, "Name:\t\t%s\n", description.Name)
+		fmt.Fprintf(writer, "Display Name:\t%s\n", description.DisplayName)
+		fmt.Fprintf(writer, "Description:\t%s\n", description.Description)
+		fmt.Fprintf(writer, "Provider:\t%s\n", description.ProviderDisplayName)
+		fmt.Fprintf(writer, "Documentation:\t%s\n", description.DocumentationURL)
+		fmt.Fprintf(writer, "Dependencies:\t%s\n", strings.Join(description.Dependencies, ", "))
+		fmt.Fprintf(writer, "Integrations:\t%s\n", strings.Join(description.Integrations, ", "))
+		for _, plan := range description.Plans {
+			fmt.Fprintf(writer, "\nPlan: %s\n", plan.Name)
+			if plan.Description != "" {
+				fmt.Fprintf(writer, "%s\n", plan.Description)
+			}
+			for _, section := range []struct {
+				title  string
+				params []ServiceParamDescription
+			}{{"Create Parameters", plan.CreateParameters}, {"Bind Parameters", plan.BindParameters}} {
+				if len(section.params) == 0 {
+					fmt.Fprintf(writer, "%s: none\n", section.title)
+					continue
+				}
+				fmt.Fprintf(writer, "%s:\n", section.title)
+				var data [][]string
+				for _, p := range section.params {
+					data = append(data, []string{p.Name, p.Type, p.Default, fmt.Sprintf("%v", p.Required), p.Description})
+				}
+				table := tablewriter.NewWriter(writer)
+				table.AppendBulk(data)
+				table.SetHeader([]string{"Name", "Type", "Default", "Required", "Description"})
+				table.Render()
+			}
+		}
+		return nil
+	})
+	return cmd
+}
+
+// describeServiceClass builds the description of a serviceclass and its plans
+func describeServiceClass(serviceClass v1beta1.ClusterServiceClass, plans []v1beta1.ClusterServicePlan) (*ServiceDescription, error) {
+	var extMeta ExternalServiceMetaData
+	if serviceClass.Spec.ExternalMetadata != nil {
+		if err := json.Unmarshal(serviceClass.Spec.ExternalMetadata.Raw, &extMeta); err != nil {
+			return nil, err
+		}
+	}
+	_, integrations, err := serviceNameAndIntegrations(serviceClass)
+	if err != nil {
+		return nil, err
+	}
+	description := &ServiceDescription{
+		Name:                extMeta.ServiceName,
+		DisplayName:         extMeta.DisplayName,
+		Description:         serviceClass.Spec.Description,
+		DocumentationURL:    extMeta.DocumentationURL,
+		ProviderDisplayName: extMeta.ProviderDisplayName,
+		Dependencies:        extMeta.Dependencies,
+		Integrations:        []string{},
+		Plans:               []ServicePlanDescription{},
+	}
+	for _, i := range strings.Split(integrations, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			description.Integrations = append(description.Integrations, i)
+		}
+	}
+	// fall back to the integrations we know about when the service does not declare them
+	if len(description.Integrations) == 0 {
+		for _, i := range capabilities[extMeta.ServiceName]["integrations"] {
+			if i != "" {
+				description.Integrations = append(description.Integrations, i)
+			}
+		}
+	}
+	for _, plan := range plans {
+		createParams, err := describeParams(plan.Spec.ServiceInstanceCreateParameterSchema)
+		if err != nil {
+			return nil, err
+		}
+		bindParams, err := describeParams(plan.Spec.ServiceBindingCreateParameterSchema)
+		if err != nil {
+			return nil, err
+		}
+		description.Plans = append(description.Plans, ServicePlanDescription{
+			Name:             plan.Spec.ExternalName,
+			Description:      plan.Spec.Description,
+			Free:             plan.Spec.Free,
+			CreateParameters: createParams,
+			BindParameters:   bindParams,
+		})
+	}
+	return description, nil
+}
+
+// describeParams lists the parameters of a plan schema, required parameters first
+func describeParams(schema *runtime.RawExtension) ([]ServiceParamDescription, error) {
+	params, err := schemaParams(schema)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := []ServiceParamDescription{}
+	for _, k := range orderedParamKeys(*params) {
+		property := params.Properties[k]
+		description := ServiceParamDescription{
+			Name:     k,
+			Required: isRequired(*params, k),
+		}
+		if v, ok := property["type"].(string); ok {
+			description.Type = v
+		}
+		if v, ok := defaultValue(property); ok {
+			description.Default = v
+			if isPassword(property) {
+				description.Default = "<hidden>"
+			}
+		}
+		if v, ok := property["description"].(string); ok {
+			description.Description = v
+		} else if v, ok := property["title"].(string); ok {
+			description.Description = v
+		}
+		descriptions = append(descriptions, description)
+	}
+	return descriptions, nil
+}
+
+// serviceNameAndIntegrations reads the mobile service name and the services it integrates with from the external metadata
+func serviceNameAndIntegrations(serviceClass v1beta1.ClusterServiceClass) (string, string, error) {
+	serviceName := ""
+	integrations := ""
+	if serviceClass.Spec.ExternalMetadata == nil {
+		return serviceName, integrations, nil
+	}
+	extServiceClass := map[string]interface{}{}
+	if err := json.Unmarshal(serviceClass.Spec.ExternalMetadata.Raw, &extServiceClass); err != nil {
+		return serviceName, integrations, err
+	}
+	if v, ok := extServiceClass["serviceName"].(string); ok {
+		serviceName = v
+	}
+	if v, ok := extServiceClass["integrations"].(string); ok {
+		integrations = v
+	}
+	return serviceName, integrations, nil
+}
+
+// planParamNames returns the sorted names of the parameters accepted when provisioning the plan
+func planParamNames(plan *v1beta1.ClusterServicePlan) ([]string, error) {
+	var names []string
+	params, err := schemaParams(plan.Spec.ServiceInstanceCreateParameterSchema)
+	if err != nil {
+		return nil, err
+	}
+	for k := range params.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func findServiceClassByName(scClient versioned.Interface, name string) (*v1beta1.ClusterServiceClass, error) {
 	mobileServices, err := scClient.ServicecatalogV1beta1().ClusterServiceClasses().List(metav1.ListOptions{})
 	if err != nil {
@@ -159,18 +383,60 @@ func findServiceClassByName(scClient versioned.Interface, name string) (*v1beta1
 	return nil, errors.New("failed to find serviceclass with name: " + name)
 }
 
+// defaultServicePlan is the plan used when no plan is asked for
+const defaultServicePlan = "default"
+
 func findServicePlanByNameAndClass(scClient versioned.Interface, planName, serviceClassName string) (*v1beta1.ClusterServicePlan, error) {
 	plans, err := scClient.ServicecatalogV1beta1().ClusterServicePlans().List(metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
-	for _, item := range plans.Items {
+	if plan := servicePlanFromList(plans.Items, planName, serviceClassName); plan != nil {
+		return plan, nil
+	}
+	if planName != defaultServicePlan {
+		var available []string
+		for _, p := range servicePlansForClass(plans.Items, serviceClassName) {
+			available = append(available, p.Spec.ExternalName)
+		}
+		return nil, errors.New(fmt.Sprintf("failed to find serviceplan %s associated with the serviceclass %s. Available plans are %s", planName, serviceClassName, strings.Join(available, ",")))
+	}
+	return nil, errors.New("failed to find serviceplan associated with the serviceclass " + serviceClassName)
+}
+
+func servicePlanFromList(plans []v1beta1.ClusterServicePlan, planName, serviceClassName string) *v1beta1.ClusterServicePlan {
+	for _, item := range plans {
 		if item.Spec.ClusterServiceClassRef.Name == serviceClassName && item.Spec.ExternalName == planName {
-			return &item, nil
+			return &item
 		}
 	}
+	return nil
+}
 
-	return nil, errors.New("failed to find serviceplan associated with the serviceclass " + serviceClassName)
+// servicePlansForClass returns the plans of the serviceclass sorted by name
+func servicePlansForClass(plans []v1beta1.ClusterServicePlan, serviceClassName string) []v1beta1.ClusterServicePlan {
+	var classPlans []v1beta1.ClusterServicePlan
+	for _, item := range plans {
+		if item.Spec.ClusterServiceClassRef.Name == serviceClassName {
+			classPlans = append(classPlans, item)
+		}
+	}
+	sort.Slice(classPlans, func(i, j int) bool {
+		return classPlans[i].Spec.ExternalName < classPlans[j].Spec.ExternalName
+	})
+	return classPlans
+}
+
+// findServicePlanForInstance returns the plan the service instance was provisioned with
+func findServicePlanForInstance(scClient versioned.Interface, si *v1beta1.ServiceInstance, serviceClassName string) (*v1beta1.ClusterServicePlan, error) {
+	if si.Spec.ClusterServicePlanRef != nil && si.Spec.ClusterServicePlanRef.Name != "" {
+		return scClient.ServicecatalogV1beta1().ClusterServicePlans().Get(si.Spec.ClusterServicePlanRef.Name, metav1.GetOptions{})
+	}
+	planName := si.Spec.ClusterServicePlanExternalName
+	if planName == "" {
+		planName = defaultServicePlan
+	}
+	return findServicePlanByNameAndClass(scClient, planName, serviceClassName)
 }
 
 func (sc *ServicesCmd) CreateServiceInstanceCmd() *cobra.Command {
@@ -180,6 +446,7 @@ func (sc *ServicesCmd) CreateServiceInstanceCmd() *cobra.Command {
 		Long: `create service instance allows you to create a running instance of a service in your namespace. 
 Run the "mobile get services" command from this tool to see which services are available for provisioning.`,
 		Example: `  mobile create serviceinstance <serviceName> --namespace=myproject 
+  mobile create serviceinstance <serviceName> --plan=<planName> --namespace=myproject
   kubectl plugin mobile create serviceinstance <serviceName>
   oc plugin mobile create serviceinstance <serviceName>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -199,62 +466,224 @@ Run the "mobile get services" command from this tool to see which services are a
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			clusterServicePlan, err := findServicePlanByNameAndClass(sc.scClient, "default", clusterServiceClass.Name)
+			planName, err := cmd.PersistentFlags().GetString("plan")
 			if err != nil {
 				return errors.WithStack(err)
 			}
-
-			instParams := &ServiceParams{}
-
-			if err := json.Unmarshal(clusterServicePlan.Spec.ServiceInstanceCreateParameterSchema.Raw, instParams); err != nil {
+			clusterServicePlan, err := findServicePlanByNameAndClass(sc.scClient, planName, clusterServiceClass.Name)
+			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			flagParams, err := cmd.Flags().GetStringArray("params")
+			flagParams, err := paramsFromFlags(cmd.Flags())
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
 			// Get provision parameters value from user input
-			instParams, err = GetParams(flagParams, instParams)
+			parameters, err := instanceParams(clusterServicePlan, flagParams, isInteractive(cmd.Flags()))
 			if err != nil {
-				return errors.WithStack(err)
+				return err
 			}
 
-			validServiceName := clusterServiceClass.Spec.ExternalName
-			extMeta := clusterServiceClass.Spec.ExternalMetadata.Raw
 			var extServiceClass ExternalServiceMetaData
-			if err := json.Unmarshal(extMeta, &extServiceClass); err != nil {
+			if err := json.Unmarshal(clusterServiceClass.Spec.ExternalMetadata.Raw, &extServiceClass); err != nil {
 				return errors.WithStack(err)
 			}
 
-			si := buildServiceInstance(ns, validServiceName+"-", validServiceName+"-", *clusterServiceClass)
-
-			if _, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Create(&si); err != nil {
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				pSecret, err := paramsSecret(clusterServiceClass.Spec.ExternalName, parameters)
+				if err != nil {
+					return err
+				}
+				secretName, err := dryRun.create(sc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", &pSecret)
+				if err != nil {
+					return err
+				}
+				si := buildServiceInstance(ns, clusterServiceClass.Spec.ExternalName+"-", secretName, *clusterServiceClass, clusterServicePlan.Spec.ExternalName)
+				if _, err := dryRun.create(sc.scClient.ServicecatalogV1beta1().RESTClient(), serviceInstancesResource, "ServiceInstance", &si); err != nil {
+					return err
+				}
+				return dryRun.render(sc.Out, cmd.Flags())
+			}
+			noWait, err := cmd.PersistentFlags().GetBool("no-wait")
+			if err != nil {
 				return errors.WithStack(err)
 			}
+			// the objects created are deleted again if the service fails to provision
+			steps := newJournal(cmd.Flags())
+			created, err := sc.provision(ns, clusterServiceClass, clusterServicePlan.Spec.ExternalName, parameters, steps)
+			if err != nil {
+				return steps.finish(err)
+			}
 			fmt.Println("creating service")
-			pSecret := v1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: validServiceName + "-" + "params",
-				},
+
+			if noWait {
+				return steps.finish(nil)
+			}
+			waiter, err := newWaiter(cmd.Flags(), created.Name, sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
+			if err != nil {
+				return steps.finish(err)
 			}
-			pSecret.Data = map[string][]byte{}
-			parameters := map[string]string{}
+			if err := waiter.Until(wait.ServiceInstanceReady(0)); err != nil {
+				return steps.finish(errors.Wrap(err, "Failed to provision "+extServiceClass.ServiceName))
+			}
+			return steps.finish(nil)
+		},
+	}
+	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the service is fully provisioned")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the service to be provisioned before giving up. 0 waits forever")
+	cmd.PersistentFlags().String("plan", defaultServicePlan, "--plan=<planName> the service plan to provision. mobile get services -o wide lists the available plans")
+	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set the parameters  needed to set up the service programatically rather than being prompted for them: -p PARAM1=val -p PARAM2=val2. Values can be read from a file with -p PARAM=@file or from the environment with -p PARAM=env:VAR, start the value with \\ to use it as it is: -p PARAM=\\@value")
+	cmd.PersistentFlags().String("params-file", "", "--params-file=params.yaml read the parameters from a YAML or JSON file. Values set with --params override the values in the file")
+	addNoRollbackFlag(cmd)
+	return cmd
+}
 
-			for k, v := range instParams.Properties {
-				if v, ok := v["value"]; ok && v != nil {
-					parameters[k] = v.(string)
-				}
+// instanceParams resolves the provision parameters of the plan from the given values, prompting for the missing ones when interactive
+func instanceParams(plan *v1beta1.ClusterServicePlan, given map[string]string, interactive bool) (map[string]string, error) {
+	instParams, err := schemaParams(plan.Spec.ServiceInstanceCreateParameterSchema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	instParams, err = GetParams(given, instParams, interactive)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	parameters := map[string]string{}
+	for k, v := range instParams.Properties {
+		if v, ok := v["value"]; ok && v != nil {
+			parameters[k] = v.(string)
+		}
+	}
+	return parameters, nil
+}
+
+// paramsSecret builds the secret holding the provision parameters of an instance of the service
+func paramsSecret(serviceName string, parameters map[string]string) (v1.Secret, error) {
+	secretData, err := json.Marshal(parameters)
+	if err != nil {
+		return v1.Secret{}, errors.WithStack(err)
+	}
+	return v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: serviceName + "-params-",
+		},
+		Data: map[string][]byte{"parameters": secretData},
+	}, nil
+}
+
+// provision creates an instance of the service class with the given plan and parameters, recording the objects created in steps
+func (sc *ServicesCmd) provision(ns string, clusterServiceClass *v1beta1.ClusterServiceClass, planName string, parameters map[string]string, steps *journal) (*v1beta1.ServiceInstance, error) {
+	pSecret, err := paramsSecret(clusterServiceClass.Spec.ExternalName, parameters)
+	if err != nil {
+		return nil, err
+	}
+	// the params secret is created first so the instance can reference its generated name
+	secrets := sc.k8Client.CoreV1().Secrets(ns)
+	var createdSecret *v1.Secret
+	err = steps.do("secret", func() (string, error) {
+		var err error
+		if createdSecret, err = secrets.Create(&pSecret); err != nil {
+			return "", errors.WithStack(err)
+		}
+		return createdSecret.Name, nil
+	}, func(name string) error {
+		return secrets.Delete(name, &metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	si := buildServiceInstance(ns, clusterServiceClass.Spec.ExternalName+"-", createdSecret.Name, *clusterServiceClass, planName)
+	instances := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns)
+	var created *v1beta1.ServiceInstance
+	err = steps.do("service instance", func() (string, error) {
+		var err error
+		if created, err = instances.Create(&si); err != nil {
+			return "", errors.WithStack(err)
+		}
+		return created.Name, nil
+	}, func(name string) error {
+		return instances.Delete(name, &metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (sc *ServicesCmd) UpdateServiceInstanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serviceinstance <serviceInstanceID>",
+		Short: `update the parameters or plan of a provisioned service instance`,
+		Long: `update serviceinstance allows you to change the parameters or the plan of a service instance in your namespace.
+Only the parameters passed are changed, the other parameters keep their current values.
+Run the "mobile describe service" command from this tool to see which parameters can be updated.`,
+		Example: `  mobile update serviceinstance <serviceInstanceID> -p PARAM1=val --namespace=myproject
+  mobile update serviceinstance <serviceInstanceID> --params-file=params.yaml
+  mobile update serviceinstance <serviceInstanceID> --plan=<planName>
+  kubectl plugin mobile update serviceinstance <serviceInstanceID> -p PARAM1=val
+  oc plugin mobile update serviceinstance <serviceInstanceID> -p PARAM1=val`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			sid := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
 			}
-			secretData, err := json.Marshal(parameters)
+			si, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Get(sid, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get service instance "+sid)
+			}
+			if si.Spec.ClusterServiceClassRef == nil {
+				return errors.New("the service instance " + sid + " has not been resolved to a serviceclass yet")
+			}
+			serviceClassName := si.Spec.ClusterServiceClassRef.Name
+
+			planName, err := cmd.PersistentFlags().GetString("plan")
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			pSecret.Data["parameters"] = secretData
-			if _, err := sc.k8Client.CoreV1().Secrets(ns).Create(&pSecret); err != nil {
+			// an empty plan keeps the plan the instance was provisioned with
+			var clusterServicePlan *v1beta1.ClusterServicePlan
+			planChanged := planName != ""
+			if planChanged {
+				clusterServicePlan, err = findServicePlanByNameAndClass(sc.scClient, planName, serviceClassName)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			} else {
+				clusterServicePlan, err = findServicePlanForInstance(sc.scClient, si, serviceClassName)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			flagParams, err := paramsFromFlags(cmd.Flags())
+			if err != nil {
 				return errors.WithStack(err)
 			}
+			if len(flagParams) == 0 && !planChanged {
+				return errors.New("nothing to update. Set the parameters to change with --params or --params-file, or the new plan with --plan")
+			}
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			updated, err := sc.updateInstance(ns, si, clusterServicePlan, planChanged, flagParams, dryRun)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				return dryRun.render(sc.Out, cmd.Flags())
+			}
+			fmt.Println("updating service")
 
 			noWait, err := cmd.PersistentFlags().GetBool("no-wait")
 			if err != nil {
@@ -263,58 +692,176 @@ Run the "mobile get services" command from this tool to see which services are a
 			if noWait {
 				return nil
 			}
-			timeout := int64(10 * 60) // ten minutes
-			w, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch(metav1.ListOptions{TimeoutSeconds: &timeout})
+			waiter, err := newWaiter(cmd.Flags(), updated.Name, sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
 			if err != nil {
-				return errors.WithStack(err)
+				return err
 			}
-			for {
-				select {
-				case msg, ok := <-w.ResultChan():
-					if !ok {
-						fmt.Println("Timedout waiting. It seems to be taking a long time for the service to provision. Your service may still be provisioning.")
-						return nil
-					}
-					o := msg.Object.(*v1beta1.ServiceInstance)
-					switch msg.Type {
-					case watch.Error:
-						w.Stop()
-						return errors.New("unexpected error watching ServiceInstance " + err.Error())
-					case watch.Modified:
-						for _, c := range o.Status.Conditions {
-							fmt.Println("status: " + c.Message)
-							if c.Type == "Ready" && c.Status == "True" {
-								w.Stop()
-								return nil
-							}
-
-							if c.Type == "Failed" {
-								w.Stop()
-								return errors.New("Failed to provision " + extServiceClass.ServiceName + ". " + c.Message)
-							}
-						}
-					}
-				}
+			if err := waiter.Until(wait.ServiceInstanceReady(updated.Generation)); err != nil {
+				return errors.Wrap(err, "Failed to update "+sid)
 			}
+			return nil
 		},
 	}
-	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the service is fully provisioned")
-	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set the parameters  needed to set up the service programatically rather than being prompted for them: -p PARAM1=val -p PARAM2=val2")
+	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the service is updated")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the service to be updated before giving up. 0 waits forever")
+	cmd.PersistentFlags().String("plan", "", "--plan=<planName> change the service instance to the given plan. mobile get services -o wide lists the available plans")
+	cmd.PersistentFlags().StringArrayP("params", "p", []string{}, "set the parameters to change: -p PARAM1=val -p PARAM2=val2. Values can be read from a file with -p PARAM=@file or from the environment with -p PARAM=env:VAR, start the value with \\ to use it as it is: -p PARAM=\\@value")
+	cmd.PersistentFlags().String("params-file", "", "--params-file=params.yaml read the parameters to change from a YAML or JSON file. Values set with --params override the values in the file")
 	return cmd
 }
 
+// updateInstance changes the plan of the service instance when planChanged is set and merges the parameters into its parameters secret.
+// With a dry run the changes are recorded instead and the instance is returned unchanged
+func (sc *ServicesCmd) updateInstance(ns string, si *v1beta1.ServiceInstance, plan *v1beta1.ClusterServicePlan, planChanged bool, params map[string]string, dryRun *dryRun) (*v1beta1.ServiceInstance, error) {
+	parametersFrom := len(si.Spec.ParametersFrom)
+	if len(params) > 0 {
+		updateParams, err := schemaParams(plan.Spec.ServiceInstanceUpdateParameterSchema)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := validateUpdateParams(params, updateParams, plan.Spec.ExternalName); err != nil {
+			return nil, err
+		}
+		secretName, secretKey := paramsSecretRef(si)
+		if err := sc.updateParamsSecret(ns, secretName, secretKey, params, dryRun); err != nil {
+			return nil, err
+		}
+	}
+	if planChanged {
+		si.Spec.ClusterServicePlanExternalName = plan.Spec.ExternalName
+		si.Spec.ClusterServicePlanName = ""
+		si.Spec.ClusterServicePlanRef = nil
+	}
+	// the broker is only called again when the spec changes, so bump the update requests for parameter only changes
+	si.Spec.UpdateRequests++
+	if dryRun != nil {
+		spec := map[string]interface{}{"updateRequests": si.Spec.UpdateRequests}
+		if planChanged {
+			spec["clusterServicePlanExternalName"] = plan.Spec.ExternalName
+			spec["clusterServicePlanName"] = nil
+			spec["clusterServicePlanRef"] = nil
+		}
+		if len(si.Spec.ParametersFrom) != parametersFrom {
+			spec["parametersFrom"] = si.Spec.ParametersFrom
+		}
+		dryRun.patch("ServiceInstance", si.Name, map[string]interface{}{"spec": spec})
+		return si, nil
+	}
+	updated, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Update(si)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update service instance "+si.Name)
+	}
+	return updated, nil
+}
+
+// validateUpdateParams checks the parameters are declared by the update schema of the plan and match any allowed values
+func validateUpdateParams(params map[string]string, schema *ServiceParams, planName string) error {
+	if len(schema.Properties) == 0 {
+		return errors.New("the plan " + planName + " does not allow any parameters to be updated")
+	}
+	var allowed []string
+	for k := range schema.Properties {
+		allowed = append(allowed, k)
+	}
+	sort.Strings(allowed)
+	for k, v := range params {
+		property, ok := schema.Properties[k]
+		if !ok {
+			return errors.New(fmt.Sprintf("unknown parameter %s for plan %s. Parameters that can be updated are %s", k, planName, strings.Join(allowed, ", ")))
+		}
+		enum, ok := property["enum"].([]interface{})
+		if !ok || len(enum) == 0 {
+			continue
+		}
+		var values []string
+		for _, e := range enum {
+			values = append(values, fmt.Sprint(e))
+		}
+		valid := false
+		for _, e := range values {
+			if e == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.New(fmt.Sprintf("invalid value %s for parameter %s. Allowed values are %s", v, k, strings.Join(values, ", ")))
+		}
+	}
+	return nil
+}
+
+// paramsSecretRef returns the name and key of the secret holding the parameters of the service instance
+func paramsSecretRef(si *v1beta1.ServiceInstance) (string, string) {
+	for _, pf := range si.Spec.ParametersFrom {
+		if pf.SecretKeyRef != nil {
+			return pf.SecretKeyRef.Name, pf.SecretKeyRef.Key
+		}
+	}
+	secretName := si.Name + "-params"
+	si.Spec.ParametersFrom = append(si.Spec.ParametersFrom, v1beta1.ParametersFromSource{
+		SecretKeyRef: &v1beta1.SecretKeyReference{Name: secretName, Key: "parameters"},
+	})
+	return secretName, "parameters"
+}
+
+// updateParamsSecret merges the given parameters into the parameters secret, creating it if it does not exist.
+// With a dry run the change to the secret is recorded instead
+func (sc *ServicesCmd) updateParamsSecret(ns, name, key string, params map[string]string, dryRun *dryRun) error {
+	secret, err := sc.k8Client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get the parameters secret "+name)
+	}
+	exists := err == nil
+	if !exists {
+		secret = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	parameters := map[string]interface{}{}
+	if raw, ok := secret.Data[key]; ok && len(raw) > 0 {
+		if err := json.Unmarshal(raw, &parameters); err != nil {
+			return errors.Wrap(err, "failed to read the parameters from secret "+name)
+		}
+	}
+	for k, v := range params {
+		parameters[k] = v
+	}
+	secretData, err := json.Marshal(parameters)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = secretData
+	if dryRun != nil && exists {
+		dryRun.patch("Secret", name, map[string]interface{}{"data": map[string][]byte{key: secretData}})
+		return nil
+	}
+	if dryRun != nil {
+		_, err := dryRun.create(sc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", secret)
+		return err
+	}
+	if exists {
+		_, err = sc.k8Client.CoreV1().Secrets(ns).Update(secret)
+	} else {
+		_, err = sc.k8Client.CoreV1().Secrets(ns).Create(secret)
+	}
+	return errors.Wrap(err, "failed to save the parameters secret "+name)
+}
+
 func (sc *ServicesCmd) DeleteServiceInstanceCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "serviceinstance <serviceInstanceID>",
 		Short: "deletes a service instance and other objects created when provisioning the services instance, such as pod presets",
 		Long: `delete serviceinstance allows you to delete a service instance and other objects created when provisioning the services instance, such as pod presets. 
+If other services are integrated with the service instance the delete is refused unless --cascade is set, in which case the integrations are deleted too
+and the <service>=enabled label is removed from the pod templates of the Deployments, DeploymentConfigs and StatefulSets they were injected into.
 Run the "mobile get serviceinstances" command from this tool to see which service instances are available for deleting.`,
 		Example: `  mobile delete serviceinstance <serviceInstanceID> --namespace=myproject 
+  mobile delete serviceinstance <serviceInstanceID> --cascade
   kubectl plugin mobile delete serviceinstance <serviceInstanceID>
   oc plugin mobile delete serviceinstance <serviceInstanceID>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			//delete service instance
-			//delete params secret
 			if len(args) != 1 {
 				return cmd.Usage()
 			}
@@ -323,18 +870,242 @@ Run the "mobile get serviceinstances" command from this tool to see which servic
 				return errors.Wrap(err, "failed to get namespace")
 			}
 			sid := args[0]
-			// Retrieve the service instance in full so we can build the secret name
+			// Retrieve the service instance in full so we can find the params secret
 			serviceInstance, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Get(sid, metav1.GetOptions{})
 			if err != nil {
 				return err
 			}
-			if err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Delete(sid, &metav1.DeleteOptions{}); err != nil {
+			cascade, err := cmd.PersistentFlags().GetBool("cascade")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			bindings, presets, err := sc.serviceInstanceDependents(ns, sid)
+			if err != nil {
+				return err
+			}
+			if (len(bindings) > 0 || len(presets) > 0) && !cascade {
+				return errors.New(fmt.Sprintf("the service instance %s is still used by the integrations %s. Delete them first or use --cascade to delete them with the service instance", sid, strings.Join(dependentNames(bindings, presets), ", ")))
+			}
+			dryRun, err := newDryRun(cmd.Flags(), ns)
+			if err != nil {
+				return err
+			}
+			if dryRun != nil {
+				for _, p := range presets {
+					if err := dryRun.delete(sc.k8Client.SettingsV1alpha1().RESTClient(), podPresetsResource, "PodPreset", p.Name); err != nil {
+						return err
+					}
+				}
+				for _, b := range bindings {
+					if err := dryRun.delete(sc.scClient.ServicecatalogV1beta1().RESTClient(), serviceBindingsResource, "ServiceBinding", b.Name); err != nil {
+						return err
+					}
+				}
+				if err := sc.clearTemplateLabels(ns, presets, dryRun); err != nil {
+					return err
+				}
+				if err := dryRun.delete(sc.scClient.ServicecatalogV1beta1().RESTClient(), serviceInstancesResource, "ServiceInstance", sid); err != nil {
+					return err
+				}
+				for _, pf := range serviceInstance.Spec.ParametersFrom {
+					if pf.SecretKeyRef == nil {
+						continue
+					}
+					if err := dryRun.delete(sc.k8Client.CoreV1().RESTClient(), secretsResource, "Secret", pf.SecretKeyRef.Name); err != nil {
+						return err
+					}
+				}
+				return dryRun.render(sc.Out, cmd.Flags())
+			}
+			if len(bindings) > 0 || len(presets) > 0 {
+				for _, p := range presets {
+					if err := sc.k8Client.SettingsV1alpha1().PodPresets(ns).Delete(p.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+						return errors.Wrap(err, "failed to delete pod preset "+p.Name)
+					}
+				}
+				for _, b := range bindings {
+					if err := sc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).Delete(b.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+						return errors.Wrap(err, "failed to delete integration "+b.Name)
+					}
+				}
+				if err := sc.clearTemplateLabels(ns, presets, nil); err != nil {
+					return err
+				}
+			}
+			if err := sc.deprovision(ns, sid, serviceInstance.Spec.ParametersFrom); err != nil {
+				return err
+			}
+
+			noWait, err := cmd.PersistentFlags().GetBool("no-wait")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if noWait {
+				return nil
+			}
+			waiter, err := newWaiter(cmd.Flags(), sid, sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Watch)
+			if err != nil {
 				return err
 			}
-			secretName := serviceInstance.ObjectMeta.GenerateName + "params"
-			return sc.k8Client.CoreV1().Secrets(ns).Delete(secretName, &metav1.DeleteOptions{})
+			waiter.ResourceVersion = serviceInstance.ResourceVersion
+			if err := waiter.Until(wait.ServiceInstanceDeprovisioned()); err != nil {
+				return errors.Wrap(err, "Failed to delete "+sid)
+			}
+			return nil
 		},
 	}
+	cmd.PersistentFlags().Bool("cascade", false, "--cascade will also delete the integrations that use the service instance")
+	cmd.PersistentFlags().Bool("no-wait", false, "--no-wait will cause the command to exit immediately after a successful response instead of waiting until the service is deprovisioned")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait for the service to be deprovisioned before giving up. 0 waits forever")
+	return cmd
+}
+
+// deprovision deletes the named service instance and the parameters secrets it reads from
+func (sc *ServicesCmd) deprovision(ns, name string, parametersFrom []v1beta1.ParametersFromSource) error {
+	if err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Delete(name, &metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	for _, pf := range parametersFrom {
+		if pf.SecretKeyRef == nil {
+			continue
+		}
+		if err := sc.k8Client.CoreV1().Secrets(ns).Delete(pf.SecretKeyRef.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete the parameters secret "+pf.SecretKeyRef.Name)
+		}
+	}
+	return nil
+}
+
+// serviceInstanceDependents returns the bindings to the service instance and the pod presets that inject them
+func (sc *ServicesCmd) serviceInstanceDependents(ns, sid string) ([]v1beta1.ServiceBinding, []kalpha.PodPreset, error) {
+	sbList, err := sc.scClient.ServicecatalogV1beta1().ServiceBindings(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list integrations")
+	}
+	var bindings []v1beta1.ServiceBinding
+	secrets := map[string]bool{}
+	for _, b := range sbList.Items {
+		if b.Spec.ServiceInstanceRef.Name == sid {
+			bindings = append(bindings, b)
+			secrets[b.Spec.SecretName] = true
+		}
+	}
+	if len(bindings) == 0 {
+		return nil, nil, nil
+	}
+	ppList, err := sc.k8Client.SettingsV1alpha1().PodPresets(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list pod presets")
+	}
+	var presets []kalpha.PodPreset
+	for _, p := range ppList.Items {
+		for _, v := range p.Spec.Volumes {
+			if v.Secret != nil && secrets[v.Secret.SecretName] {
+				presets = append(presets, p)
+				break
+			}
+		}
+	}
+	return bindings, presets, nil
+}
+
+// clearTemplateLabels removes the <service>=enabled label selecting the deleted pod presets from the pod templates of the
+// workloads they were injected into, as delete integration --auto-redeploy does. With a dry run the patches are recorded instead
+func (sc *ServicesCmd) clearTemplateLabels(ns string, presets []kalpha.PodPreset, dryRun *dryRun) error {
+	if len(presets) == 0 {
+		return nil
+	}
+	found, err := sc.workloads.list(ns, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, w := range found {
+		cleared := map[string]bool{}
+		for _, p := range presets {
+			provider := p.Labels["service"]
+			if provider == "" || cleared[provider] || w.TemplateLabels[provider] == "" {
+				continue
+			}
+			if !labels.SelectorFromSet(p.Spec.Selector.MatchLabels).Matches(labels.Set(w.TemplateLabels)) {
+				continue
+			}
+			cleared[provider] = true
+			if dryRun != nil {
+				dryRun.patch(w.Kind, w.Name, templateLabelPatch(provider, ""))
+				continue
+			}
+			if err := sc.workloads.setTemplateLabel(ns, w, provider, ""); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("failed to remove the %s label from %s %s", provider, w.Kind, w.Name))
+			}
+		}
+	}
+	return nil
+}
+
+func dependentNames(bindings []v1beta1.ServiceBinding, presets []kalpha.PodPreset) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, b := range bindings {
+		if !seen[b.Name] {
+			seen[b.Name] = true
+			names = append(names, b.Name)
+		}
+	}
+	for _, p := range presets {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WaitServiceInstanceCmd waits for a service instance to reach a condition
+func (sc *ServicesCmd) WaitServiceInstanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serviceinstance <serviceInstanceID>",
+		Short: "wait for a service instance to reach a condition",
+		Long: `wait serviceinstance blocks until the service instance reaches the condition set with --for or the timeout is reached.
+Run the "mobile get serviceinstances" command from this tool to get the service instance ID.`,
+		Example: `  mobile wait serviceinstance <serviceInstanceID> --for=condition=Ready --timeout=5m --namespace=myproject
+  mobile wait serviceinstance <serviceInstanceID> --for=delete
+  kubectl plugin mobile wait serviceinstance <serviceInstanceID>
+  oc plugin mobile wait serviceinstance <serviceInstanceID>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			sid := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			condition, err := waitFor(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			instances := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns)
+			if condition == waitForDelete {
+				getInstance := func() (runtime.Object, error) {
+					return instances.Get(sid, metav1.GetOptions{})
+				}
+				return errors.Wrap(waitForDeletion(cmd.Flags(), sid, getInstance, instances.Watch), "failed waiting for service instance "+sid+" to be deleted")
+			}
+			si, err := instances.Get(sid, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get service instance "+sid)
+			}
+			waiter, err := newWaiter(cmd.Flags(), sid, instances.Watch)
+			if err != nil {
+				return err
+			}
+			ready := wait.ServiceInstanceCondition(v1beta1.ServiceInstanceConditionType(condition), si.Generation)
+			return errors.Wrap(waitFrom(waiter, si, ready), "failed waiting for service instance "+sid+" to be "+condition)
+		},
+	}
+	addWaitFlags(cmd)
+	return cmd
 }
 
 func findServiceInstanceByExternalName(client versioned.Interface, ns, name string) ([]v1beta1.ServiceInstance, error) {
@@ -353,29 +1124,37 @@ func findServiceInstanceByExternalName(client versioned.Interface, ns, name stri
 
 func (sc *ServicesCmd) ListServiceInstCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "serviceinstances <serviceName>",
-		Short: "get a list of provisioned service instances based on the service name.",
-		Long:  `get serviceinstances allows you to get a list of provisioned service instances in your namespace, based on the service name.`,
-		Example: `  mobile get serviceinstances <serviceName> --namespace=myproject 
+		Use:   "serviceinstances [serviceName]",
+		Short: "get a list of provisioned service instances, optionally only those of the given service.",
+		Long:  `get serviceinstances allows you to get a list of provisioned service instances in your namespace, optionally filtered by the service name.`,
+		Example: `  mobile get serviceinstances --namespace=myproject
+  mobile get serviceinstances <serviceName> --namespace=myproject 
   kubectl plugin mobile get serviceinstances <serviceName>
   oc plugin mobile get serviceinstances <serviceName>`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 1 {
+			if len(args) > 1 {
 				return cmd.Usage()
 			}
-			serviceName := args[0]
 			ns, err := currentNamespace(cmd.Flags())
 			if err != nil {
 				return errors.Wrap(err, "failed to get namespace")
 			}
-			scs, err := findServiceClassByName(sc.scClient, serviceName)
-			if err != nil {
-				return err
-			}
-
-			si, err := findServiceInstanceByExternalName(sc.scClient, ns, scs.Spec.ExternalName)
-			if err != nil {
-				return err
+			var si []v1beta1.ServiceInstance
+			if len(args) == 1 {
+				scs, err := findServiceClassByName(sc.scClient, args[0])
+				if err != nil {
+					return err
+				}
+				si, err = findServiceInstanceByExternalName(sc.scClient, ns, scs.Spec.ExternalName)
+				if err != nil {
+					return err
+				}
+			} else {
+				sis, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).List(metav1.ListOptions{})
+				if err != nil {
+					return errors.Wrap(err, "failed to list service instances")
+				}
+				si = sis.Items
 			}
 			outType := outputType(cmd.Flags())
 			if err := sc.Out.Render("list"+cmd.Name(), outType, si); err != nil {
@@ -389,18 +1168,271 @@ func (sc *ServicesCmd) ListServiceInstCmd() *cobra.Command {
 		scL := serviceInstances.([]v1beta1.ServiceInstance)
 		var data [][]string
 		for _, item := range scL {
-			data = append(data, []string{item.Spec.ClusterServiceClassExternalName, item.Name})
+			dashboardURL := ""
+			if item.Status.DashboardURL != nil {
+				dashboardURL = *item.Status.DashboardURL
+			}
+			data = append(data, []string{
+				item.Spec.ClusterServiceClassExternalName,
+				item.Name,
+				item.Spec.ClusterServicePlanExternalName,
+				serviceInstanceStatus(item),
+				string(item.Status.CurrentOperation),
+				fmt.Sprintf("%v", item.Status.AsyncOpInProgress),
+				age(item.CreationTimestamp),
+				dashboardURL,
+			})
 		}
 		table := tablewriter.NewWriter(writer)
 		table.AppendBulk(data)
-		table.SetHeader([]string{"Name", "ID"})
+		table.SetHeader([]string{"Name", "ID", "Plan", "Status", "Operation", "Async", "Age", "Dashboard"})
 		table.Render()
 		return nil
 	})
 	return cmd
 }
 
-func buildServiceInstance(namespace string, serviceName string, secretName string, clusterServiceClass v1beta1.ClusterServiceClass) v1beta1.ServiceInstance {
+// GetServiceInstanceCmd shows the details of a single service instance
+func (sc *ServicesCmd) GetServiceInstanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serviceinstance <serviceInstanceID>",
+		Short: "get the status, conditions and parameters of a provisioned service instance",
+		Long: `get serviceinstance shows the status of a service instance in your namespace, the history of its conditions and the parameters it was provisioned with.
+Password parameters are not shown.
+Run the "mobile get serviceinstances" command from this tool to get the service instance ID.`,
+		Example: `  mobile get serviceinstance <serviceInstanceID> --namespace=myproject
+  kubectl plugin mobile get serviceinstance <serviceInstanceID>
+  oc plugin mobile get serviceinstance <serviceInstanceID>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			sid := args[0]
+			ns, err := currentNamespace(cmd.Flags())
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			si, err := sc.scClient.ServicecatalogV1beta1().ServiceInstances(ns).Get(sid, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get service instance "+sid)
+			}
+			description, err := sc.describeServiceInstance(ns, si)
+			if err != nil {
+				return errors.Wrap(err, "failed to describe service instance "+sid)
+			}
+			outType := outputType(cmd.Flags())
+			if err := sc.Out.Render("get"+cmd.Name(), outType, description); err != nil {
+				return errors.Wrap(err, fmt.Sprintf(output.FailedToOutPutInFormat, "service instance", outType))
+			}
+			return nil
+		},
+	}
+	sc.Out.AddRenderer("get"+cmd.Name(), "table", func(writer io.Writer, serviceInstance interface{}) error {
+		description := serviceInstance.(*ServiceInstanceDescription)
+		fmt.Fprintf(writer, "ID:\t\t\t%s\n", description.ID)
+		fmt.Fprintf(writer, "Service:\t\t%s\n", description.ServiceName)
+		fmt.Fprintf(writer, "Plan:\t\t\t%s\n", description.Plan)
+		fmt.Fprintf(writer, "Status:\t\t\t%s\n", description.Status)
+		fmt.Fprintf(writer, "Current Operation:\t%s\n", description.CurrentOperation)
+		fmt.Fprintf(writer, "Async Op In Progress:\t%v\n", description.AsyncOpInProgress)
+		fmt.Fprintf(writer, "Dashboard:\t\t%s\n", description.DashboardURL)
+		fmt.Fprintf(writer, "Created:\t\t%s\n", description.Created.Format(time.RFC3339))
+
+		fmt.Fprintf(writer, "\nConditions:\n")
+		var conditions [][]string
+		for _, c := range description.Conditions {
+			conditions = append(conditions, []string{string(c.Type), string(c.Status), c.Reason, c.Message, c.LastTransitionTime.Format(time.RFC3339)})
+		}
+		table := tablewriter.NewWriter(writer)
+		table.AppendBulk(conditions)
+		table.SetHeader([]string{"Type", "Status", "Reason", "Message", "Last Transition"})
+		table.Render()
+
+		if len(description.Events) > 0 {
+			fmt.Fprintf(writer, "\nEvents:\n")
+			var events [][]string
+			for _, e := range description.Events {
+				events = append(events, []string{e.Type, e.Reason, e.Message, fmt.Sprintf("%v", e.Count), e.LastSeen.Format(time.RFC3339)})
+			}
+			table := tablewriter.NewWriter(writer)
+			table.AppendBulk(events)
+			table.SetHeader([]string{"Type", "Reason", "Message", "Count", "Last Seen"})
+			table.Render()
+		}
+
+		fmt.Fprintf(writer, "\nParameters:\n")
+		var params [][]string
+		for _, k := range sortedKeys(description.Parameters) {
+			params = append(params, []string{k, description.Parameters[k]})
+		}
+		table = tablewriter.NewWriter(writer)
+		table.AppendBulk(params)
+		table.SetHeader([]string{"Name", "Value"})
+		table.Render()
+		return nil
+	})
+	return cmd
+}
+
+// describeServiceInstance gathers the status, condition history and parameters of the service instance
+func (sc *ServicesCmd) describeServiceInstance(ns string, si *v1beta1.ServiceInstance) (*ServiceInstanceDescription, error) {
+	description := &ServiceInstanceDescription{
+		ID:                si.Name,
+		ServiceName:       si.Spec.ClusterServiceClassExternalName,
+		Plan:              si.Spec.ClusterServicePlanExternalName,
+		Status:            serviceInstanceStatus(*si),
+		CurrentOperation:  string(si.Status.CurrentOperation),
+		AsyncOpInProgress: si.Status.AsyncOpInProgress,
+		Created:           si.CreationTimestamp.Time,
+		Conditions:        append([]v1beta1.ServiceInstanceCondition{}, si.Status.Conditions...),
+		Events:            []ServiceInstanceEvent{},
+		Parameters:        map[string]string{},
+	}
+	if si.Status.DashboardURL != nil {
+		description.DashboardURL = *si.Status.DashboardURL
+	}
+	sort.SliceStable(description.Conditions, func(i, j int) bool {
+		return description.Conditions[i].LastTransitionTime.Before(description.Conditions[j].LastTransitionTime)
+	})
+
+	events, err := sc.k8Client.CoreV1().Events(ns).List(metav1.ListOptions{
+		FieldSelector: fields.Set{"involvedObject.kind": "ServiceInstance", "involvedObject.name": si.Name}.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list events")
+	}
+	for _, e := range events.Items {
+		description.Events = append(description.Events, ServiceInstanceEvent{Type: e.Type, Reason: e.Reason, Message: e.Message, Count: e.Count, LastSeen: e.LastTimestamp.Time})
+	}
+	sort.SliceStable(description.Events, func(i, j int) bool {
+		return description.Events[i].LastSeen.Before(description.Events[j].LastSeen)
+	})
+
+	hidden := sc.passwordParams(si)
+	values, err := sc.instanceParameterValues(ns, si)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		if hidden[k] || looksSecret(k) {
+			description.Parameters[k] = "<hidden>"
+			continue
+		}
+		description.Parameters[k] = fmt.Sprint(v)
+	}
+	return description, nil
+}
+
+// passwordParams returns the parameters the plan schemas of the service instance declare as passwords
+func (sc *ServicesCmd) passwordParams(si *v1beta1.ServiceInstance) map[string]bool {
+	var schemas []*runtime.RawExtension
+	if si.Spec.ClusterServiceClassRef != nil {
+		if plan, err := findServicePlanForInstance(sc.scClient, si, si.Spec.ClusterServiceClassRef.Name); err == nil {
+			schemas = append(schemas, plan.Spec.ServiceInstanceCreateParameterSchema, plan.Spec.ServiceInstanceUpdateParameterSchema)
+		}
+	}
+	passwords := map[string]bool{}
+	for _, schema := range schemas {
+		params, err := schemaParams(schema)
+		if err != nil {
+			continue
+		}
+		for k, v := range params.Properties {
+			if isPassword(v) {
+				passwords[k] = true
+			}
+		}
+	}
+	return passwords
+}
+
+// instanceParameterValues reads the parameters of the service instance from its spec and its parameters secrets
+func (sc *ServicesCmd) instanceParameterValues(ns string, si *v1beta1.ServiceInstance) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if si.Spec.Parameters != nil && len(si.Spec.Parameters.Raw) > 0 {
+		if err := json.Unmarshal(si.Spec.Parameters.Raw, &values); err != nil {
+			return nil, errors.Wrap(err, "failed to read the service instance parameters")
+		}
+	}
+	for _, pf := range si.Spec.ParametersFrom {
+		if pf.SecretKeyRef == nil {
+			continue
+		}
+		secret, err := sc.k8Client.CoreV1().Secrets(ns).Get(pf.SecretKeyRef.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the parameters secret "+pf.SecretKeyRef.Name)
+		}
+		if raw, ok := secret.Data[pf.SecretKeyRef.Key]; ok && len(raw) > 0 {
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return nil, errors.Wrap(err, "failed to read the parameters from secret "+pf.SecretKeyRef.Name)
+			}
+		}
+	}
+	return values, nil
+}
+
+// looksSecret catches secret parameters of services whose plans do not declare them as passwords
+func looksSecret(param string) bool {
+	param = strings.ToLower(param)
+	for _, s := range []string{"password", "secret", "token"} {
+		if strings.Contains(param, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceInstanceStatus summarises the conditions of a service instance in a single word
+func serviceInstanceStatus(si v1beta1.ServiceInstance) string {
+	if si.DeletionTimestamp != nil {
+		return "Deleting"
+	}
+	status := "Pending"
+	for _, c := range si.Status.Conditions {
+		if c.Type == v1beta1.ServiceInstanceConditionFailed && c.Status == v1beta1.ConditionTrue {
+			return "Failed"
+		}
+		if c.Type == v1beta1.ServiceInstanceConditionReady {
+			if c.Status == v1beta1.ConditionTrue {
+				status = "Ready"
+			} else if c.Reason != "" {
+				status = c.Reason
+			}
+		}
+	}
+	return status
+}
+
+// age returns how long ago the timestamp was in the short form used by kubectl, e.g. 5m or 2d
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	d := time.Since(t.Time)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func buildServiceInstance(namespace string, serviceName string, secretName string, clusterServiceClass v1beta1.ClusterServiceClass, planName string) v1beta1.ServiceInstance {
 	return v1beta1.ServiceInstance{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "servicecatalog.k8s.io/v1beta1",
@@ -413,13 +1445,11 @@ func buildServiceInstance(namespace string, serviceName string, secretName strin
 		Spec: v1beta1.ServiceInstanceSpec{
 			PlanReference: v1beta1.PlanReference{
 				ClusterServiceClassExternalName: clusterServiceClass.Spec.ExternalName,
+				ClusterServicePlanExternalName:  planName,
 			},
 			ClusterServiceClassRef: &v1beta1.ClusterObjectReference{
 				Name: clusterServiceClass.Name,
 			},
-			ClusterServicePlanRef: &v1beta1.ClusterObjectReference{
-				Name: "default",
-			},
 			ParametersFrom: []v1beta1.ParametersFromSource{
 				{
 					SecretKeyRef: &v1beta1.SecretKeyReference{
@@ -16,6 +16,11 @@ package cmd_test
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"encoding/json"
@@ -29,8 +34,12 @@ import (
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	kFake "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+	kbeta "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	kalpha "k8s.io/client-go/pkg/apis/settings/v1alpha1"
 	ktesting "k8s.io/client-go/testing"
 )
 
@@ -89,7 +98,7 @@ func TestServicesCmd_DeleteServiceInstanceCmd(t *testing.T) {
 			SvcCatalogClient: func() versioned.Interface {
 				fake := &scFake.Clientset{}
 				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
-					return true, nil, nil
+					return true, &v1beta1.ServiceInstance{}, nil
 				})
 				fake.AddReactor("delete", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
 					return true, nil, fmt.Errorf("error in delete")
@@ -124,6 +133,11 @@ func TestServicesCmd_DeleteServiceInstanceCmd(t *testing.T) {
 				fake.AddReactor("delete", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
 					return true, nil, nil
 				})
+				fake.AddWatchReactor("serviceinstances", func(action ktesting.Action) (handled bool, ret watch.Interface, err error) {
+					fakeWatch := watch.NewRaceFreeFake()
+					fakeWatch.Delete(&v1beta1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "someid"}})
+					return true, fakeWatch, nil
+				})
 				return fake
 			},
 			K8Client: func() kubernetes.Interface {
@@ -167,7 +181,7 @@ func TestServicesCmd_DeleteServiceInstanceCmd(t *testing.T) {
 			var out bytes.Buffer
 			root := cmd.NewRootCmd()
 			deleteClient := cmd.NewDeleteComand()
-			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &out)
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &fakeDeploymentConfigs{}, &out)
 			deleteServiceInstCmd := serviceCmd.DeleteServiceInstanceCmd()
 			deleteClient.AddCommand(deleteServiceInstCmd)
 			root.AddCommand(deleteClient)
@@ -191,6 +205,323 @@ func TestServicesCmd_DeleteServiceInstanceCmd(t *testing.T) {
 	}
 }
 
+func TestServicesCmd_DeleteServiceInstanceCmdDependents(t *testing.T) {
+	svcCatalogClient := func() *scFake.Clientset {
+		fake := &scFake.Clientset{}
+		fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "keycloak-xyz"},
+				Spec: v1beta1.ServiceInstanceSpec{
+					ParametersFrom: []v1beta1.ParametersFromSource{
+						{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "keycloak-params-abc", Key: "parameters"}},
+					},
+				},
+			}, nil
+		})
+		fake.AddReactor("list", "servicebindings", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{
+				{ObjectMeta: metav1.ObjectMeta{Name: "sync-xyz-keycloak-xyz"}, Spec: v1beta1.ServiceBindingSpec{ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "keycloak-xyz"}, SecretName: "sync-xyz-keycloak-xyz"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Spec: v1beta1.ServiceBindingSpec{ServiceInstanceRef: v1beta1.LocalObjectReference{Name: "other"}, SecretName: "other"}},
+			}}, nil
+		})
+		return fake
+	}
+	k8Client := func() *kFake.Clientset {
+		fake := &kFake.Clientset{}
+		fake.AddReactor("list", "podpresets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &kalpha.PodPresetList{Items: []kalpha.PodPreset{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "sync-xyz-keycloak-xyz", Labels: map[string]string{"service": "keycloak"}},
+					Spec: kalpha.PodPresetSpec{
+						Selector: metav1.LabelSelector{MatchLabels: map[string]string{"run": "fh-sync-server", "keycloak": "enabled"}},
+						Volumes:  []corev1.Volume{{Name: "keycloak", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "sync-xyz-keycloak-xyz"}}}},
+					},
+				},
+				{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Spec: kalpha.PodPresetSpec{Volumes: []corev1.Volume{{Name: "other", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "other"}}}}}},
+			}}, nil
+		})
+		deployments := map[string]*kbeta.Deployment{
+			"fh-sync-server": {ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server"}, Spec: kbeta.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"run": "fh-sync-server", "keycloak": "enabled"}}}}},
+			"ups":            {ObjectMeta: metav1.ObjectMeta{Name: "ups"}, Spec: kbeta.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"run": "ups", "keycloak": "enabled"}}}}},
+		}
+		fake.AddReactor("list", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &kbeta.DeploymentList{Items: []kbeta.Deployment{*deployments["fh-sync-server"], *deployments["ups"]}}, nil
+		})
+		fake.AddReactor("get", "deployments", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, deployments[action.(ktesting.GetAction).GetName()], nil
+		})
+		return fake
+	}
+	deleted := func(actions []ktesting.Action, resource string) []string {
+		var names []string
+		for _, a := range actions {
+			if a.GetVerb() == "delete" && a.GetResource().Resource == resource {
+				names = append(names, a.(ktesting.DeleteAction).GetName())
+			}
+		}
+		return names
+	}
+
+	cases := []struct {
+		Name        string
+		Flags       []string
+		ExpectError string
+		Validate    func(t *testing.T, scClient *scFake.Clientset, k8Client *kFake.Clientset)
+	}{
+		{
+			Name:        "test delete serviceinstance refuses to delete an instance with integrations",
+			Flags:       []string{"--namespace=test", "--no-wait"},
+			ExpectError: "the service instance keycloak-xyz is still used by the integrations sync-xyz-keycloak-xyz. Delete them first or use --cascade to delete them with the service instance",
+			Validate: func(t *testing.T, scClient *scFake.Clientset, k8Client *kFake.Clientset) {
+				if names := deleted(scClient.Actions(), "serviceinstances"); len(names) != 0 {
+					t.Fatalf("expected the service instance not to be deleted but got %v", names)
+				}
+			},
+		},
+		{
+			Name:  "test delete serviceinstance with cascade deletes the integrations and params secret",
+			Flags: []string{"--namespace=test", "--no-wait", "--cascade"},
+			Validate: func(t *testing.T, scClient *scFake.Clientset, k8Client *kFake.Clientset) {
+				if names := deleted(scClient.Actions(), "servicebindings"); !reflect.DeepEqual(names, []string{"sync-xyz-keycloak-xyz"}) {
+					t.Fatalf("expected only the dependent binding to be deleted but got %v", names)
+				}
+				if names := deleted(k8Client.Actions(), "podpresets"); !reflect.DeepEqual(names, []string{"sync-xyz-keycloak-xyz"}) {
+					t.Fatalf("expected only the dependent pod preset to be deleted but got %v", names)
+				}
+				if names := deleted(scClient.Actions(), "serviceinstances"); !reflect.DeepEqual(names, []string{"keycloak-xyz"}) {
+					t.Fatalf("expected the service instance to be deleted but got %v", names)
+				}
+				if names := deleted(k8Client.Actions(), "secrets"); !reflect.DeepEqual(names, []string{"keycloak-params-abc"}) {
+					t.Fatalf("expected the params secret to be deleted but got %v", names)
+				}
+				var updated []string
+				for _, a := range k8Client.Actions() {
+					if a.GetVerb() == "update" && a.GetResource().Resource == "deployments" {
+						dep := a.(ktesting.UpdateAction).GetObject().(*kbeta.Deployment)
+						if _, ok := dep.Spec.Template.Labels["keycloak"]; ok {
+							t.Fatalf("expected the keycloak label to be removed from %s but got %v", dep.Name, dep.Spec.Template.Labels)
+						}
+						updated = append(updated, dep.Name)
+					}
+				}
+				if !reflect.DeepEqual(updated, []string{"fh-sync-server"}) {
+					t.Fatalf("expected only the deployment the integration was injected into to be updated but got %v", updated)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			scClient := svcCatalogClient()
+			kClient := k8Client()
+			serviceCmd := cmd.NewServicesCmd(scClient, kClient, &fakeDeploymentConfigs{}, &out)
+			deleteCmd := serviceCmd.DeleteServiceInstanceCmd()
+			root.AddCommand(deleteCmd)
+			if err := deleteCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := deleteCmd.RunE(deleteCmd, []string{"keycloak-xyz"})
+			if tc.ExpectError != "" {
+				if err == nil || err.Error() != tc.ExpectError {
+					t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+				}
+			} else if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			tc.Validate(t, scClient, kClient)
+		})
+	}
+}
+
+func TestServicesCmd_UpdateServiceInstanceCmd(t *testing.T) {
+	serviceInstance := func() *v1beta1.ServiceInstance {
+		return &v1beta1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "keycloak-xyz", Namespace: "test"},
+			Spec: v1beta1.ServiceInstanceSpec{
+				PlanReference:          v1beta1.PlanReference{ClusterServiceClassExternalName: "keycloak", ClusterServicePlanExternalName: "default"},
+				ClusterServiceClassRef: &v1beta1.ClusterObjectReference{Name: "keycloak-class"},
+				ParametersFrom: []v1beta1.ParametersFromSource{
+					{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "keycloak-params", Key: "parameters"}},
+				},
+			},
+		}
+	}
+	svcCatalogClient := func(updated *v1beta1.ServiceInstance) func() versioned.Interface {
+		return func() versioned.Interface {
+			fake := &scFake.Clientset{}
+			fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, serviceInstance(), nil
+			})
+			fake.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				schema := []byte(`{"properties":{"REALM":{"type":"string"},"LOG_LEVEL":{"type":"string","enum":["INFO","DEBUG"]}}}`)
+				return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{
+					{Spec: v1beta1.ClusterServicePlanSpec{ExternalName: "default", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "keycloak-class"}, ServiceInstanceUpdateParameterSchema: &runtime.RawExtension{Raw: schema}}},
+					{Spec: v1beta1.ClusterServicePlanSpec{ExternalName: "large", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "keycloak-class"}}},
+				}}, nil
+			})
+			fake.AddReactor("update", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				si := action.(ktesting.UpdateAction).GetObject().(*v1beta1.ServiceInstance)
+				if updated != nil {
+					*updated = *si
+				}
+				return true, si, nil
+			})
+			return fake
+		}
+	}
+	paramsSecret := func() kubernetes.Interface {
+		return kFake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keycloak-params", Namespace: "test"},
+			Data:       map[string][]byte{"parameters": []byte(`{"ADMIN_NAME":"admin","REALM":"old"}`)},
+		})
+	}
+	var updated v1beta1.ServiceInstance
+
+	cases := []struct {
+		Name             string
+		SvcCatalogClient func() versioned.Interface
+		K8Client         func() kubernetes.Interface
+		ExpectError      string
+		ExpectUsage      bool
+		Validate         func(t *testing.T, k8Client kubernetes.Interface)
+		Flags            []string
+		Args             []string
+	}{
+		{
+			Name:             "test update serviceinstance returns usage when missing the service instance id",
+			SvcCatalogClient: svcCatalogClient(nil),
+			K8Client:         paramsSecret,
+			ExpectUsage:      true,
+			Flags:            []string{"--namespace=test"},
+			Args:             []string{},
+		},
+		{
+			Name:             "test update serviceinstance returns an error when there is nothing to update",
+			SvcCatalogClient: svcCatalogClient(nil),
+			K8Client:         paramsSecret,
+			ExpectError:      "nothing to update. Set the parameters to change with --params or --params-file, or the new plan with --plan",
+			Flags:            []string{"--namespace=test"},
+			Args:             []string{"keycloak-xyz"},
+		},
+		{
+			Name:             "test update serviceinstance rejects parameters not in the update schema",
+			SvcCatalogClient: svcCatalogClient(nil),
+			K8Client:         paramsSecret,
+			ExpectError:      "unknown parameter ADMIN_NAME for plan default. Parameters that can be updated are LOG_LEVEL, REALM",
+			Flags:            []string{"--namespace=test", "-p", "ADMIN_NAME=other"},
+			Args:             []string{"keycloak-xyz"},
+		},
+		{
+			Name:             "test update serviceinstance rejects values not allowed by the update schema",
+			SvcCatalogClient: svcCatalogClient(nil),
+			K8Client:         paramsSecret,
+			ExpectError:      "invalid value TRACE for parameter LOG_LEVEL. Allowed values are INFO, DEBUG",
+			Flags:            []string{"--namespace=test", "-p", "LOG_LEVEL=TRACE"},
+			Args:             []string{"keycloak-xyz"},
+		},
+		{
+			Name:             "test update serviceinstance rejects parameters when the plan has no update schema",
+			SvcCatalogClient: svcCatalogClient(nil),
+			K8Client:         paramsSecret,
+			ExpectError:      "the plan large does not allow any parameters to be updated",
+			Flags:            []string{"--namespace=test", "--plan=large", "-p", "REALM=new"},
+			Args:             []string{"keycloak-xyz"},
+		},
+		{
+			Name:             "test update serviceinstance merges the parameters secret and bumps the update requests",
+			SvcCatalogClient: svcCatalogClient(&updated),
+			K8Client:         paramsSecret,
+			Flags:            []string{"--namespace=test", "--no-wait", "-p", "REALM=new"},
+			Args:             []string{"keycloak-xyz"},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				if updated.Spec.UpdateRequests != 1 {
+					t.Fatalf("expected the update requests to be bumped to 1 but got %v", updated.Spec.UpdateRequests)
+				}
+				secret, err := k8Client.CoreV1().Secrets("test").Get("keycloak-params", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal("failed to get params secret", err)
+				}
+				params := map[string]string{}
+				if err := json.Unmarshal(secret.Data["parameters"], &params); err != nil {
+					t.Fatal("failed to unmarshal params", err)
+				}
+				expected := map[string]string{"ADMIN_NAME": "admin", "REALM": "new"}
+				if !reflect.DeepEqual(params, expected) {
+					t.Fatalf("expected params %v but got %v", expected, params)
+				}
+			},
+		},
+		{
+			Name:             "test update serviceinstance changes the plan",
+			SvcCatalogClient: svcCatalogClient(&updated),
+			K8Client:         paramsSecret,
+			Flags:            []string{"--namespace=test", "--no-wait", "--plan=large"},
+			Args:             []string{"keycloak-xyz"},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				if updated.Spec.ClusterServicePlanExternalName != "large" {
+					t.Fatalf("expected the plan to be large but got %v", updated.Spec.ClusterServicePlanExternalName)
+				}
+			},
+		},
+		{
+			Name: "test update serviceinstance with --dry-run changes nothing",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := svcCatalogClient(nil)().(*scFake.Clientset)
+				fake.PrependReactor("update", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("should not have been called")
+				})
+				return fake
+			},
+			K8Client: paramsSecret,
+			Flags:    []string{"--namespace=test", "--dry-run", "-p", "REALM=new"},
+			Args:     []string{"keycloak-xyz"},
+			Validate: func(t *testing.T, k8Client kubernetes.Interface) {
+				for _, a := range k8Client.(*kFake.Clientset).Actions() {
+					if a.GetVerb() != "get" {
+						t.Fatalf("expected the params secret to only be read but got %s %s", a.GetVerb(), a.GetResource().Resource)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			k8Client := tc.K8Client()
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), k8Client, &fakeDeploymentConfigs{}, &out)
+			updateCmd := serviceCmd.UpdateServiceInstanceCmd()
+			root.AddCommand(updateCmd)
+			if err := updateCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := updateCmd.RunE(updateCmd, tc.Args)
+			if tc.ExpectError != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if err.Error() != tc.ExpectError {
+					t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+				}
+				return
+			}
+			if err != nil && !tc.ExpectUsage {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if tc.ExpectUsage && err != updateCmd.Usage() {
+				t.Fatalf("Expected error to be '%s' but got '%v'", updateCmd.Usage(), err)
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, k8Client)
+			}
+		})
+	}
+}
+
 func TestServicesCmd_ListServicesCmd(t *testing.T) {
 	cases := []struct {
 		Name             string
@@ -237,6 +568,35 @@ func TestServicesCmd_ListServicesCmd(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:  "test list services includes the mobile client types",
+			Flags: []string{"-o=json"},
+			SvcCatalogClient: func() versioned.Interface {
+				fakeClient := &scFake.Clientset{}
+				fakeClient.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ClusterServiceClassList{
+						Items: []v1beta1.ClusterServiceClass{
+							{ObjectMeta: metav1.ObjectMeta{Name: "keycloak"}, Spec: v1beta1.ClusterServiceClassSpec{Tags: []string{"mobile-service"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "flutter-app"}, Spec: v1beta1.ClusterServiceClassSpec{Tags: []string{"mobile-client-type"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "mysql"}, Spec: v1beta1.ClusterServiceClassSpec{Tags: []string{"database"}}},
+						},
+					}, nil
+				})
+				return fakeClient
+			},
+			K8Client: func() kubernetes.Interface {
+				return &kFake.Clientset{}
+			},
+			Validate: func(t *testing.T, data []byte) {
+				var list = &v1beta1.ClusterServiceClassList{}
+				if err := json.Unmarshal(data, list); err != nil {
+					t.Fatal("failed to unmarshal data", err)
+				}
+				if len(list.Items) != 2 || list.Items[0].Name != "keycloak" || list.Items[1].Name != "flutter-app" {
+					t.Fatalf("expected the keycloak and flutter-app service classes but got %v", list.Items)
+				}
+			},
+		},
 		{
 			Name:  "test list services returns error on failure",
 			Flags: []string{"-o=json"},
@@ -258,7 +618,7 @@ func TestServicesCmd_ListServicesCmd(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			var out bytes.Buffer
-			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &out)
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &fakeDeploymentConfigs{}, &out)
 			listCmd := serviceCmd.ListServicesCmd()
 			err := listCmd.RunE(listCmd, tc.Flags)
 			if err != nil && !tc.ExpectError {
@@ -274,6 +634,117 @@ func TestServicesCmd_ListServicesCmd(t *testing.T) {
 	}
 }
 
+func TestServicesCmd_DescribeServiceCmd(t *testing.T) {
+	cases := []struct {
+		Name             string
+		SvcCatalogClient func() versioned.Interface
+		ExpectError      bool
+		ExpectUsage      bool
+		Validate         func(t *testing.T, description *cmd.ServiceDescription)
+		Args             []string
+	}{
+		{
+			Name: "test describe service returns plans, parameters and integrations",
+			SvcCatalogClient: func() versioned.Interface {
+				fakeClient := &scFake.Clientset{}
+				fakeClient.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "sync"},
+							Spec: v1beta1.ClusterServiceClassSpec{
+								Description:      "data sync",
+								ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"fh-sync-server","displayName":"Sync","documentationUrl":"http://docs","providerDisplayName":"Red Hat","dependencies":["postgres"],"integrations":"keycloak, 3scale"}`)},
+							},
+						},
+					}}, nil
+				})
+				fakeClient.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					create := []byte(`{"required":["ADMIN_PASSWORD"],"properties":{"ADMIN_PASSWORD":{"type":"string","format":"password","default":"secret","title":"admin password"},"REPLICAS":{"type":"integer","default":1,"description":"number of replicas"}}}`)
+					bind := []byte(`{"required":["CLIENT_NAME"],"properties":{"CLIENT_NAME":{"type":"string"}}}`)
+					return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{
+						{Spec: v1beta1.ClusterServicePlanSpec{
+							ExternalName:                         "default",
+							ClusterServiceClassRef:               v1beta1.ClusterObjectReference{Name: "sync"},
+							ServiceInstanceCreateParameterSchema: &runtime.RawExtension{Raw: create},
+							ServiceBindingCreateParameterSchema:  &runtime.RawExtension{Raw: bind},
+						}},
+						{Spec: v1beta1.ClusterServicePlanSpec{ExternalName: "other", ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "other"}}},
+					}}, nil
+				})
+				return fakeClient
+			},
+			Validate: func(t *testing.T, description *cmd.ServiceDescription) {
+				if description.Name != "fh-sync-server" || description.DisplayName != "Sync" || description.ProviderDisplayName != "Red Hat" {
+					t.Fatalf("expected the service metadata to be described but got %v", description)
+				}
+				if !reflect.DeepEqual(description.Integrations, []string{"keycloak", "3scale"}) {
+					t.Fatalf("expected integrations keycloak and 3scale but got %v", description.Integrations)
+				}
+				if len(description.Plans) != 1 {
+					t.Fatalf("expected one plan but got %v", len(description.Plans))
+				}
+				expectedCreate := []cmd.ServiceParamDescription{
+					{Name: "ADMIN_PASSWORD", Type: "string", Default: "<hidden>", Required: true, Description: "admin password"},
+					{Name: "REPLICAS", Type: "integer", Default: "1", Description: "number of replicas"},
+				}
+				if !reflect.DeepEqual(description.Plans[0].CreateParameters, expectedCreate) {
+					t.Fatalf("expected create parameters %v but got %v", expectedCreate, description.Plans[0].CreateParameters)
+				}
+				if len(description.Plans[0].BindParameters) != 1 || !description.Plans[0].BindParameters[0].Required {
+					t.Fatalf("expected one required bind parameter but got %v", description.Plans[0].BindParameters)
+				}
+			},
+			Args: []string{"fh-sync-server"},
+		},
+		{
+			Name: "test describe service returns an error when the service does not exist",
+			SvcCatalogClient: func() versioned.Interface {
+				return &scFake.Clientset{}
+			},
+			ExpectError: true,
+			Args:        []string{"fh-sync-server"},
+		},
+		{
+			Name: "test describe service returns usage when missing the service name",
+			SvcCatalogClient: func() versioned.Interface {
+				return &scFake.Clientset{}
+			},
+			ExpectUsage: true,
+			Args:        []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), &kFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+			describeCmd := serviceCmd.DescribeServiceCmd()
+			root.AddCommand(describeCmd)
+			if err := describeCmd.ParseFlags([]string{"-o=json"}); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := describeCmd.RunE(describeCmd, tc.Args)
+			if err != nil && !tc.ExpectError {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if err == nil && tc.ExpectError {
+				t.Fatal("expected an error but got none")
+			}
+			if tc.ExpectUsage && err != describeCmd.Usage() {
+				t.Fatalf("Expected error to be '%s' but got '%v'", describeCmd.Usage(), err)
+			}
+			if tc.Validate != nil {
+				description := &cmd.ServiceDescription{}
+				if err := json.Unmarshal(out.Bytes(), description); err != nil {
+					t.Fatal("failed to unmarshal output", err)
+				}
+				tc.Validate(t, description)
+			}
+		})
+	}
+}
+
 func TestServicesCmd_CreateServiceInstanceCmd(t *testing.T) {
 	cases := []struct {
 		Name             string
@@ -438,7 +909,7 @@ func TestServicesCmd_CreateServiceInstanceCmd(t *testing.T) {
 			var out bytes.Buffer
 			//need root cmd to allow parsing shared flags
 			root := cmd.NewRootCmd()
-			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &out)
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &fakeDeploymentConfigs{}, &out)
 			createCmd := serviceCmd.CreateServiceInstanceCmd()
 			root.AddCommand(createCmd)
 			if err := createCmd.ParseFlags(tc.Flags); err != nil {
@@ -452,6 +923,282 @@ func TestServicesCmd_CreateServiceInstanceCmd(t *testing.T) {
 	}
 }
 
+func TestServicesCmd_CreateServiceInstanceCmdRollback(t *testing.T) {
+	cases := []struct {
+		Name                 string
+		Flags                []string
+		TimeOut              bool
+		ExpectError          string
+		ExpectK8Changes      []string
+		ExpectCatalogChanges []string
+	}{
+		{
+			Name:                 "test the service instance and its params secret are deleted when the provision fails",
+			Flags:                []string{"--namespace=test", "-pADMIN_NAME=test"},
+			ExpectError:          "Failed to provision keycloak: the apb failed",
+			ExpectK8Changes:      []string{"create secrets", "delete secrets keycloak-params-1"},
+			ExpectCatalogChanges: []string{"create serviceinstances", "delete serviceinstances keycloak-1"},
+		},
+		{
+			Name:                 "test the service instance and its params secret are kept with --no-rollback",
+			Flags:                []string{"--namespace=test", "-pADMIN_NAME=test", "--no-rollback"},
+			ExpectError:          "Failed to provision keycloak: the apb failed",
+			ExpectK8Changes:      []string{"create secrets"},
+			ExpectCatalogChanges: []string{"create serviceinstances"},
+		},
+		{
+			Name:                 "test the service instance and its params secret are deleted when the provision times out",
+			Flags:                []string{"--namespace=test", "-pADMIN_NAME=test", "--timeout=10ms"},
+			TimeOut:              true,
+			ExpectError:          "Failed to provision keycloak: timed out waiting for the condition. It may still be in progress",
+			ExpectK8Changes:      []string{"create secrets", "delete secrets keycloak-params-1"},
+			ExpectCatalogChanges: []string{"create serviceinstances", "delete serviceinstances keycloak-1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			scClient := &scFake.Clientset{}
+			fakeWatch := watch.NewFake()
+			scClient.AddWatchReactor("serviceinstances", ktesting.DefaultWatchReactor(fakeWatch, nil))
+			scClient.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{{
+					ObjectMeta: metav1.ObjectMeta{Name: "test"},
+					Spec:       v1beta1.ClusterServiceClassSpec{ExternalName: "keycloak", ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"keycloak"}`)}},
+				}}}, nil
+			})
+			scClient.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{{
+					Spec: v1beta1.ClusterServicePlanSpec{ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "test"}, ExternalName: "default"},
+				}}}, nil
+			})
+			scClient.AddReactor("create", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				si := action.(ktesting.CreateAction).GetObject().(*v1beta1.ServiceInstance)
+				si.Name = si.GenerateName + "1"
+				if tc.TimeOut {
+					return true, si, nil
+				}
+				go fakeWatch.Modify(&v1beta1.ServiceInstance{
+					ObjectMeta: metav1.ObjectMeta{Name: si.Name},
+					Status: v1beta1.ServiceInstanceStatus{Conditions: []v1beta1.ServiceInstanceCondition{
+						{Type: v1beta1.ServiceInstanceConditionFailed, Status: v1beta1.ConditionTrue, Message: "the apb failed"},
+					}},
+				})
+				return true, si, nil
+			})
+			k8Client := &kFake.Clientset{}
+			k8Client.AddReactor("create", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				secret := action.(ktesting.CreateAction).GetObject().(*corev1.Secret)
+				secret.Name = secret.GenerateName + "1"
+				return true, secret, nil
+			})
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			createCmd := cmd.NewServicesCmd(scClient, k8Client, &fakeDeploymentConfigs{}, &out).CreateServiceInstanceCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"keycloak"})
+			if err == nil || err.Error() != tc.ExpectError {
+				t.Fatalf("expected the error to be %s but got %v", tc.ExpectError, err)
+			}
+			if changes := integrationChanges(k8Client.Actions()); !reflect.DeepEqual(changes, tc.ExpectK8Changes) {
+				t.Fatalf("expected the changes %v but got %v", tc.ExpectK8Changes, changes)
+			}
+			if changes := integrationChanges(scClient.Actions()); !reflect.DeepEqual(changes, tc.ExpectCatalogChanges) {
+				t.Fatalf("expected the service catalog changes %v but got %v", tc.ExpectCatalogChanges, changes)
+			}
+		})
+	}
+}
+
+func TestServicesCmd_CreateServiceInstanceCmdParamSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "params")
+	if err != nil {
+		t.Fatal("failed to create temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+	paramsFile := filepath.Join(dir, "params.yaml")
+	if err := ioutil.WriteFile(paramsFile, []byte("ADMIN_NAME: fromfile\nADMIN_PASSWORD: env:TEST_ADMIN_PASSWORD\nREPLICAS: 2\n"), 0600); err != nil {
+		t.Fatal("failed to write params file", err)
+	}
+	escapedParamsFile := filepath.Join(dir, "escaped.yaml")
+	if err := ioutil.WriteFile(escapedParamsFile, []byte("ADMIN_NAME: '\\@dmin'\n"), 0600); err != nil {
+		t.Fatal("failed to write params file", err)
+	}
+	passwordFile := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("fromvaluefile\n"), 0600); err != nil {
+		t.Fatal("failed to write password file", err)
+	}
+	os.Setenv("TEST_ADMIN_PASSWORD", "fromenv")
+	defer os.Unsetenv("TEST_ADMIN_PASSWORD")
+
+	cases := []struct {
+		Name        string
+		Flags       []string
+		ExpectError bool
+		Expected    map[string]string
+	}{
+		{
+			Name:     "should read params from the params file",
+			Flags:    []string{"--namespace=test", "--no-wait=true", "--params-file=" + paramsFile},
+			Expected: map[string]string{"ADMIN_NAME": "fromfile", "ADMIN_PASSWORD": "fromenv", "REPLICAS": "2"},
+		},
+		{
+			Name:     "should override params file values with params flags",
+			Flags:    []string{"--namespace=test", "--no-wait=true", "--params-file=" + paramsFile, "-pADMIN_NAME=fromflag", "-pADMIN_PASSWORD=@" + passwordFile},
+			Expected: map[string]string{"ADMIN_NAME": "fromflag", "ADMIN_PASSWORD": "fromvaluefile", "REPLICAS": "2"},
+		},
+		{
+			Name:     "should use values escaped with a backslash as they are",
+			Flags:    []string{"--namespace=test", "--no-wait=true", "--params-file=" + escapedParamsFile, `-pADMIN_PASSWORD=\env:TEST_ADMIN_PASSWORD`},
+			Expected: map[string]string{"ADMIN_NAME": "@dmin", "ADMIN_PASSWORD": "env:TEST_ADMIN_PASSWORD", "REPLICAS": ""},
+		},
+		{
+			Name:        "should fail when a param env var is not set",
+			Flags:       []string{"--namespace=test", "--no-wait=true", "-pADMIN_NAME=env:TEST_NOT_SET"},
+			ExpectError: true,
+		},
+		{
+			Name:        "should fail when the params file does not exist",
+			Flags:       []string{"--namespace=test", "--no-wait=true", "--params-file=" + filepath.Join(dir, "missing.yaml")},
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			scClient := &scFake.Clientset{}
+			scClient.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "test"},
+						Spec: v1beta1.ClusterServiceClassSpec{
+							ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"keycloak"}`)},
+						},
+					},
+				}}, nil
+			})
+			scClient.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				params := &cmd.ServiceParams{Required: []string{"ADMIN_NAME", "ADMIN_PASSWORD"}, Properties: map[string]map[string]interface{}{"ADMIN_NAME": {}, "ADMIN_PASSWORD": {}, "REPLICAS": {}}}
+				b, _ := json.Marshal(params)
+				return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{{
+					Spec: v1beta1.ClusterServicePlanSpec{ServiceInstanceCreateParameterSchema: &runtime.RawExtension{Raw: b}, ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "test"}, ExternalName: "default"},
+				}}}, nil
+			})
+			var created map[string]string
+			k8Client := &kFake.Clientset{}
+			k8Client.AddReactor("create", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				secret := action.(ktesting.CreateAction).GetObject().(*corev1.Secret)
+				if err := json.Unmarshal(secret.Data["parameters"], &created); err != nil {
+					t.Fatal("failed to unmarshal params secret", err)
+				}
+				return true, secret, nil
+			})
+			root := cmd.NewRootCmd()
+			serviceCmd := cmd.NewServicesCmd(scClient, k8Client, &fakeDeploymentConfigs{}, &out)
+			createCmd := serviceCmd.CreateServiceInstanceCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"keycloak"})
+			if err != nil && !tc.ExpectError {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if err == nil && tc.ExpectError {
+				t.Fatal("expected an error but got none")
+			}
+			if tc.Expected != nil && !reflect.DeepEqual(tc.Expected, created) {
+				t.Fatalf("expected params %v but got %v", tc.Expected, created)
+			}
+		})
+	}
+}
+
+func TestServicesCmd_CreateServiceInstanceCmdPlan(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Flags        []string
+		ExpectError  string
+		ExpectedPlan string
+	}{
+		{
+			Name:         "should provision the default plan when no plan is set",
+			Flags:        []string{"--namespace=test", "--no-wait=true"},
+			ExpectedPlan: "default",
+		},
+		{
+			Name:         "should provision the plan set with the plan flag using its own parameters",
+			Flags:        []string{"--namespace=test", "--no-wait=true", "--plan=persistent", "-pSTORAGE_SIZE=1Gi"},
+			ExpectedPlan: "persistent",
+		},
+		{
+			Name:        "should fail with the available plans when the plan does not exist",
+			Flags:       []string{"--namespace=test", "--no-wait=true", "--plan=missing"},
+			ExpectError: "failed to find serviceplan missing associated with the serviceclass test. Available plans are default,persistent",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			var created *v1beta1.ServiceInstance
+			scClient := &scFake.Clientset{}
+			scClient.AddReactor("list", "clusterserviceclasses", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &v1beta1.ClusterServiceClassList{Items: []v1beta1.ClusterServiceClass{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "test"},
+						Spec: v1beta1.ClusterServiceClassSpec{
+							ExternalMetadata: &runtime.RawExtension{Raw: []byte(`{"serviceName":"fh-sync-server"}`)},
+						},
+					},
+				}}, nil
+			})
+			scClient.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				persistent, _ := json.Marshal(&cmd.ServiceParams{Required: []string{"STORAGE_SIZE"}, Properties: map[string]map[string]interface{}{"STORAGE_SIZE": {}}})
+				return true, &v1beta1.ClusterServicePlanList{Items: []v1beta1.ClusterServicePlan{
+					{Spec: v1beta1.ClusterServicePlanSpec{ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "test"}, ExternalName: "persistent", ServiceInstanceCreateParameterSchema: &runtime.RawExtension{Raw: persistent}}},
+					{Spec: v1beta1.ClusterServicePlanSpec{ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "test"}, ExternalName: "default"}},
+					{Spec: v1beta1.ClusterServicePlanSpec{ClusterServiceClassRef: v1beta1.ClusterObjectReference{Name: "other"}, ExternalName: "other"}},
+				}}, nil
+			})
+			scClient.AddReactor("create", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				created = action.(ktesting.CreateAction).GetObject().(*v1beta1.ServiceInstance)
+				return true, created, nil
+			})
+			root := cmd.NewRootCmd()
+			serviceCmd := cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &out)
+			createCmd := serviceCmd.CreateServiceInstanceCmd()
+			root.AddCommand(createCmd)
+			if err := createCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := createCmd.RunE(createCmd, []string{"fh-sync-server"})
+			if tc.ExpectError != "" {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				if err.Error() != tc.ExpectError {
+					t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if created == nil {
+				t.Fatal("expected a service instance to be created")
+			}
+			if created.Spec.ClusterServicePlanExternalName != tc.ExpectedPlan {
+				t.Fatalf("expected plan to be %s but got %s", tc.ExpectedPlan, created.Spec.ClusterServicePlanExternalName)
+			}
+		})
+	}
+}
+
 func TestServicesCmd_ListServiceInstanceCmd(t *testing.T) {
 	cases := []struct {
 		Name             string
@@ -519,7 +1266,60 @@ func TestServicesCmd_ListServiceInstanceCmd(t *testing.T) {
 			Args:  []string{"keycloak"},
 		},
 		{
-			Name: "Usage is returned when no service name is passed",
+			Name: "all service instances are listed with their status when no service name is passed",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					dashboard := "https://keycloak.example.com"
+					return true, &v1beta1.ServiceInstanceList{
+						Items: []v1beta1.ServiceInstance{
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "keycloak-x1"},
+								Spec: v1beta1.ServiceInstanceSpec{
+									PlanReference: v1beta1.PlanReference{
+										ClusterServiceClassExternalName: "keycloak",
+										ClusterServicePlanExternalName:  "default",
+									},
+								},
+								Status: v1beta1.ServiceInstanceStatus{
+									Conditions:   []v1beta1.ServiceInstanceCondition{{Type: v1beta1.ServiceInstanceConditionReady, Status: v1beta1.ConditionTrue}},
+									DashboardURL: &dashboard,
+								},
+							},
+							{
+								ObjectMeta: metav1.ObjectMeta{Name: "fh-sync-server-x2"},
+								Spec: v1beta1.ServiceInstanceSpec{
+									PlanReference: v1beta1.PlanReference{
+										ClusterServiceClassExternalName: "fh-sync-server",
+										ClusterServicePlanExternalName:  "default",
+									},
+								},
+								Status: v1beta1.ServiceInstanceStatus{
+									Conditions:        []v1beta1.ServiceInstanceCondition{{Type: v1beta1.ServiceInstanceConditionReady, Status: v1beta1.ConditionFalse, Reason: "Provisioning"}},
+									CurrentOperation:  v1beta1.ServiceInstanceOperationProvision,
+									AsyncOpInProgress: true,
+								},
+							},
+						},
+					}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				return &kFake.Clientset{}
+			},
+			ValidateOut: func(t *testing.T, data []byte) {
+				out := string(data)
+				for _, expected := range []string{"PLAN", "STATUS", "OPERATION", "ASYNC", "AGE", "DASHBOARD", "keycloak-x1", "Ready", "https://keycloak.example.com", "fh-sync-server-x2", "Provisioning", "Provision"} {
+					if !strings.Contains(out, expected) {
+						t.Fatalf("expected %s in the output but got %s", expected, out)
+					}
+				}
+			},
+			Flags: []string{"--namespace=myproject"},
+		},
+		{
+			Name: "Usage is returned when more than one service name is passed",
 			SvcCatalogClient: func() versioned.Interface {
 				fake := &scFake.Clientset{}
 				return fake
@@ -529,6 +1329,8 @@ func TestServicesCmd_ListServiceInstanceCmd(t *testing.T) {
 			},
 			ExpectError: false,
 			ExpectUsage: true,
+			Flags:       []string{"--namespace=myproject"},
+			Args:        []string{"keycloak", "fh-sync-server"},
 		},
 		{
 			Name: "error is returned when no namespace is set",
@@ -558,7 +1360,7 @@ func TestServicesCmd_ListServiceInstanceCmd(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			var out bytes.Buffer
 			root := cmd.NewRootCmd()
-			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &out)
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &fakeDeploymentConfigs{}, &out)
 			listInstCmd := serviceCmd.ListServiceInstCmd()
 			root.AddCommand(listInstCmd)
 			if err := listInstCmd.ParseFlags(tc.Flags); err != nil {
@@ -584,3 +1386,131 @@ func TestServicesCmd_ListServiceInstanceCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestServicesCmd_GetServiceInstanceCmd(t *testing.T) {
+	cases := []struct {
+		Name             string
+		SvcCatalogClient func() versioned.Interface
+		K8Client         func() kubernetes.Interface
+		ExpectError      bool
+		ErrorPattern     string
+		ValidateOut      func(t *testing.T, description *cmd.ServiceInstanceDescription)
+		Args             []string
+		Flags            []string
+	}{
+		{
+			Name: "should describe the service instance hiding password parameters",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ServiceInstance{
+						ObjectMeta: metav1.ObjectMeta{Name: "keycloak-x1", Namespace: "myproject"},
+						Spec: v1beta1.ServiceInstanceSpec{
+							PlanReference: v1beta1.PlanReference{
+								ClusterServiceClassExternalName: "keycloak",
+								ClusterServicePlanExternalName:  "default",
+							},
+							ClusterServiceClassRef: &v1beta1.ClusterObjectReference{Name: "keycloak-id"},
+							ParametersFrom: []v1beta1.ParametersFromSource{
+								{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "keycloak-x1-params", Key: "parameters"}},
+							},
+						},
+						Status: v1beta1.ServiceInstanceStatus{
+							Conditions: []v1beta1.ServiceInstanceCondition{
+								{Type: v1beta1.ServiceInstanceConditionReady, Status: v1beta1.ConditionTrue, Reason: "ProvisionedSuccessfully", LastTransitionTime: metav1.Unix(200, 0)},
+								{Type: v1beta1.ServiceInstanceConditionFailed, Status: v1beta1.ConditionFalse, Reason: "ProvisionCallFailed", LastTransitionTime: metav1.Unix(100, 0)},
+							},
+						},
+					}, nil
+				})
+				fake.AddReactor("list", "clusterserviceplans", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v1beta1.ClusterServicePlanList{
+						Items: []v1beta1.ClusterServicePlan{
+							{
+								Spec: v1beta1.ClusterServicePlanSpec{
+									ExternalName:                         "default",
+									ClusterServiceClassRef:               v1beta1.ClusterObjectReference{Name: "keycloak-id"},
+									ServiceInstanceCreateParameterSchema: &runtime.RawExtension{Raw: []byte(`{"properties":{"ADMIN_NAME":{"type":"string"},"ADMIN_PASS":{"type":"string","display_type":"password"}}}`)},
+								},
+							},
+						},
+					}, nil
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				fake := &kFake.Clientset{}
+				fake.AddReactor("get", "secrets", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "keycloak-x1-params"},
+						Data:       map[string][]byte{"parameters": []byte(`{"ADMIN_NAME":"admin","ADMIN_PASS":"changeme"}`)},
+					}, nil
+				})
+				return fake
+			},
+			ValidateOut: func(t *testing.T, description *cmd.ServiceInstanceDescription) {
+				if description.Status != "Ready" {
+					t.Fatalf("expected status Ready but got %s", description.Status)
+				}
+				if description.Parameters["ADMIN_NAME"] != "admin" {
+					t.Fatalf("expected ADMIN_NAME to be admin but got %s", description.Parameters["ADMIN_NAME"])
+				}
+				if description.Parameters["ADMIN_PASS"] != "<hidden>" {
+					t.Fatalf("expected ADMIN_PASS to be hidden but got %s", description.Parameters["ADMIN_PASS"])
+				}
+				if len(description.Conditions) != 2 || description.Conditions[0].Reason != "ProvisionCallFailed" {
+					t.Fatalf("expected the conditions to be ordered oldest first but got %v", description.Conditions)
+				}
+			},
+			Args:  []string{"keycloak-x1"},
+			Flags: []string{"--namespace=myproject", "-o=json"},
+		},
+		{
+			Name: "should return an error when the service instance does not exist",
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("not found")
+				})
+				return fake
+			},
+			K8Client: func() kubernetes.Interface {
+				return &kFake.Clientset{}
+			},
+			ExpectError:  true,
+			ErrorPattern: "failed to get service instance keycloak-x1: not found",
+			Args:         []string{"keycloak-x1"},
+			Flags:        []string{"--namespace=myproject", "-o=json"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			root := cmd.NewRootCmd()
+			serviceCmd := cmd.NewServicesCmd(tc.SvcCatalogClient(), tc.K8Client(), &fakeDeploymentConfigs{}, &out)
+			getCmd := serviceCmd.GetServiceInstanceCmd()
+			root.AddCommand(getCmd)
+			if err := getCmd.ParseFlags(tc.Flags); err != nil {
+				t.Fatal("failed to parse command flags", err)
+			}
+			err := getCmd.RunE(getCmd, tc.Args)
+			if err != nil && !tc.ExpectError {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if err == nil && tc.ExpectError {
+				t.Fatal("expected an error but got none")
+			}
+			if tc.ExpectError && err.Error() != tc.ErrorPattern {
+				t.Fatalf("expected error '%s' but got '%v'", tc.ErrorPattern, err)
+			}
+			if tc.ValidateOut != nil {
+				description := &cmd.ServiceInstanceDescription{}
+				if err := json.Unmarshal(out.Bytes(), description); err != nil {
+					t.Fatal("failed to unmarshal the output", err, out.String())
+				}
+				tc.ValidateOut(t, description)
+			}
+		})
+	}
+}
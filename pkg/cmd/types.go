@@ -16,9 +16,11 @@ package cmd
 
 import (
 	"encoding/json"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
 	"github.com/pkg/errors"
 	"k8s.io/client-go/pkg/api/v1"
 	"net/http"
+	"time"
 )
 
 //Service represents a serverside application that mobile application will interact with
@@ -45,8 +47,222 @@ type ExternalServiceMetaData struct {
 	ImageURL            string   `json:"imageUrl"`
 	ProviderDisplayName string   `json:"providerDisplayName"`
 	ServiceName         string   `json:"serviceName"`
+	// ClientType is the type of mobile client provisioned by the service classes tagged mobile-client-type
+	ClientType string `json:"clientType,omitempty"`
 }
 
+//ServiceDescription is the detailed view of a service that can be provisioned
+type ServiceDescription struct {
+	Name                string                   `json:"name"`
+	DisplayName         string                   `json:"displayName"`
+	Description         string                   `json:"description"`
+	DocumentationURL    string                   `json:"documentationUrl"`
+	ProviderDisplayName string                   `json:"providerDisplayName"`
+	Dependencies        []string                 `json:"dependencies"`
+	Integrations        []string                 `json:"integrations"`
+	Plans               []ServicePlanDescription `json:"plans"`
+}
+
+//ServicePlanDescription describes a plan of a service and the parameters it accepts
+type ServicePlanDescription struct {
+	Name             string                    `json:"name"`
+	Description      string                    `json:"description"`
+	Free             bool                      `json:"free"`
+	CreateParameters []ServiceParamDescription `json:"createParameters"`
+	BindParameters   []ServiceParamDescription `json:"bindParameters"`
+}
+
+//ServiceParamDescription describes a single parameter of a plan schema
+type ServiceParamDescription struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+//ServiceInstanceDescription is the detailed view of a provisioned service instance
+type ServiceInstanceDescription struct {
+	ID                string                             `json:"id"`
+	ServiceName       string                             `json:"serviceName"`
+	Plan              string                             `json:"plan"`
+	Status            string                             `json:"status"`
+	CurrentOperation  string                             `json:"currentOperation,omitempty"`
+	AsyncOpInProgress bool                               `json:"asyncOpInProgress"`
+	DashboardURL      string                             `json:"dashboardURL,omitempty"`
+	Created           time.Time                          `json:"created"`
+	Conditions        []v1beta1.ServiceInstanceCondition `json:"conditions"`
+	Events            []ServiceInstanceEvent             `json:"events"`
+	Parameters        map[string]string                  `json:"parameters"`
+}
+
+//ServiceInstanceEvent is an event recorded against a service instance
+type ServiceInstanceEvent struct {
+	Type     string    `json:"type"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int32     `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+//IntegrationGraph is the graph of the service instances and mobile clients in a namespace and the integrations between them
+type IntegrationGraph struct {
+	Namespace string                 `json:"namespace"`
+	Nodes     []IntegrationGraphNode `json:"nodes"`
+	Edges     []IntegrationGraphEdge `json:"edges"`
+	// HighlightBroken is only used when drawing the graph
+	HighlightBroken bool `json:"-"`
+}
+
+//IntegrationGraphNode is a service instance, a mobile client or a service that is integrated with but has no instance in the namespace
+type IntegrationGraphNode struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`
+	Status string `json:"status,omitempty"`
+}
+
+//IntegrationGraphEdge points from the consumer to the provider it uses
+type IntegrationGraphEdge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Kind      string `json:"kind"`
+	Binding   string `json:"binding,omitempty"`
+	PodPreset string `json:"podPreset,omitempty"`
+	Broken    bool   `json:"broken"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+//IntegrationProblem is an inconsistency between the pieces making up the integrations in a namespace
+type IntegrationProblem struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Problem  string `json:"problem"`
+	Fix      string `json:"fix"`
+	Fixed    bool   `json:"fixed"`
+	FixError string `json:"fixError,omitempty"`
+	// fix repairs the problem, or records the repair with a dry run. It is nil when the problem has to be fixed by hand
+	fix func(dryRun *dryRun) error
+}
+
+//DryRunChange is a change a command would have made had it not been run with --dry-run
+type DryRunChange struct {
+	Action    string      `json:"action"`
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Object    interface{} `json:"object,omitempty"`
+	Patch     interface{} `json:"patch,omitempty"`
+}
+
+//Manifest declares the mobile clients, services, integrations and external service configs of a namespace
+type Manifest struct {
+	APIVersion     string                  `json:"apiVersion"`
+	Kind           string                  `json:"kind"`
+	Clients        []ManifestClient        `json:"clients,omitempty"`
+	Services       []ManifestService       `json:"services,omitempty"`
+	Integrations   []ManifestIntegration   `json:"integrations,omitempty"`
+	ServiceConfigs []ManifestServiceConfig `json:"serviceConfigs,omitempty"`
+}
+
+//ManifestClient is a mobile client declared in a manifest
+type ManifestClient struct {
+	Name             string   `json:"name"`
+	ClientType       string   `json:"clientType"`
+	AppIdentifier    string   `json:"appIdentifier"`
+	// DmzURL is only set when given, an empty DmzURL keeps the current one
+	DmzURL string `json:"dmzUrl,omitempty"`
+	// ExcludedServices are the names of the services, they are excluded by the name of their instance in the namespace
+	ExcludedServices []string `json:"excludedServices,omitempty"`
+}
+
+//ManifestService is a service instance declared in a manifest. A namespace holds at most one instance of each service
+type ManifestService struct {
+	Service string            `json:"service"`
+	Plan    string            `json:"plan,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+//ManifestIntegration is an integration between two of the services declared in a manifest
+type ManifestIntegration struct {
+	Consumer string            `json:"consumer"`
+	Provider string            `json:"provider"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+//ManifestServiceConfig is the config of an external service declared in a manifest
+type ManifestServiceConfig struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Data   map[string]string `json:"data"`
+}
+
+//APIKeyRotation is the new api key of a mobile client and the integration secrets it was updated in
+type APIKeyRotation struct {
+	ClientID              string   `json:"clientId"`
+	APIKey                string   `json:"apiKey"`
+	PreviousAPIKeyExpires string   `json:"previousApiKeyExpires,omitempty"`
+	UpdatedSecrets        []string `json:"updatedSecrets,omitempty"`
+}
+
+//AccessCheck is whether the current user is allowed a verb on a kind of resource needed by a command, Flag is the flag of the command needing it.
+//The checks with the same AnyOf are alternatives, the command only needs one of them
+type AccessCheck struct {
+	Verb     string `json:"verb"`
+	Group    string `json:"group,omitempty"`
+	Resource string `json:"resource"`
+	Flag     string `json:"flag,omitempty"`
+	Unless   string `json:"unless,omitempty"`
+	AnyOf    string `json:"anyOf,omitempty"`
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+//ManifestDifference is a resource or a field of a resource that differs between two namespaces or a namespace and a manifest
+type ManifestDifference struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Change string      `json:"change"`
+	Field  string      `json:"field,omitempty"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+//The changes of a ManifestDifference
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+//ApplyStep is a change mobile apply makes to bring the namespace in line with the manifest
+type ApplyStep struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+	// run makes the change and waits for it to be ready, it is nil for the steps that change nothing
+	run func() error
+}
+
+const (
+	ManifestKind = "MobileManifest"
+
+	ApplyCreate    = "create"
+	ApplyUpdate    = "update"
+	ApplyDelete    = "delete"
+	ApplyUnchanged = "unchanged"
+	ApplyKeep      = "keep"
+)
+
+const (
+	GraphNodeServiceInstance = "serviceinstance"
+	GraphNodeMobileClient    = "mobileclient"
+	GraphNodeService         = "service"
+	GraphEdgeIntegration     = "integration"
+	GraphEdgeClient          = "client"
+)
+
 type ServiceIntegration struct {
 	Enabled         bool   `json:"enabled"`
 	Component       string `json:"component"`
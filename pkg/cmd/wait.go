@@ -0,0 +1,129 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// exit codes returned by the mobile command so scripts can tell why a command failed
+const (
+	ExitOK              = 0
+	ExitError           = 1
+	ExitConditionFailed = 2
+	ExitTimeout         = 3
+	ExitInterrupted     = 130
+)
+
+const (
+	waitForDelete    = "delete"
+	waitForCondition = "condition="
+)
+
+func NewWaitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "wait",
+		Short: "wait for services, integrations and clients to reach a condition",
+		Long: `wait blocks until the given resource reaches the condition set with --for, then exits with one of the following codes:
+  0   the condition was met
+  1   an error occurred
+  2   the resource reached a failed state, so the condition can never be met
+  3   the timeout was reached before the condition was met
+  130 the wait was interrupted`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// ExitCode returns the code the mobile command should exit with for the given error
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case wait.IsFailed(err):
+		return ExitConditionFailed
+	case wait.IsTimeout(err):
+		return ExitTimeout
+	case wait.IsInterrupted(err):
+		return ExitInterrupted
+	}
+	return ExitError
+}
+
+// addWaitFlags adds the flags shared by the wait sub commands
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("for", waitForCondition+"Ready", "--for=condition=Ready wait for the condition to be true or --for=delete wait for the resource to be deleted")
+	cmd.PersistentFlags().Duration("timeout", wait.DefaultTimeout, "--timeout=5m how long to wait before giving up. 0 waits forever")
+}
+
+// waitFor parses the --for flag returning the condition to wait for or delete
+func waitFor(flags *pflag.FlagSet) (string, error) {
+	waitFor, err := flags.GetString("for")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if waitFor == waitForDelete {
+		return waitFor, nil
+	}
+	if !strings.HasPrefix(waitFor, waitForCondition) || waitFor == waitForCondition {
+		return "", errors.New("unknown value for --for " + waitFor + ". Use --for=condition=<condition> or --for=delete")
+	}
+	return strings.TrimPrefix(waitFor, waitForCondition), nil
+}
+
+// waitForDeletion waits until the named object is deleted. The watch starts from the version returned by get so the
+// deletion cannot be missed between the get and the watch
+func waitForDeletion(flags *pflag.FlagSet, name string, get func() (runtime.Object, error), watchFunc wait.WatchFunc) error {
+	obj, err := get()
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	waiter, err := newWaiter(flags, name, watchFunc)
+	if err != nil {
+		return err
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		waiter.ResourceVersion = accessor.GetResourceVersion()
+	}
+	return waiter.Until(wait.Deleted())
+}
+
+// waitFrom waits until obj, as returned by a get, meets the condition. The condition is checked against obj first and
+// the watch starts from its version, so a change between the get and the watch cannot be missed
+func waitFrom(waiter *wait.Waiter, obj runtime.Object, condition wait.Condition) error {
+	if done, _, err := condition(watch.Added, obj); done || err != nil {
+		return err
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		waiter.ResourceVersion = accessor.GetResourceVersion()
+	}
+	return waiter.Until(condition)
+}
@@ -0,0 +1,147 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ServiceInstanceReady is met once the ServiceInstance is Ready and has reconciled at least the given generation of its spec
+func ServiceInstanceReady(generation int64) Condition {
+	return serviceInstanceCondition(v1beta1.ServiceInstanceConditionReady, generation)
+}
+
+// ServiceInstanceCondition is met once the ServiceInstance has the given condition set to True and has reconciled at least the given generation of its spec
+func ServiceInstanceCondition(conditionType v1beta1.ServiceInstanceConditionType, generation int64) Condition {
+	return serviceInstanceCondition(conditionType, generation)
+}
+
+func serviceInstanceCondition(conditionType v1beta1.ServiceInstanceConditionType, generation int64) Condition {
+	return func(event watch.EventType, obj runtime.Object) (bool, string, error) {
+		si, ok := obj.(*v1beta1.ServiceInstance)
+		if !ok {
+			return false, "", nil
+		}
+		status := Status(si)
+		if event == watch.Deleted {
+			return false, status, Failed("the service instance " + si.Name + " was deleted")
+		}
+		for _, c := range si.Status.Conditions {
+			if c.Status != v1beta1.ConditionTrue {
+				continue
+			}
+			if c.Type == conditionType && si.Status.ReconciledGeneration >= generation {
+				return true, status, nil
+			}
+			if c.Type == v1beta1.ServiceInstanceConditionFailed {
+				return false, status, Failed(c.Message)
+			}
+		}
+		return false, status, nil
+	}
+}
+
+// ServiceBindingReady is met once the ServiceBinding is Ready
+func ServiceBindingReady() Condition {
+	return ServiceBindingCondition(v1beta1.ServiceBindingConditionReady)
+}
+
+// ServiceBindingCondition is met once the ServiceBinding has the given condition set to True
+func ServiceBindingCondition(conditionType v1beta1.ServiceBindingConditionType) Condition {
+	return func(event watch.EventType, obj runtime.Object) (bool, string, error) {
+		sb, ok := obj.(*v1beta1.ServiceBinding)
+		if !ok {
+			return false, "", nil
+		}
+		status := Status(sb)
+		if event == watch.Deleted {
+			return false, status, Failed("the service binding " + sb.Name + " was deleted")
+		}
+		for _, c := range sb.Status.Conditions {
+			if c.Status != v1beta1.ConditionTrue {
+				continue
+			}
+			if c.Type == conditionType {
+				return true, status, nil
+			}
+			if c.Type == v1beta1.ServiceBindingConditionFailed {
+				return false, status, Failed(c.Message)
+			}
+		}
+		return false, status, nil
+	}
+}
+
+// ServiceInstanceDeprovisioned is met once the broker has deprovisioned the ServiceInstance or it has been deleted
+func ServiceInstanceDeprovisioned() Condition {
+	return func(event watch.EventType, obj runtime.Object) (bool, string, error) {
+		si, ok := obj.(*v1beta1.ServiceInstance)
+		if !ok {
+			return false, "", nil
+		}
+		status := Status(si)
+		if event == watch.Deleted {
+			return true, status, nil
+		}
+		switch si.Status.DeprovisionStatus {
+		case v1beta1.ServiceInstanceDeprovisionStatusSucceeded, v1beta1.ServiceInstanceDeprovisionStatusNotRequired:
+			return si.DeletionTimestamp != nil, status, nil
+		case v1beta1.ServiceInstanceDeprovisionStatusFailed:
+			return false, status, Failed("failed to deprovision " + si.Name + ". " + status)
+		}
+		return false, status, nil
+	}
+}
+
+// Exists is met as soon as the object is seen by the watch
+func Exists() Condition {
+	return func(event watch.EventType, obj runtime.Object) (bool, string, error) {
+		if event == watch.Deleted {
+			return false, "", Failed("the object was deleted")
+		}
+		return true, "", nil
+	}
+}
+
+// Deleted is met once the object has been deleted
+func Deleted() Condition {
+	return func(event watch.EventType, obj runtime.Object) (bool, string, error) {
+		return event == watch.Deleted, Status(obj), nil
+	}
+}
+
+// Status returns the message of the most recently changed condition of a service catalog object
+func Status(obj runtime.Object) string {
+	var latest metav1.Time
+	var message string
+	switch o := obj.(type) {
+	case *v1beta1.ServiceInstance:
+		for _, c := range o.Status.Conditions {
+			if message == "" || !c.LastTransitionTime.Before(latest) {
+				latest, message = c.LastTransitionTime, c.Message
+			}
+		}
+	case *v1beta1.ServiceBinding:
+		for _, c := range o.Status.Conditions {
+			if message == "" || !c.LastTransitionTime.Before(latest) {
+				latest, message = c.LastTransitionTime, c.Message
+			}
+		}
+	}
+	return message
+}
@@ -0,0 +1,83 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// DefaultPollInterval is how often a Poller checks its condition
+const DefaultPollInterval = 2 * time.Second
+
+// CheckFunc checks the current state of an object. It returns true once the wait is complete, a message
+// describing the current state to show as progress and an error if the condition can no longer be met
+type CheckFunc func() (done bool, status string, err error)
+
+// Poller checks a condition at an interval, for objects that cannot be watched or whose state is spread over several objects
+type Poller struct {
+	// Interval between checks
+	Interval time.Duration
+	// Timeout after which the wait gives up. Zero waits forever
+	Timeout time.Duration
+	out     io.Writer
+}
+
+// NewPoller returns a Poller which writes progress to out. A nil out discards progress
+func NewPoller(interval, timeout time.Duration, out io.Writer) *Poller {
+	if out == nil {
+		out = ioutil.Discard
+	}
+	return &Poller{Interval: interval, Timeout: timeout, out: out}
+}
+
+// Until blocks until the check is done, the check fails, the timeout is reached or the user presses Ctrl-C.
+// The first check is made straight away
+func (p *Poller) Until(check CheckFunc) error {
+	var timeout <-chan time.Time
+	if p.Timeout > 0 {
+		timer := time.NewTimer(p.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	lastStatus := ""
+	for {
+		done, status, err := check()
+		if status != "" && status != lastStatus {
+			fmt.Fprintln(p.out, "status: "+status)
+			lastStatus = status
+		}
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-timeout:
+			return ErrTimeout
+		case <-interrupt:
+			return ErrInterrupted
+		case <-ticker.C:
+		}
+	}
+}
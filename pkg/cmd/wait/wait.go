@@ -0,0 +1,184 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// DefaultTimeout is how long commands wait for an object to reach its target condition unless told otherwise
+const DefaultTimeout = 10 * time.Minute
+
+var (
+	// ErrTimeout is returned when the object did not reach the condition before the timeout
+	ErrTimeout = errors.New("timed out waiting for the condition. It may still be in progress")
+	// ErrInterrupted is returned when the wait was cancelled with Ctrl-C
+	ErrInterrupted = errors.New("interrupted while waiting for the condition. It may still be in progress")
+)
+
+// FailedError is returned when the object reached a state where the condition can never be met
+type FailedError struct {
+	Message string
+}
+
+func (e *FailedError) Error() string {
+	return e.Message
+}
+
+// Failed returns a FailedError with the given message
+func Failed(message string) error {
+	return &FailedError{Message: message}
+}
+
+// IsFailed checks if the wait ended because the object reached a failed state
+func IsFailed(err error) bool {
+	_, ok := errors.Cause(err).(*FailedError)
+	return ok
+}
+
+// IsTimeout checks if the wait ended because the timeout was reached
+func IsTimeout(err error) bool {
+	return errors.Cause(err) == ErrTimeout
+}
+
+// IsInterrupted checks if the wait ended because it was cancelled by the user
+func IsInterrupted(err error) bool {
+	return errors.Cause(err) == ErrInterrupted
+}
+
+// Condition checks an event received for the watched object. It returns true once the wait is complete, a message
+// describing the current state of the object to show as progress and an error if the condition can no longer be met
+type Condition func(event watch.EventType, obj runtime.Object) (done bool, status string, err error)
+
+// WatchFunc starts a watch with the given options, such as the Watch func of a typed client
+type WatchFunc func(options metav1.ListOptions) (watch.Interface, error)
+
+// Waiter watches a named object until it reaches a condition
+type Waiter struct {
+	// Name of the object to wait for. When empty every object returned by the watch is checked
+	Name string
+	// ResourceVersion to start watching from. When empty the watch starts with the current state of the object
+	ResourceVersion string
+	// Timeout after which the wait gives up. Zero waits forever
+	Timeout time.Duration
+	watch   WatchFunc
+	out     io.Writer
+}
+
+// New returns a Waiter for the named object which writes progress to out. A nil out discards progress
+func New(name string, watchFunc WatchFunc, timeout time.Duration, out io.Writer) *Waiter {
+	if out == nil {
+		out = ioutil.Discard
+	}
+	return &Waiter{Name: name, Timeout: timeout, watch: watchFunc, out: out}
+}
+
+// Until blocks until the condition is met, the condition fails, the timeout is reached or the user presses Ctrl-C.
+// When the server closes the watch it is resumed from the last resourceVersion seen
+func (w *Waiter) Until(condition Condition) error {
+	var timeout <-chan time.Time
+	if w.Timeout > 0 {
+		timer := time.NewTimer(w.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	resourceVersion := w.ResourceVersion
+	lastStatus := ""
+	for {
+		watcher, err := w.watch(w.listOptions(resourceVersion))
+		if err != nil {
+			return errors.Wrap(err, "failed to watch "+w.Name)
+		}
+		resume := false
+		for !resume {
+			select {
+			case <-timeout:
+				watcher.Stop()
+				return ErrTimeout
+			case <-interrupt:
+				watcher.Stop()
+				return ErrInterrupted
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					resume = true
+					break
+				}
+				if event.Type == watch.Error {
+					watcher.Stop()
+					err := apierrors.FromObject(event.Object)
+					if isExpired(err) {
+						// the resourceVersion is too old to resume from so start again from the current state
+						resourceVersion = ""
+						resume = true
+						break
+					}
+					return errors.Wrap(err, "unexpected error watching "+w.Name)
+				}
+				accessor, err := meta.Accessor(event.Object)
+				if err != nil {
+					watcher.Stop()
+					return errors.WithStack(err)
+				}
+				if w.Name != "" && accessor.GetName() != w.Name {
+					continue
+				}
+				resourceVersion = accessor.GetResourceVersion()
+				done, status, err := condition(event.Type, event.Object)
+				if status != "" && status != lastStatus {
+					fmt.Fprintln(w.out, "status: "+status)
+					lastStatus = status
+				}
+				if err != nil || done {
+					watcher.Stop()
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *Waiter) listOptions(resourceVersion string) metav1.ListOptions {
+	options := metav1.ListOptions{ResourceVersion: resourceVersion}
+	if w.Name != "" {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.Name).String()
+	}
+	return options
+}
+
+func isExpired(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		return false
+	}
+	return status.Status().Code == http.StatusGone || status.Status().Reason == metav1.StatusReasonExpired
+}
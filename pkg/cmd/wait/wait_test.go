@@ -0,0 +1,217 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func serviceInstance(name, resourceVersion string, conditionType v1beta1.ServiceInstanceConditionType, message string) *v1beta1.ServiceInstance {
+	return &v1beta1.ServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+		Status: v1beta1.ServiceInstanceStatus{
+			Conditions: []v1beta1.ServiceInstanceCondition{
+				{Type: conditionType, Status: v1beta1.ConditionTrue, Message: message},
+			},
+		},
+	}
+}
+
+func TestWaiter_Until(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Events   [][]watch.Event
+		Timeout  time.Duration
+		Validate func(t *testing.T, err error, options []metav1.ListOptions, progress string)
+	}{
+		{
+			Name: "should ignore other objects and finish when the named object is ready",
+			Events: [][]watch.Event{{
+				{Type: watch.Modified, Object: serviceInstance("other", "1", v1beta1.ServiceInstanceConditionReady, "other ready")},
+				{Type: watch.Modified, Object: serviceInstance("test", "2", v1beta1.ServiceInstanceConditionReady, "ready")},
+			}},
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if err != nil {
+					t.Fatal("did not expect an error but got one ", err)
+				}
+				if options[0].FieldSelector != "metadata.name=test" {
+					t.Fatalf("expected the watch to be filtered by name but got '%s'", options[0].FieldSelector)
+				}
+				if progress != "status: ready\n" {
+					t.Fatalf("expected only the progress of the named object but got '%s'", progress)
+				}
+			},
+		},
+		{
+			Name: "should resume the watch from the last resource version when it is closed",
+			Events: [][]watch.Event{
+				{{Type: watch.Modified, Object: serviceInstance("test", "5", v1beta1.ServiceInstanceConditionType("Provisioning"), "provisioning")}},
+				{{Type: watch.Modified, Object: serviceInstance("test", "6", v1beta1.ServiceInstanceConditionReady, "ready")}},
+			},
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if err != nil {
+					t.Fatal("did not expect an error but got one ", err)
+				}
+				if len(options) != 2 || options[1].ResourceVersion != "5" {
+					t.Fatalf("expected the watch to be resumed from resource version 5 but got %v", options)
+				}
+			},
+		},
+		{
+			Name: "should restart from the current state when the resource version has expired",
+			Events: [][]watch.Event{
+				{
+					{Type: watch.Modified, Object: serviceInstance("test", "5", v1beta1.ServiceInstanceConditionType("Provisioning"), "provisioning")},
+					{Type: watch.Error, Object: &metav1.Status{Code: http.StatusGone, Reason: metav1.StatusReasonExpired}},
+				},
+				{{Type: watch.Added, Object: serviceInstance("test", "9", v1beta1.ServiceInstanceConditionReady, "ready")}},
+			},
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if err != nil {
+					t.Fatal("did not expect an error but got one ", err)
+				}
+				if len(options) != 2 || options[1].ResourceVersion != "" {
+					t.Fatalf("expected the watch to be restarted without a resource version but got %v", options)
+				}
+			},
+		},
+		{
+			Name: "should return the error sent by the watch",
+			Events: [][]watch.Event{{
+				{Type: watch.Error, Object: &metav1.Status{Code: http.StatusInternalServerError, Message: "broken"}},
+			}},
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if err == nil || err.Error() != "unexpected error watching test: broken" {
+					t.Fatalf("expected the watch error to be returned but got '%v'", err)
+				}
+			},
+		},
+		{
+			Name: "should return a failed error when the object fails",
+			Events: [][]watch.Event{{
+				{Type: watch.Modified, Object: serviceInstance("test", "2", v1beta1.ServiceInstanceConditionFailed, "no quota")},
+			}},
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if !wait.IsFailed(err) || err.Error() != "no quota" {
+					t.Fatalf("expected a failed error but got '%v'", err)
+				}
+			},
+		},
+		{
+			Name:    "should return a timeout error when the object is not ready in time",
+			Events:  [][]watch.Event{{}},
+			Timeout: time.Millisecond * 10,
+			Validate: func(t *testing.T, err error, options []metav1.ListOptions, progress string) {
+				if !wait.IsTimeout(err) {
+					t.Fatalf("expected a timeout error but got '%v'", err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var options []metav1.ListOptions
+			watchFunc := func(opts metav1.ListOptions) (watch.Interface, error) {
+				events := tc.Events[len(options)]
+				options = append(options, opts)
+				w := watch.NewFakeWithChanSize(len(events), false)
+				for _, e := range events {
+					w.Action(e.Type, e.Object)
+				}
+				if len(options) < len(tc.Events) {
+					w.Stop()
+				}
+				return w, nil
+			}
+			var progress bytes.Buffer
+			timeout := tc.Timeout
+			if timeout == 0 {
+				timeout = time.Second * 5
+			}
+			err := wait.New("test", watchFunc, timeout, &progress).Until(wait.ServiceInstanceReady(0))
+			tc.Validate(t, err, options, progress.String())
+		})
+	}
+}
+
+func TestPoller_Until(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Statuses       []string
+		Err            error
+		Timeout        time.Duration
+		ExpectTimeout  bool
+		ExpectFailed   bool
+		ExpectProgress string
+	}{
+		{
+			Name:           "should return once the check is done reporting each new status once",
+			Statuses:       []string{"1 of 2 updated", "1 of 2 updated", "2 of 2 updated"},
+			ExpectProgress: "status: 1 of 2 updated\nstatus: 2 of 2 updated\n",
+		},
+		{
+			Name:         "should return the failure of the check",
+			Statuses:     []string{"1 of 2 updated"},
+			Err:          wait.Failed("crash looping"),
+			ExpectFailed: true,
+		},
+		{
+			Name:          "should time out when the check is never done",
+			Statuses:      []string{"0 of 2 updated"},
+			Timeout:       50 * time.Millisecond,
+			ExpectTimeout: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			checks := 0
+			poller := wait.NewPoller(time.Millisecond, tc.Timeout, &out)
+			err := poller.Until(func() (bool, string, error) {
+				status := tc.Statuses[checks]
+				checks++
+				if checks < len(tc.Statuses) {
+					return false, status, nil
+				}
+				if tc.ExpectTimeout {
+					checks--
+					return false, status, nil
+				}
+				return tc.Err == nil, status, tc.Err
+			})
+			if tc.ExpectTimeout != wait.IsTimeout(err) {
+				t.Fatalf("expected timeout %v but got %v", tc.ExpectTimeout, err)
+			}
+			if tc.ExpectFailed != wait.IsFailed(err) {
+				t.Fatalf("expected failed %v but got %v", tc.ExpectFailed, err)
+			}
+			if !tc.ExpectTimeout && !tc.ExpectFailed && err != nil {
+				t.Fatal("did not expect an error but got one", err)
+			}
+			if tc.ExpectProgress != "" && out.String() != tc.ExpectProgress {
+				t.Fatalf("expected progress %q but got %q", tc.ExpectProgress, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/mobile-crd-client/pkg/apis/servicecatalog/v1beta1"
+	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	mcFake "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned/fake"
+	"github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned"
+	scFake "github.com/aerogear/mobile-crd-client/pkg/client/servicecatalog/clientset/versioned/fake"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	kFake "k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func watchReactor(objects ...runtime.Object) ktesting.WatchReactionFunc {
+	return func(action ktesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewRaceFreeFake()
+		for _, o := range objects {
+			w.Modify(o)
+		}
+		return true, w, nil
+	}
+}
+
+func instanceWithCondition(name string, conditionType v1beta1.ServiceInstanceConditionType, message string) *v1beta1.ServiceInstance {
+	return &v1beta1.ServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1beta1.ServiceInstanceSpec{
+			ParametersFrom: []v1beta1.ParametersFromSource{
+				{SecretKeyRef: &v1beta1.SecretKeyReference{Name: "myapp-android-apb-params", Key: "parameters"}},
+			},
+		},
+		Status: v1beta1.ServiceInstanceStatus{
+			Conditions: []v1beta1.ServiceInstanceCondition{
+				{Type: conditionType, Status: v1beta1.ConditionTrue, Message: message},
+			},
+		},
+	}
+}
+
+func TestWaitCmd(t *testing.T) {
+	cases := []struct {
+		Name             string
+		Cmd              func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command
+		SvcCatalogClient func() versioned.Interface
+		MobileClient     func() mc.Interface
+		ExpectUsage      bool
+		ExpectError      string
+		ExitCode         int
+		Flags            []string
+		Args             []string
+	}{
+		{
+			Name: "test wait serviceinstance returns usage when missing the id",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface { return &scFake.Clientset{} },
+			ExpectUsage:      true,
+			ExitCode:         cmd.ExitError,
+			Flags:            []string{"--namespace=test"},
+			Args:             []string{},
+		},
+		{
+			Name: "test wait serviceinstance succeeds once the instance is ready",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddWatchReactor("serviceinstances", watchReactor(
+					instanceWithCondition("other", v1beta1.ServiceInstanceConditionFailed, "other failed"),
+					instanceWithCondition("keycloak-xyz", v1beta1.ServiceInstanceConditionReady, "ready"),
+				))
+				return fake
+			},
+			ExitCode: cmd.ExitOK,
+			Flags:    []string{"--namespace=test", "--for=condition=Ready"},
+			Args:     []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait serviceinstance exits with the condition failed code when the instance fails",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddWatchReactor("serviceinstances", watchReactor(instanceWithCondition("keycloak-xyz", v1beta1.ServiceInstanceConditionFailed, "no quota")))
+				return fake
+			},
+			ExpectError: "failed waiting for service instance keycloak-xyz to be Ready: no quota",
+			ExitCode:    cmd.ExitConditionFailed,
+			Flags:       []string{"--namespace=test"},
+			Args:        []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait serviceinstance exits with the timeout code when the instance is not ready in time",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddWatchReactor("serviceinstances", watchReactor())
+				return fake
+			},
+			ExitCode: cmd.ExitTimeout,
+			Flags:    []string{"--namespace=test", "--timeout=10ms"},
+			Args:     []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait serviceinstance returns the not found error instead of waiting for an unknown instance",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "serviceinstances"}, "keycloak-typo")
+				})
+				fake.AddWatchReactor("serviceinstances", func(action ktesting.Action) (bool, watch.Interface, error) {
+					t.Fatal("the instance should not have been watched")
+					return false, nil, nil
+				})
+				return fake
+			},
+			ExpectError: `failed to get service instance keycloak-typo: serviceinstances "keycloak-typo" not found`,
+			ExitCode:    cmd.ExitError,
+			Flags:       []string{"--namespace=test"},
+			Args:        []string{"keycloak-typo"},
+		},
+		{
+			Name: "test wait serviceinstance succeeds without an event when the instance is already ready",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, instanceWithCondition("keycloak-xyz", v1beta1.ServiceInstanceConditionReady, "ready"), nil
+				})
+				fake.AddWatchReactor("serviceinstances", watchReactor())
+				return fake
+			},
+			ExitCode: cmd.ExitOK,
+			Flags:    []string{"--namespace=test", "--timeout=10ms"},
+			Args:     []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait serviceinstance waits for the ready condition of the current generation",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					si := instanceWithCondition("keycloak-xyz", v1beta1.ServiceInstanceConditionReady, "ready")
+					si.Generation = 2
+					si.Status.ReconciledGeneration = 1
+					return true, si, nil
+				})
+				fake.AddWatchReactor("serviceinstances", watchReactor())
+				return fake
+			},
+			ExitCode: cmd.ExitTimeout,
+			Flags:    []string{"--namespace=test", "--timeout=10ms"},
+			Args:     []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait integration returns the not found error instead of waiting for an unknown binding",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewIntegrationCmd(scClient, &kFake.Clientset{}, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitIntegrationCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "servicebindings", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "servicebindings"}, "keycloak-typo")
+				})
+				return fake
+			},
+			ExpectError: `failed to get integration keycloak-typo: servicebindings "keycloak-typo" not found`,
+			ExitCode:    cmd.ExitError,
+			Flags:       []string{"--namespace=test"},
+			Args:        []string{"keycloak-typo"},
+		},
+		{
+			Name: "test wait serviceinstance for delete succeeds when the instance is already gone",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("get", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "serviceinstances"}, "keycloak-xyz")
+				})
+				return fake
+			},
+			ExitCode: cmd.ExitOK,
+			Flags:    []string{"--namespace=test", "--for=delete"},
+			Args:     []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait returns an error for an unknown --for value",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewServicesCmd(scClient, &kFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitServiceInstanceCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface { return &scFake.Clientset{} },
+			ExpectError:      "unknown value for --for Ready. Use --for=condition=<condition> or --for=delete",
+			ExitCode:         cmd.ExitError,
+			Flags:            []string{"--namespace=test", "--for=Ready"},
+			Args:             []string{"keycloak-xyz"},
+		},
+		{
+			Name: "test wait integration succeeds once the binding is ready",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewIntegrationCmd(scClient, &kFake.Clientset{}, &mcFake.Clientset{}, &fakeDeploymentConfigs{}, &bytes.Buffer{}).WaitIntegrationCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddWatchReactor("servicebindings", watchReactor(&v1beta1.ServiceBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "keycloak-fh-sync-server"},
+					Status: v1beta1.ServiceBindingStatus{Conditions: []v1beta1.ServiceBindingCondition{
+						{Type: v1beta1.ServiceBindingConditionReady, Status: v1beta1.ConditionTrue},
+					}},
+				}))
+				return fake
+			},
+			ExitCode: cmd.ExitOK,
+			Flags:    []string{"--namespace=test"},
+			Args:     []string{"keycloak-fh-sync-server"},
+		},
+		{
+			Name: "test wait client waits for the provisioning service and the client",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewClientCmd(mobileClient, scClient, &kFake.Clientset{}, &bytes.Buffer{}).WaitClientCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, &v1beta1.ServiceInstanceList{Items: []v1beta1.ServiceInstance{
+						*instanceWithCondition("android-app-xyz", v1beta1.ServiceInstanceConditionType("Provisioning"), "provisioning"),
+					}}, nil
+				})
+				fake.AddWatchReactor("serviceinstances", watchReactor(instanceWithCondition("android-app-xyz", v1beta1.ServiceInstanceConditionReady, "ready")))
+				return fake
+			},
+			MobileClient: func() mc.Interface {
+				fake := &mcFake.Clientset{}
+				fake.AddWatchReactor("mobileclients", watchReactor(&v1alpha1.MobileClient{ObjectMeta: metav1.ObjectMeta{Name: "myapp-android"}}))
+				return fake
+			},
+			ExitCode: cmd.ExitOK,
+			Flags:    []string{"--namespace=test"},
+			Args:     []string{"myapp-android"},
+		},
+		{
+			Name: "test wait client exits with the condition failed code when the provisioning service fails",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewClientCmd(mobileClient, scClient, &kFake.Clientset{}, &bytes.Buffer{}).WaitClientCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface {
+				fake := &scFake.Clientset{}
+				fake.AddReactor("list", "serviceinstances", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, &v1beta1.ServiceInstanceList{Items: []v1beta1.ServiceInstance{
+						*instanceWithCondition("android-app-xyz", v1beta1.ServiceInstanceConditionType("Provisioning"), "provisioning"),
+					}}, nil
+				})
+				fake.AddWatchReactor("serviceinstances", watchReactor(instanceWithCondition("android-app-xyz", v1beta1.ServiceInstanceConditionFailed, "apb failed")))
+				return fake
+			},
+			ExpectError: "failed waiting for the service provisioning mobile client myapp-android: apb failed",
+			ExitCode:    cmd.ExitConditionFailed,
+			Flags:       []string{"--namespace=test"},
+			Args:        []string{"myapp-android"},
+		},
+		{
+			Name: "test wait client returns the not found error when nothing provisions the client",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewClientCmd(mobileClient, scClient, &kFake.Clientset{}, &bytes.Buffer{}).WaitClientCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface { return &scFake.Clientset{} },
+			MobileClient: func() mc.Interface {
+				fake := &mcFake.Clientset{}
+				fake.AddReactor("get", "mobileclients", func(action ktesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "mobileclients"}, "myapp-typo")
+				})
+				return fake
+			},
+			ExpectError: `failed to get mobile client myapp-typo: mobileclients "myapp-typo" not found`,
+			ExitCode:    cmd.ExitError,
+			Flags:       []string{"--namespace=test"},
+			Args:        []string{"myapp-typo"},
+		},
+		{
+			Name: "test wait clientbuild is not supported",
+			Cmd: func(scClient versioned.Interface, mobileClient mc.Interface) *cobra.Command {
+				return cmd.NewClientBuildsCmd().WaitClientBuildsCmd()
+			},
+			SvcCatalogClient: func() versioned.Interface { return &scFake.Clientset{} },
+			ExpectError:      "waiting for clientbuilds is not supported yet as clientbuilds cannot be created by this tool",
+			ExitCode:         cmd.ExitError,
+			Flags:            []string{"--namespace=test"},
+			Args:             []string{"mybuild"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			var mobileClient mc.Interface = &mcFake.Clientset{}
+			if tc.MobileClient != nil {
+				mobileClient = tc.MobileClient()
+			}
+			waitCmd := cmd.NewWaitCommand()
+			subCmd := tc.Cmd(tc.SvcCatalogClient(), mobileClient)
+			waitCmd.AddCommand(subCmd)
+			root.AddCommand(waitCmd)
+			if err := subCmd.ParseFlags(append(tc.Flags, "-q")); err != nil {
+				t.Fatal("failed to parse flags ", err)
+			}
+			err := subCmd.RunE(subCmd, tc.Args)
+			if tc.ExpectUsage {
+				if err != subCmd.Usage() {
+					t.Fatalf("Expected error to be '%s' but got '%v'", subCmd.Usage(), err)
+				}
+				return
+			}
+			if tc.ExpectError != "" && (err == nil || err.Error() != tc.ExpectError) {
+				t.Fatalf("expected error to be '%s' but got '%v'", tc.ExpectError, err)
+			}
+			if code := cmd.ExitCode(err); code != tc.ExitCode {
+				t.Fatalf("expected exit code %v but got %v for error %v", tc.ExitCode, code, err)
+			}
+		})
+	}
+}
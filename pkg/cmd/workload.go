@@ -0,0 +1,514 @@
+// Copyright Red Hat, Inc., and individual contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aerogear/mobile-cli/pkg/cmd/wait"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	kbeta "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	kext "k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/rest"
+)
+
+// DeploymentConfig is the subset of an OpenShift DeploymentConfig used by this tool
+type DeploymentConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              DeploymentConfigSpec   `json:"spec"`
+	Status            DeploymentConfigStatus `json:"status"`
+}
+
+type DeploymentConfigSpec struct {
+	Replicas int32               `json:"replicas"`
+	Selector map[string]string   `json:"selector,omitempty"`
+	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+}
+
+type DeploymentConfigStatus struct {
+	LatestVersion       int64                       `json:"latestVersion"`
+	ObservedGeneration  int64                       `json:"observedGeneration"`
+	Replicas            int32                       `json:"replicas"`
+	UpdatedReplicas     int32                       `json:"updatedReplicas"`
+	AvailableReplicas   int32                       `json:"availableReplicas"`
+	UnavailableReplicas int32                       `json:"unavailableReplicas"`
+	Conditions          []DeploymentConfigCondition `json:"conditions,omitempty"`
+}
+
+type DeploymentConfigCondition struct {
+	Type    string             `json:"type"`
+	Status  v1.ConditionStatus `json:"status"`
+	Reason  string             `json:"reason,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+type DeploymentConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DeploymentConfig `json:"items"`
+}
+
+// DeploymentConfigInterface reads and patches OpenShift DeploymentConfigs
+type DeploymentConfigInterface interface {
+	Get(namespace, name string) (*DeploymentConfig, error)
+	List(namespace string, options metav1.ListOptions) (*DeploymentConfigList, error)
+	Patch(namespace, name string, pt types.PatchType, data []byte) (*DeploymentConfig, error)
+}
+
+const deploymentConfigsPath = "/apis/apps.openshift.io/v1"
+
+type deploymentConfigs struct {
+	client rest.Interface
+}
+
+// NewDeploymentConfigClient returns a DeploymentConfigInterface using a REST client with no group version set, such as the discovery REST client
+func NewDeploymentConfigClient(client rest.Interface) DeploymentConfigInterface {
+	return &deploymentConfigs{client: client}
+}
+
+func (c *deploymentConfigs) Get(namespace, name string) (*DeploymentConfig, error) {
+	data, err := c.client.Get().AbsPath(deploymentConfigsPath, "namespaces", namespace, "deploymentconfigs", name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	dc := &DeploymentConfig{}
+	if err := json.Unmarshal(data, dc); err != nil {
+		return nil, errors.Wrap(err, "failed to decode DeploymentConfig "+name)
+	}
+	return dc, nil
+}
+
+func (c *deploymentConfigs) List(namespace string, options metav1.ListOptions) (*DeploymentConfigList, error) {
+	req := c.client.Get().AbsPath(deploymentConfigsPath, "namespaces", namespace, "deploymentconfigs")
+	if options.LabelSelector != "" {
+		req = req.Param("labelSelector", options.LabelSelector)
+	}
+	data, err := req.DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	list := &DeploymentConfigList{}
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode DeploymentConfigs")
+	}
+	return list, nil
+}
+
+func (c *deploymentConfigs) Patch(namespace, name string, pt types.PatchType, data []byte) (*DeploymentConfig, error) {
+	result, err := c.client.Patch(pt).AbsPath(deploymentConfigsPath, "namespaces", namespace, "deploymentconfigs", name).Body(data).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	dc := &DeploymentConfig{}
+	if err := json.Unmarshal(result, dc); err != nil {
+		return nil, errors.Wrap(err, "failed to decode DeploymentConfig "+name)
+	}
+	return dc, nil
+}
+
+const (
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	workloadDeployment           = "Deployment"
+	workloadDeploymentConfig     = "DeploymentConfig"
+	workloadStatefulSet          = "StatefulSet"
+)
+
+// workload is a Deployment, DeploymentConfig or StatefulSet running a consuming service
+type workload struct {
+	Kind           string
+	Name           string
+	TemplateLabels map[string]string
+}
+
+// workloads finds the Deployments, DeploymentConfigs and StatefulSets running the services integrations are injected into
+type workloads struct {
+	k8Client kubernetes.Interface
+	dcClient DeploymentConfigInterface
+}
+
+// find returns the workloads matching the label selector or, when there is no selector, the first workload with the name
+func (w *workloads) find(ns, name, selector string) ([]workload, error) {
+	if selector != "" {
+		found, err := w.list(ns, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, errors.New("no Deployment, DeploymentConfig or StatefulSet matches the selector " + selector)
+		}
+		return found, nil
+	}
+	dep, err := w.k8Client.AppsV1beta1().Deployments(ns).Get(name, metav1.GetOptions{})
+	if err == nil {
+		return []workload{{Kind: workloadDeployment, Name: name, TemplateLabels: dep.Spec.Template.Labels}}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	dc, err := w.dcClient.Get(ns, name)
+	if err == nil {
+		return []workload{dcWorkload(*dc)}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	ss, err := w.k8Client.AppsV1beta1().StatefulSets(ns).Get(name, metav1.GetOptions{})
+	if err == nil {
+		return []workload{{Kind: workloadStatefulSet, Name: name, TemplateLabels: ss.Spec.Template.Labels}}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	return nil, errors.New("no Deployment, DeploymentConfig or StatefulSet named " + name)
+}
+
+// list returns the workloads of every kind matching the options. DeploymentConfigs are skipped on clusters without them
+func (w *workloads) list(ns string, options metav1.ListOptions) ([]workload, error) {
+	var found []workload
+	deps, err := w.k8Client.AppsV1beta1().Deployments(ns).List(options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deployments")
+	}
+	for _, d := range deps.Items {
+		found = append(found, workload{Kind: workloadDeployment, Name: d.Name, TemplateLabels: d.Spec.Template.Labels})
+	}
+	dcs, err := w.dcClient.List(ns, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "failed to list deploymentconfigs")
+	}
+	if err == nil {
+		for _, dc := range dcs.Items {
+			found = append(found, dcWorkload(dc))
+		}
+	}
+	sss, err := w.k8Client.AppsV1beta1().StatefulSets(ns).List(options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets")
+	}
+	for _, ss := range sss.Items {
+		found = append(found, workload{Kind: workloadStatefulSet, Name: ss.Name, TemplateLabels: ss.Spec.Template.Labels})
+	}
+	return found, nil
+}
+
+func dcWorkload(dc DeploymentConfig) workload {
+	w := workload{Kind: workloadDeploymentConfig, Name: dc.Name}
+	if dc.Spec.Template != nil {
+		w.TemplateLabels = dc.Spec.Template.Labels
+	}
+	return w
+}
+
+// templateLabelPatch is the merge patch setting, or removing when the value is empty, the label on the pod template of a workload
+func templateLabelPatch(key, value string) map[string]interface{} {
+	var label interface{}
+	if value != "" {
+		label = value
+	}
+	return map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{key: label}}}},
+	}
+}
+
+// setTemplateLabel sets the label on the pod template of the workload causing it to roll out. An empty value removes the label
+func (w *workloads) setTemplateLabel(ns string, wl workload, key, value string) error {
+	setLabel := func(labels map[string]string) map[string]string {
+		if value == "" {
+			delete(labels, key)
+			return labels
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = value
+		return labels
+	}
+	switch wl.Kind {
+	case workloadDeployment:
+		dep, err := w.k8Client.AppsV1beta1().Deployments(ns).Get(wl.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		dep.Spec.Template.Labels = setLabel(dep.Spec.Template.Labels)
+		_, err = w.k8Client.AppsV1beta1().Deployments(ns).Update(dep)
+		return err
+	case workloadStatefulSet:
+		ss, err := w.k8Client.AppsV1beta1().StatefulSets(ns).Get(wl.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ss.Spec.Template.Labels = setLabel(ss.Spec.Template.Labels)
+		_, err = w.k8Client.AppsV1beta1().StatefulSets(ns).Update(ss)
+		return err
+	case workloadDeploymentConfig:
+		// only part of the DeploymentConfig is decoded so it is patched rather than updated
+		patch, err := json.Marshal(templateLabelPatch(key, value))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = w.dcClient.Patch(ns, wl.Name, types.MergePatchType, patch)
+		return err
+	}
+	return errors.New("unknown kind of workload " + wl.Kind)
+}
+
+// rolloutStatus checks whether the latest change to the workload has been rolled out to all of its replicas.
+// It fails as soon as one of the new pods is crash looping and, when mountPath is set, if none of the new pods mount it
+func (w *workloads) rolloutStatus(ns string, wl workload, mountPath string) wait.CheckFunc {
+	return func() (bool, string, error) {
+		var (
+			done   bool
+			status string
+			pods   []v1.Pod
+			err    error
+		)
+		switch wl.Kind {
+		case workloadDeployment:
+			done, status, pods, err = w.deploymentRollout(ns, wl.Name)
+		case workloadDeploymentConfig:
+			done, status, pods, err = w.deploymentConfigRollout(ns, wl.Name)
+		case workloadStatefulSet:
+			done, status, pods, err = w.statefulSetRollout(ns, wl.Name)
+		default:
+			err = errors.New("unknown kind of workload " + wl.Kind)
+		}
+		if err != nil {
+			return false, "", err
+		}
+		for _, pod := range pods {
+			if reason := crashLoopReason(pod); reason != "" {
+				return false, "", wait.Failed(fmt.Sprintf("pod %s of %s %s is crash looping after the integration: %s", pod.Name, wl.Kind, wl.Name, reason))
+			}
+		}
+		if !done || mountPath == "" {
+			return done, status, nil
+		}
+		if len(pods) == 0 {
+			return true, fmt.Sprintf("%s %s has no pods running to check for the %s volume mount", wl.Kind, wl.Name, mountPath), nil
+		}
+		for _, pod := range pods {
+			if mountsPath(pod, mountPath) {
+				return true, fmt.Sprintf("%s. Pod %s mounts %s", status, pod.Name, mountPath), nil
+			}
+		}
+		return false, "", wait.Failed(fmt.Sprintf("none of the new pods of %s %s mount %s. Check the pod preset of the integration selects the pods of %s %s", wl.Kind, wl.Name, mountPath, wl.Kind, wl.Name))
+	}
+}
+
+// deploymentRollout waits for the ReplicaSet of the latest revision of the deployment to become available
+func (w *workloads) deploymentRollout(ns, name string) (bool, string, []v1.Pod, error) {
+	dep, err := w.k8Client.AppsV1beta1().Deployments(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", nil, err
+	}
+	if dep.Generation > dep.Status.ObservedGeneration {
+		return false, fmt.Sprintf("waiting for the update of deployment %s to be observed", name), nil, nil
+	}
+	for _, c := range dep.Status.Conditions {
+		if c.Type == kbeta.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return false, "", nil, wait.Failed(fmt.Sprintf("deployment %s exceeded its progress deadline", name))
+		}
+	}
+	selector, err := workloadSelector(dep.Spec.Selector, dep.Spec.Template.Labels)
+	if err != nil {
+		return false, "", nil, err
+	}
+	rsList, err := w.k8Client.ExtensionsV1beta1().ReplicaSets(ns).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, "", nil, errors.Wrap(err, "failed to list the replica sets of deployment "+name)
+	}
+	var newRS *kext.ReplicaSet
+	for i, rs := range rsList.Items {
+		if rs.Annotations[deploymentRevisionAnnotation] == dep.Annotations[deploymentRevisionAnnotation] {
+			newRS = &rsList.Items[i]
+		}
+	}
+	if newRS == nil {
+		return false, fmt.Sprintf("waiting for the new replica set of deployment %s to be created", name), nil, nil
+	}
+	pods, err := w.pods(ns, newRS.Spec.Selector, nil)
+	if err != nil {
+		return false, "", nil, err
+	}
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	if newRS.Status.AvailableReplicas < replicas {
+		return false, fmt.Sprintf("waiting for the new replica set %s of deployment %s to become available: %d of %d replicas are available", newRS.Name, name, newRS.Status.AvailableReplicas, replicas), pods, nil
+	}
+	done, status := replicasRolloutStatus("deployment", name, replicas, dep.Status.Replicas, dep.Status.UpdatedReplicas, dep.Status.AvailableReplicas)
+	return done, status, pods, nil
+}
+
+// deploymentConfigRollout waits for the ReplicationController of the latest version of the deploymentconfig to become available
+func (w *workloads) deploymentConfigRollout(ns, name string) (bool, string, []v1.Pod, error) {
+	dc, err := w.dcClient.Get(ns, name)
+	if err != nil {
+		return false, "", nil, err
+	}
+	if dc.Generation > dc.Status.ObservedGeneration {
+		return false, fmt.Sprintf("waiting for the update of deploymentconfig %s to be observed", name), nil, nil
+	}
+	for _, c := range dc.Status.Conditions {
+		if c.Type == "Progressing" && c.Status == v1.ConditionFalse {
+			return false, "", nil, wait.Failed(fmt.Sprintf("rollout of deploymentconfig %s failed: %s", name, c.Message))
+		}
+	}
+	rcName := fmt.Sprintf("%s-%d", name, dc.Status.LatestVersion)
+	rc, err := w.k8Client.CoreV1().ReplicationControllers(ns).Get(rcName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, fmt.Sprintf("waiting for the replication controller %s of deploymentconfig %s to be created", rcName, name), nil, nil
+	}
+	if err != nil {
+		return false, "", nil, errors.Wrap(err, "failed to get the replication controller "+rcName)
+	}
+	pods, err := w.pods(ns, nil, rc.Spec.Selector)
+	if err != nil {
+		return false, "", nil, err
+	}
+	if rc.Status.AvailableReplicas < dc.Spec.Replicas {
+		return false, fmt.Sprintf("waiting for the replication controller %s of deploymentconfig %s to become available: %d of %d replicas are available", rcName, name, rc.Status.AvailableReplicas, dc.Spec.Replicas), pods, nil
+	}
+	done, status := replicasRolloutStatus("deploymentconfig", name, dc.Spec.Replicas, dc.Status.Replicas, dc.Status.UpdatedReplicas, dc.Status.AvailableReplicas)
+	return done, status, pods, nil
+}
+
+// statefulSetRollout waits for the pods of the update revision of the statefulset to be ready
+func (w *workloads) statefulSetRollout(ns, name string) (bool, string, []v1.Pod, error) {
+	ss, err := w.k8Client.AppsV1beta1().StatefulSets(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", nil, err
+	}
+	if ss.Spec.UpdateStrategy.Type == kbeta.OnDeleteStatefulSetStrategyType {
+		return true, fmt.Sprintf("statefulset %s uses the OnDelete update strategy. Delete its pods to pick up the change", name), nil, nil
+	}
+	if ss.Status.ObservedGeneration == nil || ss.Generation > *ss.Status.ObservedGeneration {
+		return false, fmt.Sprintf("waiting for the update of statefulset %s to be observed", name), nil, nil
+	}
+	var pods []v1.Pod
+	if ss.Status.UpdateRevision != "" {
+		pods, err = w.pods(ns, ss.Spec.Selector, map[string]string{kbeta.StatefulSetRevisionLabel: ss.Status.UpdateRevision})
+		if err != nil {
+			return false, "", nil, err
+		}
+	}
+	replicas := int32(1)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+	if ss.Status.UpdatedReplicas < replicas || ss.Status.UpdateRevision != ss.Status.CurrentRevision {
+		return false, fmt.Sprintf("waiting for the rollout of statefulset %s to finish: %d of %d pods have been updated", name, ss.Status.UpdatedReplicas, replicas), pods, nil
+	}
+	if ss.Status.ReadyReplicas < replicas {
+		return false, fmt.Sprintf("waiting for the rollout of statefulset %s to finish: %d of %d updated pods are ready", name, ss.Status.ReadyReplicas, replicas), pods, nil
+	}
+	return true, fmt.Sprintf("statefulset %s successfully rolled out", name), pods, nil
+}
+
+// pods lists the pods matching the label selector and the extra labels
+func (w *workloads) pods(ns string, selector *metav1.LabelSelector, extra map[string]string) ([]v1.Pod, error) {
+	set := labels.Set{}
+	if selector != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(extra) == 0 {
+			return w.listPods(ns, s.String())
+		}
+		requirements, _ := s.Requirements()
+		for k, v := range extra {
+			r, err := labels.NewRequirement(k, selection.Equals, []string{v})
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			requirements = append(requirements, *r)
+		}
+		return w.listPods(ns, labels.NewSelector().Add(requirements...).String())
+	}
+	for k, v := range extra {
+		set[k] = v
+	}
+	return w.listPods(ns, set.AsSelector().String())
+}
+
+func (w *workloads) listPods(ns, selector string) ([]v1.Pod, error) {
+	podList, err := w.k8Client.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+	return podList.Items, nil
+}
+
+// workloadSelector returns the selector of the workload's pods, which defaults to the labels of its template
+func workloadSelector(selector *metav1.LabelSelector, templateLabels map[string]string) (labels.Selector, error) {
+	if selector == nil {
+		return labels.SelectorFromSet(templateLabels), nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	return s, errors.WithStack(err)
+}
+
+// crashLoopReason returns why a container of the pod is crash looping or an empty string when none are
+func crashLoopReason(pod v1.Pod) string {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil || cs.State.Waiting.Reason != "CrashLoopBackOff" {
+			continue
+		}
+		reason := fmt.Sprintf("container %s restarted %d times", cs.Name, cs.RestartCount)
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			reason += fmt.Sprintf(", last exit code %d", t.ExitCode)
+			if t.Message != "" {
+				reason += ": " + t.Message
+			}
+		}
+		return reason
+	}
+	return ""
+}
+
+func mountsPath(pod v1.Pod, mountPath string) bool {
+	for _, c := range pod.Spec.Containers {
+		for _, m := range c.VolumeMounts {
+			if m.MountPath == mountPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func replicasRolloutStatus(kind, name string, replicas, current, updated, available int32) (bool, string) {
+	if updated < replicas {
+		return false, fmt.Sprintf("waiting for the rollout of %s %s to finish: %d of %d new replicas have been updated", kind, name, updated, replicas)
+	}
+	if current > updated {
+		return false, fmt.Sprintf("waiting for the rollout of %s %s to finish: %d old replicas are pending termination", kind, name, current-updated)
+	}
+	if available < updated {
+		return false, fmt.Sprintf("waiting for the rollout of %s %s to finish: %d of %d updated replicas are available", kind, name, available, updated)
+	}
+	return true, fmt.Sprintf("%s %s successfully rolled out", kind, name)
+}